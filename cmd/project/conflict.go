@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/JackDrogon/project/pkg/prompt"
+	"github.com/JackDrogon/project/pkg/scaffold"
+)
+
+// promptResolver returns a ConflictResolver that asks the user about each
+// conflicting file, showing a diff on request.
+func promptResolver(p *prompt.Prompter, out io.Writer) scaffold.ConflictResolver {
+	choices := map[string]scaffold.ConflictPolicy{
+		"s": scaffold.ConflictSkip,
+		"o": scaffold.ConflictOverwrite,
+		"b": scaffold.ConflictKeepBoth,
+	}
+
+	return func(path string, existing, incoming []byte) (scaffold.ConflictPolicy, error) {
+		_, _ = fmt.Fprintf(out, "Conflict: %s differs from the template\n", path)
+		for {
+			answer, err := p.Input("[s]kip, [o]verwrite, keep [b]oth, show [d]iff", "s", func(s string) error {
+				if _, ok := choices[s]; ok || s == "d" {
+					return nil
+				}
+				return fmt.Errorf("answer s, o, b, or d")
+			})
+			if err != nil {
+				return "", err
+			}
+			if answer == "d" {
+				_, _ = fmt.Fprint(out, scaffold.UnifiedDiff(path, existing, incoming))
+				continue
+			}
+			return choices[answer], nil
+		}
+	}
+}
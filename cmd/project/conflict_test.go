@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/JackDrogon/project/pkg/prompt"
+	"github.com/JackDrogon/project/pkg/scaffold"
+)
+
+func TestPromptResolver(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		want     scaffold.ConflictPolicy
+		wantDiff bool
+	}{
+		{"default skips", "\n", scaffold.ConflictSkip, false},
+		{"overwrite", "o\n", scaffold.ConflictOverwrite, false},
+		{"diff then keep both", "d\nb\n", scaffold.ConflictKeepBoth, true},
+		{"invalid reprompts", "x\no\n", scaffold.ConflictOverwrite, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			resolve := promptResolver(prompt.New(strings.NewReader(tt.input), &out), &out)
+
+			got, err := resolve("justfile", []byte("old\n"), []byte("new\n"))
+			if err != nil {
+				t.Fatalf("resolve() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("resolve() = %q, want %q", got, tt.want)
+			}
+			if gotDiff := strings.Contains(out.String(), "-old\n+new\n"); gotDiff != tt.wantDiff {
+				t.Errorf("diff shown = %v, want %v\noutput:\n%s", gotDiff, tt.wantDiff, out.String())
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"text/tabwriter"
 
 	"github.com/JackDrogon/project/pkg/scaffold"
 	"github.com/spf13/cobra"
@@ -13,14 +14,61 @@ func newListCmd(creator *scaffold.Creator) *cobra.Command {
 		Use:   "list",
 		Short: "list all supported languages",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			langs, err := creator.ListLangs()
+			infos, err := creator.Templates()
 			if err != nil {
 				return err
 			}
-			for _, lang := range langs {
-				fmt.Println(lang)
+			if jsonOutput(cmd) {
+				return writeJSON(cmd.OutOrStdout(), newListJSON(infos))
 			}
-			return nil
+			tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			for _, info := range infos {
+				if info.Description == "" {
+					_, _ = fmt.Fprintln(tw, info.Name)
+					continue
+				}
+				_, _ = fmt.Fprintf(tw, "%s\t%s\n", info.Name, info.Description)
+			}
+			return tw.Flush()
 		},
 	}
 }
+
+// listJSON is the output of "list" with --output json.
+type listJSON struct {
+	Templates []templateJSON `json:"templates"`
+}
+
+type templateJSON struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Variables   []variableJSON `json:"variables"`
+}
+
+type variableJSON struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required"`
+	Default     any    `json:"default,omitempty"`
+	Pattern     string `json:"pattern,omitempty"`
+}
+
+func newListJSON(infos []scaffold.TemplateInfo) listJSON {
+	out := listJSON{Templates: make([]templateJSON, 0, len(infos))}
+	for _, info := range infos {
+		t := templateJSON{Name: info.Name, Description: info.Description, Variables: []variableJSON{}}
+		for _, v := range info.Variables {
+			t.Variables = append(t.Variables, variableJSON{
+				Name:        v.Name,
+				Type:        v.Type,
+				Description: v.Description,
+				Required:    v.Required(),
+				Default:     v.Default,
+				Pattern:     v.Pattern,
+			})
+		}
+		out.Templates = append(out.Templates, t)
+	}
+	return out
+}
@@ -1,7 +1,16 @@
 package main
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/JackDrogon/project/pkg/prompt"
 	"github.com/JackDrogon/project/pkg/scaffold"
+	"github.com/JackDrogon/project/pkg/source"
 	"github.com/spf13/cobra"
 )
 
@@ -12,29 +21,490 @@ func newNewCmd(creator *scaffold.Creator) *cobra.Command {
 	var force bool
 	var signoff bool
 	var dryRun bool
+	var setValues []string
+	var valuesFile string
+	var noInput bool
+	var allowEnv bool
+	var merge bool
+	var conflict string
+	var outputFormat string
+	var outputFile string
+	var includeGit bool
+	var noHooks bool
+	var allowHooks bool
+	var templateSource string
+	var refresh bool
+	var gitFlag string
+	var noGit bool
+	var noCommit bool
+	var commit bool
+	var initialBranch string
+	var commitMessage string
+	var commitAuthor string
+	var sign bool
+	var signingKey string
+	var remotes []string
 
 	cmd := &cobra.Command{
 		Use:   "new [project_name]",
 		Short: "Create new project",
-		Args:  cobra.ExactArgs(1),
+		Long: "Create new project.\n\n" +
+			"When --lang or the project name is omitted and stdin is a terminal, an interactive\n" +
+			"wizard asks for them along with the module path, author, and year.",
+		Args: usageArgs(cobra.MaximumNArgs(1)),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return creator.Create(scaffold.Options{
-				Lang:        lang,
-				ProjectName: args[0],
-				ModulePath:  module,
-				Force:       force,
-				Signoff:     signoff,
-				DryRun:      dryRun,
+			vars, err := templateValues(valuesFile, setValues)
+			if err != nil {
+				return withCode(codeUsage, err)
+			}
+			policy, err := scaffold.ParseConflictPolicy(conflict)
+			if err != nil {
+				return withCode(codeUsage, err)
+			}
+			var format scaffold.ArchiveFormat
+			if outputFormat != "" {
+				if format, err = scaffold.ParseArchiveFormat(outputFormat); err != nil {
+					return withCode(codeUsage, err)
+				}
+			} else if outputFile != "" || includeGit {
+				return usageErrorf("--output-file and --include-git require --output-format")
+			}
+			if file := legacyArchiveFile(cmd); file != "" {
+				if outputFile != "" {
+					return usageErrorf("--output %s cannot be combined with --output-file", file)
+				}
+				_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "Flag --output for the archive file is deprecated, use --output-file instead")
+				outputFile = file
+			}
+			gitMode, err := gitModeFlag(cmd, gitFlag, noGit)
+			if err != nil {
+				return err
+			}
+			if err := checkGitFlags(cmd, gitMode, noCommit, commit); err != nil {
+				return err
+			}
+			var gitRemotes []scaffold.Remote
+			for _, r := range remotes {
+				remote, err := scaffold.ParseRemote(r)
+				if err != nil {
+					return withCode(codeUsage, err)
+				}
+				gitRemotes = append(gitRemotes, remote)
+			}
+			if commitAuthor != "" {
+				if _, err := scaffold.ParseIdentity(commitAuthor); err != nil {
+					return withCode(codeUsage, err)
+				}
+			}
+
+			// With --output json, stdout is reserved for the result, so
+			// progress and prompts go to stderr.
+			asJSON := jsonOutput(cmd)
+			out := cmd.OutOrStdout()
+			var report *scaffold.Report
+			if asJSON {
+				out = cmd.ErrOrStderr()
+				creator.SetOutput(out)
+				report = &scaffold.Report{}
+			}
+
+			opts := scaffold.Options{
+				Lang:          lang,
+				ModulePath:    module,
+				Force:         force,
+				DryRun:        dryRun,
+				AllowEnv:      allowEnv,
+				NoHooks:       noHooks,
+				Git:           gitMode,
+				NoCommit:      noCommit,
+				Commit:        commit,
+				InitialBranch: initialBranch,
+				CommitMessage: commitMessage,
+				CommitAuthor:  commitAuthor,
+				Signoff:       signoff,
+				Sign:          sign,
+				SigningKey:    signingKey,
+				Remotes:       gitRemotes,
+				Vars:          vars,
+				Merge:         merge,
+				Conflict:      policy,
+				Archive:       format,
+				ArchiveGit:    includeGit,
+				Report:        report,
+			}
+			if len(args) == 1 {
+				opts.ProjectName = args[0]
+			}
+
+			var sourceHooks scaffold.Hooks
+			if templateSource != "" {
+				name, hooks, err := useTemplateSource(cmd, creator, templateSource, lang, refresh)
+				if err != nil {
+					return err
+				}
+				opts.Lang = name
+				sourceHooks = hooks
+			} else if refresh {
+				return usageErrorf("--refresh requires --template")
+			}
+
+			interactive := !noInput && isTerminal(os.Stdin)
+			p := prompt.NewContext(cmd.Context(), cmd.InOrStdin(), out)
+
+			if opts.Lang == "" || opts.ProjectName == "" {
+				if !interactive {
+					if opts.Lang == "" {
+						return usageErrorf(`required flag "lang" not set`)
+					}
+					return usageErrorf("project name is required")
+				}
+				if err := runWizard(cmd.Context(), p, creator, &opts); err != nil {
+					return err
+				}
+			}
+
+			// Hooks are shell commands; only run those of a fetched template
+			// when the user agrees to.
+			if len(sourceHooks.Pre)+len(sourceHooks.Post) > 0 && !noHooks && !allowHooks && !dryRun {
+				run := false
+				if interactive {
+					if run, err = confirmHooks(p, out, templateSource, sourceHooks); err != nil {
+						return err
+					}
+				}
+				if !run {
+					_, _ = fmt.Fprintf(out, "Skipping the hooks of %s; pass --allow-hooks to run them\n", templateSource)
+					opts.NoHooks = true
+				}
+			}
+
+			if merge && policy == scaffold.ConflictPrompt {
+				if !interactive {
+					return usageErrorf("--conflict=prompt needs an interactive terminal")
+				}
+				opts.Resolve = promptResolver(p, out)
+			}
+
+			// From here on, errors are not about usage.
+			cmd.SilenceUsage = true
+			ctx := cmd.Context()
+
+			if format == "" || dryRun {
+				if err := creator.Create(ctx, opts); err != nil {
+					return err
+				}
+				return writeReport(cmd, opts, "")
+			}
+			if outputFile == "" {
+				outputFile = opts.ProjectName + format.Ext()
+			}
+			if outputFile == "-" {
+				if asJSON {
+					return usageErrorf("--output-file - cannot be combined with --output json, which also writes to stdout")
+				}
+				if isTerminal(os.Stdout) {
+					return errors.New("refusing to write an archive to a terminal; redirect stdout or use --output-file <file>")
+				}
+				opts.ArchiveOut = cmd.OutOrStdout()
+				creator.SetOutput(cmd.ErrOrStderr())
+				return creator.Create(ctx, opts)
+			}
+			err = writeFileAtomic(outputFile, func(w io.Writer) error {
+				opts.ArchiveOut = w
+				return creator.Create(ctx, opts)
 			})
+			if err != nil {
+				return err
+			}
+			return writeReport(cmd, opts, outputFile)
 		},
 	}
 
-	cmd.Flags().StringVarP(&lang, "lang", "l", "", "Programming language for the project")
-	_ = cmd.MarkFlagRequired("lang")
+	cmd.Flags().StringVarP(&lang, "lang", "l", "", "Programming language for the project (prompted for when omitted on a terminal)")
+	cmd.Flags().StringVar(&templateSource, "template", "", "Use the template from git+<url>[#<ref>][:<subdir>] or a .tar, .tar.gz, .tgz, or .zip file")
+	cmd.Flags().BoolVar(&refresh, "refresh", false, "With a git --template, update the cached repository before using it")
 	cmd.Flags().StringVarP(&module, "module", "m", "", "Module path (e.g. github.com/user/project)")
-	cmd.Flags().BoolVar(&force, "force", false, "Remove existing project directory before scaffolding")
+	cmd.Flags().BoolVar(&force, "force", false, "Replace an existing project directory once scaffolding succeeds")
+	cmd.Flags().StringVar(&gitFlag, "git", "", "Where to put the project under git: nested (a new repository), parent (staged in the enclosing repository), or none (default parent inside a git work tree, else nested)")
+	cmd.Flags().BoolVar(&noGit, "no-git", false, "Don't initialize a git repository (same as --git=none)")
+	cmd.Flags().BoolVar(&noCommit, "no-commit", false, "Initialize a git repository but leave the files uncommitted")
+	cmd.Flags().BoolVar(&commit, "commit", false, "In an enclosing git repository, commit the project after staging it")
+	cmd.MarkFlagsMutuallyExclusive("commit", "no-commit")
+	cmd.Flags().StringVar(&initialBranch, "initial-branch", "", "Name of the first branch (default git's init.defaultBranch)")
+	cmd.Flags().StringVar(&commitMessage, "commit-message", "", `Initial commit message, rendered with the template variables (default "Initial commit")`)
+	cmd.Flags().StringVar(&commitAuthor, "author", "", `Initial commit author as "Name <email>" (default from git config)`)
 	cmd.Flags().BoolVar(&signoff, "signoff", false, "Add Signed-off-by trailer to the initial commit")
+	cmd.Flags().BoolVar(&sign, "sign", false, "Sign the initial commit with git's configured GPG or SSH key")
+	cmd.Flags().StringVar(&signingKey, "signing-key", "", "Sign the initial commit with this key instead (implies --sign)")
+	cmd.Flags().StringArrayVar(&remotes, "remote", nil, "Add a git remote as name=url (repeatable)")
 	cmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "Preview files without creating them")
+	cmd.Flags().StringArrayVar(&setValues, "set", nil, "Set a template variable as key=value (repeatable)")
+	cmd.Flags().StringVar(&valuesFile, "values", "", "YAML or JSON file with template variables")
+	cmd.Flags().BoolVar(&allowEnv, "allow-env", false, "Allow templates to read environment variables with the env function")
+	cmd.Flags().BoolVar(&noHooks, "no-hooks", false, "Don't run the template's pre and post generation hooks")
+	cmd.Flags().BoolVar(&allowHooks, "allow-hooks", false, "Run the hooks of a --template source without asking")
+	cmd.MarkFlagsMutuallyExclusive("no-hooks", "allow-hooks")
+	cmd.Flags().BoolVar(&merge, "merge", false, "Apply the template into an existing directory, keeping unrelated files")
+	cmd.Flags().StringVar(&conflict, "conflict", string(scaffold.ConflictSkip), "With --merge, how to handle files that differ: skip, overwrite, keep-both, or prompt")
+	cmd.MarkFlagsMutuallyExclusive("force", "merge")
+	cmd.Flags().StringVar(&outputFormat, "output-format", "", "Write the project as an archive instead of a directory: tar, tgz, or zip")
+	cmd.Flags().StringVar(&outputFile, "output-file", "", "With --output-format, the archive file to write, or - for stdout (default <project_name>.<ext>)")
+	cmd.Flags().BoolVar(&includeGit, "include-git", false, "With --output-format, initialize git and include the repository in the archive")
+	cmd.MarkFlagsMutuallyExclusive("output-format", "merge")
+	cmd.Flags().BoolVar(&noInput, "no-input", false, "Never prompt; fail when required values are missing")
 
 	return cmd
 }
+
+// commitFlags configure the initial commit.
+var commitFlags = []string{"commit-message", "author", "signoff", "sign", "signing-key"}
+
+// gitModeFlag returns the mode chosen with --git or --no-git.
+func gitModeFlag(cmd *cobra.Command, value string, noGit bool) (scaffold.GitMode, error) {
+	if !cmd.Flags().Changed("git") {
+		if noGit {
+			return scaffold.GitNone, nil
+		}
+		return scaffold.GitAuto, nil
+	}
+	mode, err := scaffold.ParseGitMode(value)
+	if err != nil {
+		return "", withCode(codeUsage, err)
+	}
+	if noGit && mode != scaffold.GitNone {
+		return "", usageErrorf("--git=%s cannot be combined with --no-git", mode)
+	}
+	return mode, nil
+}
+
+// checkGitFlags rejects git flags that the git mode, --no-commit, or the
+// lack of --commit make meaningless, rather than silently ignoring them.
+func checkGitFlags(cmd *cobra.Command, mode scaffold.GitMode, noCommit, commit bool) error {
+	var flags []string
+	switch {
+	case mode == scaffold.GitNone:
+		flags = append([]string{"no-commit", "commit", "initial-branch", "remote", "include-git"}, commitFlags...)
+	case mode == scaffold.GitNested:
+		// A new repository always gets its initial commit.
+		flags = []string{"commit"}
+	case mode == scaffold.GitParent:
+		// The enclosing repository already has its branches and remotes,
+		// and an archive can't carry it.
+		flags = []string{"initial-branch", "remote", "output-format"}
+		if !commit {
+			flags = append(flags, commitFlags...)
+		}
+	case noCommit:
+		flags = commitFlags
+	default:
+		return nil
+	}
+	for _, name := range flags {
+		if !cmd.Flags().Changed(name) {
+			continue
+		}
+		switch {
+		case mode == scaffold.GitNone && cmd.Flags().Changed("no-git"):
+			return usageErrorf("--%s cannot be combined with --no-git", name)
+		case noCommit && slices.Contains(commitFlags, name):
+			return usageErrorf("--%s cannot be combined with --no-commit", name)
+		case mode == scaffold.GitParent && slices.Contains(commitFlags, name):
+			return usageErrorf("--%s needs --commit with --git=parent, which only stages the project by default", name)
+		default:
+			return usageErrorf("--%s cannot be combined with --git=%s", name, mode)
+		}
+	}
+	return nil
+}
+
+// useTemplateSource opens the template at spec, fetching git sources into
+// the cache, and layers it over the others under name, or the source's own
+// name when name is empty. It returns the name to create the project from
+// and the template's hooks.
+func useTemplateSource(cmd *cobra.Command, creator *scaffold.Creator, spec, name string, refresh bool) (string, scaffold.Hooks, error) {
+	src, err := source.Parse(spec)
+	if err != nil {
+		return "", scaffold.Hooks{}, withCode(codeUsage, err)
+	}
+	opts := source.Options{Refresh: refresh, Progress: cmd.ErrOrStderr()}
+	if _, ok := src.(*source.Git); ok {
+		if opts.CacheDir, err = source.DefaultCacheDir(); err != nil {
+			return "", scaffold.Hooks{}, err
+		}
+	} else if refresh {
+		return "", scaffold.Hooks{}, usageErrorf("--refresh only applies to git+ template sources")
+	}
+	fsys, err := src.Open(cmd.Context(), opts)
+	if err != nil {
+		return "", scaffold.Hooks{}, withCode(codeTemplateSource, err)
+	}
+	// A broken manifest is reported when the project is created.
+	var hooks scaffold.Hooks
+	if manifest, err := scaffold.LoadManifest(fsys, "."); err == nil {
+		hooks = manifest.Hooks
+	}
+
+	if name == "" {
+		name = src.Name()
+	}
+	creator.Overlay(scaffold.MountFS(name, fsys))
+	return name, hooks, nil
+}
+
+// confirmHooks lists the hooks of the template from spec and asks whether
+// to run them.
+func confirmHooks(p *prompt.Prompter, w io.Writer, spec string, hooks scaffold.Hooks) (bool, error) {
+	_, _ = fmt.Fprintf(w, "The template %s runs these commands:\n", spec)
+	for _, h := range hooks.Pre {
+		_, _ = fmt.Fprintf(w, "  %s (pre hook)\n", h.Run)
+	}
+	for _, h := range hooks.Post {
+		_, _ = fmt.Fprintf(w, "  %s (post hook)\n", h.Run)
+	}
+	return p.Confirm("Run them", false)
+}
+
+// usageArgs marks errors from an argument validator as usage errors.
+func usageArgs(validate cobra.PositionalArgs) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := validate(cmd, args); err != nil {
+			silenceForJSON(cmd)
+			return withCode(codeUsage, err)
+		}
+		return nil
+	}
+}
+
+// projectJSON is the output of "new" with --output json.
+type projectJSON struct {
+	Project string     `json:"project"`
+	Lang    string     `json:"lang"`
+	DryRun  bool       `json:"dry_run"`
+	Archive string     `json:"archive,omitempty"`
+	Files   []fileJSON `json:"files"`
+	Merge   *mergeJSON `json:"merge,omitempty"`
+	Commit  string     `json:"commit,omitempty"`
+	// Git is "nested", "parent", or "none"; Repository is the enclosing
+	// work tree with "parent", and Staged whether the project was left
+	// staged there without a commit.
+	Git        string `json:"git,omitempty"`
+	Repository string `json:"repository,omitempty"`
+	Staged     bool   `json:"staged,omitempty"`
+}
+
+type fileJSON struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	Type        string `json:"type"` // "file" or "dir"
+	Mode        string `json:"mode"` // octal permissions, e.g. "0644"
+	Rendered    bool   `json:"rendered"`
+}
+
+type mergeJSON struct {
+	Created     []string `json:"created"`
+	Identical   []string `json:"identical"`
+	Skipped     []string `json:"skipped"`
+	Overwritten []string `json:"overwritten"`
+	KeptBoth    []string `json:"kept_both"`
+}
+
+// writeReport prints the result of a successful "new" with --output json.
+// archive is the archive file written, if any.
+func writeReport(cmd *cobra.Command, opts scaffold.Options, archive string) error {
+	if opts.Report == nil {
+		return nil
+	}
+	out := projectJSON{
+		Project:    opts.ProjectName,
+		Lang:       opts.Lang,
+		DryRun:     opts.DryRun,
+		Archive:    archive,
+		Files:      make([]fileJSON, 0, len(opts.Report.Files)),
+		Commit:     opts.Report.Commit,
+		Git:        string(opts.Report.Git),
+		Repository: opts.Report.Repository,
+		Staged:     opts.Report.Staged,
+	}
+	for _, f := range opts.Report.Files {
+		kind := "file"
+		if f.Mode.IsDir() {
+			kind = "dir"
+		}
+		out.Files = append(out.Files, fileJSON{
+			Source:      f.Source,
+			Destination: filepath.ToSlash(f.Dest),
+			Type:        kind,
+			Mode:        fmt.Sprintf("%04o", f.Mode.Perm()),
+			Rendered:    f.Rendered,
+		})
+	}
+	if m := opts.Report.Merge; m != nil {
+		out.Merge = &mergeJSON{
+			Created:     slashPaths(m.Created),
+			Identical:   slashPaths(m.Identical),
+			Skipped:     slashPaths(m.Skipped),
+			Overwritten: slashPaths(m.Overwritten),
+			KeptBoth:    slashPaths(m.KeptBoth),
+		}
+	}
+	return writeJSON(cmd.OutOrStdout(), out)
+}
+
+// slashPaths returns names with forward slashes, never nil.
+func slashPaths(names []string) []string {
+	out := make([]string, 0, len(names))
+	for _, name := range names {
+		out = append(out, filepath.ToSlash(name))
+	}
+	return out
+}
+
+// writeFileAtomic calls write with a temporary file beside name and renames
+// it to name once write succeeds, so a failure never leaves a partial file.
+func writeFileAtomic(name string, write func(io.Writer) error) (err error) {
+	f, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".tmp-")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+		}
+	}()
+
+	if err = write(f); err != nil {
+		return err
+	}
+	if err = f.Chmod(0644); err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
+
+// templateValues merges variables from the values file with --set pairs;
+// --set wins when both supply the same key.
+func templateValues(valuesFile string, setValues []string) (map[string]any, error) {
+	vars := make(map[string]any)
+	if valuesFile != "" {
+		fileVars, err := scaffold.LoadValuesFile(valuesFile)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range fileVars {
+			vars[key] = value
+		}
+	}
+
+	setVars, err := scaffold.ParseSetValues(setValues)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range setVars {
+		vars[key] = value
+	}
+	return vars, nil
+}
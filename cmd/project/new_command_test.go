@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/JackDrogon/project/pkg/scaffold"
+)
+
+func TestNewGitFlags(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--no-git", "--sign"}, "--sign cannot be combined with --no-git"},
+		{[]string{"--no-git", "--remote", "origin=x"}, "--remote cannot be combined with --no-git"},
+		{[]string{"--no-commit", "--commit-message", "x"}, "--commit-message cannot be combined with --no-commit"},
+		{[]string{"--git", "sideways"}, `unknown git mode "sideways"`},
+		{[]string{"--no-git", "--git", "parent"}, "--git=parent cannot be combined with --no-git"},
+		{[]string{"--git", "none", "--author", "A <a@b>"}, "--author cannot be combined with --git=none"},
+		{[]string{"--git", "parent", "--initial-branch", "main"}, "--initial-branch cannot be combined with --git=parent"},
+		{[]string{"--git", "parent", "--no-commit", "--signoff"}, "--signoff cannot be combined with --no-commit"},
+		{[]string{"--git", "parent", "--signoff"}, "--signoff needs --commit with --git=parent"},
+		{[]string{"--git", "nested", "--commit"}, "--commit cannot be combined with --git=nested"},
+		{[]string{"--remote", "origin"}, `invalid remote "origin"`},
+		{[]string{"--author", "jane"}, `invalid identity "jane"`},
+	}
+	for _, tt := range tests {
+		args := append([]string{"new", "-l", "go", "demo", "--dry-run"}, tt.args...)
+		_, err := runCmd(t, args...)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("new %v error = %v, want %q", tt.args, err, tt.want)
+			continue
+		}
+		if code := errorCode(err); code != codeUsage {
+			t.Errorf("new %v error code = %q, want %q", tt.args, code, codeUsage)
+		}
+	}
+}
+
+func TestNewTemplateHooks(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	tmp := t.TempDir()
+	archive := filepath.Join(tmp, "remote.tgz")
+	var buf bytes.Buffer
+	fsys := fstest.MapFS{
+		"template.yaml": {Data: []byte("hooks:\n  post:\n    - run: touch hooked\n")},
+		"main.go":       {Data: []byte("package main\n")},
+	}
+	if err := scaffold.WriteArchive(&buf, fsys, scaffold.ArchiveTgz, "remote"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(archive, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(tmp); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+
+	tests := []struct {
+		name   string
+		args   []string
+		hooked bool
+	}{
+		{"skipped by default", nil, false},
+		{"allowed", []string{"--allow-hooks"}, true},
+	}
+	for _, tt := range tests {
+		project := strings.ReplaceAll(tt.name, " ", "-")
+		args := append([]string{"new", "--template", archive, project, "--no-input", "--git", "none"}, tt.args...)
+		if _, err := runCmd(t, args...); err != nil {
+			t.Fatalf("%s: new error = %v", tt.name, err)
+		}
+		_, err := os.Stat(filepath.Join(project, "hooked"))
+		if hooked := err == nil; hooked != tt.hooked {
+			t.Errorf("%s: hook ran = %v, want %v", tt.name, hooked, tt.hooked)
+		}
+	}
+
+	if _, err := runCmd(t, "new", "--template", archive, "x", "--no-hooks", "--allow-hooks"); err == nil {
+		t.Error("--no-hooks and --allow-hooks should be mutually exclusive")
+	}
+}
+
+func TestNewArchiveOutputAlias(t *testing.T) {
+	tmp := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(tmp); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+
+	for _, flag := range []string{"--output-file", "--output"} {
+		archive := filepath.Join(tmp, strings.TrimLeft(flag, "-")+".tgz")
+		if _, err := runCmd(t, "new", "-l", "go", "demo", "--no-input", "--output-format", "tgz", flag, archive); err != nil {
+			t.Fatalf("new %s error = %v", flag, err)
+		}
+		if _, err := os.Stat(archive); err != nil {
+			t.Errorf("new %s did not write the archive: %v", flag, err)
+		}
+	}
+
+	if _, err := runCmd(t, "new", "-l", "go", "demo", "--output-format", "tgz", "--output", "a.tgz", "--output-file", "b.tgz"); err == nil {
+		t.Error("--output <file> and --output-file should not be combined")
+	}
+	if _, err := runCmd(t, "new", "-l", "go", "demo", "--dry-run", "--output", "a.tgz"); err == nil {
+		t.Error("--output <file> without --output-format should be rejected")
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/JackDrogon/project/pkg/git"
+	"github.com/JackDrogon/project/pkg/scaffold"
+	"github.com/spf13/cobra"
+)
+
+// Formats accepted by the global --output flag.
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// checkOutputFormat validates the --output flag.
+func checkOutputFormat(format string) error {
+	switch format {
+	case outputText, outputJSON:
+		return nil
+	default:
+		return withCode(codeUsage, fmt.Errorf("invalid --output %q: must be text or json", format))
+	}
+}
+
+// legacyArchiveFile returns the archive file given to "new --output-format"
+// as --output <file>, which is what --output-file was called before
+// --output became global, or "" when --output holds a format. The old
+// form is deprecated but still accepted.
+func legacyArchiveFile(cmd *cobra.Command) string {
+	format, _ := cmd.Flags().GetString("output")
+	if cmd.Name() != "new" || !cmd.Flags().Changed("output-format") || format == outputText || format == outputJSON {
+		return ""
+	}
+	return format
+}
+
+// jsonOutput reports whether cmd was asked for JSON output.
+func jsonOutput(cmd *cobra.Command) bool {
+	format, _ := cmd.Flags().GetString("output")
+	return format == outputJSON
+}
+
+// silenceForJSON stops cobra from printing errors and usage as text when
+// cmd was asked for JSON output; Execute reports the error instead.
+func silenceForJSON(cmd *cobra.Command) {
+	if jsonOutput(cmd) {
+		cmd.SilenceErrors = true
+		cmd.SilenceUsage = true
+	}
+}
+
+// writeJSON writes v to w as a single indented JSON document.
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// Error codes reported with --output json. They are part of the CLI's
+// interface: add new ones freely, but never change or reuse existing ones.
+const (
+	codeError             = "error"
+	codeUsage             = "usage"
+	codeInterrupted       = "interrupted"
+	codeInvalidName       = "invalid_name"
+	codeUnsupportedLang   = "unsupported_language"
+	codeUndefinedVar      = "undefined_variable"
+	codeInvalidVariable   = "invalid_variable"
+	codeDestinationExists = "destination_exists"
+	codeHookFailed        = "hook_failed"
+	codeTemplateSource    = "template_source"
+	codeGitNotFound       = "git_not_found"
+	codeGitIdentity       = "git_identity"
+	codeGitHookRejected   = "git_hook_rejected"
+)
+
+// codedError attaches an error code to err.
+type codedError struct {
+	code string
+	err  error
+}
+
+func (e *codedError) Error() string { return e.err.Error() }
+func (e *codedError) Unwrap() error { return e.err }
+
+func withCode(code string, err error) error {
+	return &codedError{code: code, err: err}
+}
+
+// usageErrorf reports a mistake in how the command was invoked.
+func usageErrorf(format string, args ...any) error {
+	return withCode(codeUsage, fmt.Errorf(format, args...))
+}
+
+// errReported is returned by commands that have already printed their
+// result, such as lint findings, and only need a non-zero exit status.
+var errReported = errors.New("failure already reported")
+
+// errorCode returns the stable code for err.
+func errorCode(err error) string {
+	var coded *codedError
+	var undefined *scaffold.UndefinedVarError
+	switch {
+	case errors.Is(err, context.Canceled):
+		return codeInterrupted
+	case errors.As(err, &coded):
+		return coded.code
+	case errors.As(err, &undefined):
+		return codeUndefinedVar
+	case errors.Is(err, scaffold.ErrInvalidName):
+		return codeInvalidName
+	case errors.Is(err, scaffold.ErrUnsupportedLang):
+		return codeUnsupportedLang
+	case errors.Is(err, scaffold.ErrInvalidVariable):
+		return codeInvalidVariable
+	case errors.Is(err, scaffold.ErrDestinationExists):
+		return codeDestinationExists
+	case errors.Is(err, scaffold.ErrHookFailed):
+		return codeHookFailed
+	case errors.Is(err, git.ErrNotFound):
+		return codeGitNotFound
+	case errors.Is(err, git.ErrNoIdentity):
+		return codeGitIdentity
+	case errors.Is(err, git.ErrHookRejected):
+		return codeGitHookRejected
+	default:
+		return codeError
+	}
+}
+
+// errorJSON is how a failure is printed with --output json.
+type errorJSON struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func newErrorJSON(code, message string) errorJSON {
+	var e errorJSON
+	e.Error.Code = code
+	e.Error.Message = message
+	return e
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/JackDrogon/project/pkg/git"
+	"github.com/JackDrogon/project/pkg/scaffold"
+)
+
+// runJSON executes the command line with --output json and decodes stdout
+// into v, returning the command's error.
+func runJSON(t *testing.T, creator *scaffold.Creator, v any, args ...string) error {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	root := newRootCmd(creator)
+	root.SetOut(&stdout)
+	root.SetErr(&stderr)
+	root.SetArgs(append([]string{"--output", "json"}, args...))
+	err := root.Execute()
+	if err != nil {
+		if stdout.Len() != 0 {
+			t.Errorf("stdout on error = %q, want nothing", stdout.String())
+		}
+		return err
+	}
+	if err := json.Unmarshal(stdout.Bytes(), v); err != nil {
+		t.Fatalf("stdout is not JSON: %v\n%s", err, stdout.String())
+	}
+	return nil
+}
+
+func testCreator() *scaffold.Creator {
+	fsys := fstest.MapFS{
+		"go/template.yaml": {Data: []byte("description: Go\nvariables:\n  - name: license\n    default: MIT\n")},
+		"go/main.go.tmpl":  {Data: []byte("package main // {{.ProjectName}}\n")},
+		"go/run.sh":        {Data: []byte("#!/bin/sh\n"), Mode: 0755},
+	}
+	return scaffold.NewCreator(fsys, &bytes.Buffer{})
+}
+
+func TestListJSON(t *testing.T) {
+	var got listJSON
+	if err := runJSON(t, testCreator(), &got, "list"); err != nil {
+		t.Fatalf("list error = %v", err)
+	}
+	if len(got.Templates) != 1 {
+		t.Fatalf("templates = %+v, want one", got.Templates)
+	}
+	tmpl := got.Templates[0]
+	if tmpl.Name != "go" || tmpl.Description != "Go" {
+		t.Errorf("template = %+v", tmpl)
+	}
+	want := variableJSON{Name: "license", Type: "string", Default: "MIT"}
+	if len(tmpl.Variables) != 1 || tmpl.Variables[0] != want {
+		t.Errorf("variables = %+v, want [%+v]", tmpl.Variables, want)
+	}
+}
+
+func TestNewJSON_DryRun(t *testing.T) {
+	var got projectJSON
+	if err := runJSON(t, testCreator(), &got, "new", "-l", "go", "demo", "--dry-run", "--no-input"); err != nil {
+		t.Fatalf("new error = %v", err)
+	}
+	want := []fileJSON{
+		{Source: "go/main.go.tmpl", Destination: "demo/main.go", Type: "file", Mode: "0644", Rendered: true},
+		{Source: "go/run.sh", Destination: "demo/run.sh", Type: "file", Mode: "0755"},
+	}
+	if got.Project != "demo" || got.Lang != "go" || !got.DryRun || got.Commit != "" {
+		t.Errorf("result = %+v", got)
+	}
+	if fmt.Sprint(got.Files) != fmt.Sprint(want) {
+		t.Errorf("files = %+v, want %+v", got.Files, want)
+	}
+}
+
+func TestVersionJSON(t *testing.T) {
+	var got versionJSON
+	if err := runJSON(t, testCreator(), &got, "version"); err != nil {
+		t.Fatalf("version error = %v", err)
+	}
+	if got.Tag == "" || got.GoVersion == "" {
+		t.Errorf("version = %+v, want tag and Go version", got)
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"new", "-l", "cobol", "demo", "--no-input"}, codeUnsupportedLang},
+		{[]string{"new", "-l", "go", "1demo", "--no-input"}, codeInvalidName},
+		{[]string{"new", "-l", "go", "demo", "--module", "GitHub.com/x/demo", "--dry-run", "--no-input"}, codeInvalidName},
+		{[]string{"new", "-l", "go", "--no-input"}, codeUsage},
+		{[]string{"new", "a", "b"}, codeUsage},
+		{[]string{"new", "--bogus"}, codeUsage},
+		{[]string{"new", "-l", "go", "demo", "--set", "noequals", "--no-input"}, codeUsage},
+		{[]string{"new", "-l", "go", "demo", "--set", "ProjectName=x", "--no-input"}, codeInvalidVariable},
+		{[]string{"new", "-l", "go", "demo", "--set", "name=x", "--dry-run", "--no-input"}, codeInvalidVariable},
+	}
+	for _, tt := range tests {
+		err := runJSON(t, testCreator(), nil, tt.args...)
+		if got := errorCode(err); got != tt.want {
+			t.Errorf("%v: errorCode(%v) = %q, want %q", tt.args, err, got, tt.want)
+		}
+	}
+
+	if got := errorCode(fmt.Errorf("wrapped: %w", context.Canceled)); got != codeInterrupted {
+		t.Errorf("errorCode(canceled) = %q, want %q", got, codeInterrupted)
+	}
+	for cause, want := range map[error]string{
+		git.ErrNotFound:     codeGitNotFound,
+		git.ErrNoIdentity:   codeGitIdentity,
+		git.ErrHookRejected: codeGitHookRejected,
+	} {
+		if got := errorCode(fmt.Errorf("wrapped: %w", cause)); got != want {
+			t.Errorf("errorCode(%v) = %q, want %q", cause, got, want)
+		}
+	}
+	if got := errorCode(errors.New("boom")); got != codeError {
+		t.Errorf("errorCode(plain) = %q, want %q", got, codeError)
+	}
+}
+
+func TestExecute_Errors(t *testing.T) {
+	tests := []struct {
+		args []string
+		code string
+	}{
+		{[]string{"--output", "json", "bogus"}, codeUsage},
+		{[]string{"--output=json", "--bogus"}, codeUsage},
+		{[]string{"new", "--output", "json", "-l", "cobol", "demo", "--no-input"}, codeUnsupportedLang},
+	}
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		root := newRootCmd(testCreator())
+		root.SetOut(&stdout)
+		root.SetErr(&stderr)
+		if status := execute(context.Background(), root, tt.args, &stdout, &stderr); status != 1 {
+			t.Errorf("%v: status = %d, want 1", tt.args, status)
+		}
+		var got errorJSON
+		dec := json.NewDecoder(&stdout)
+		if err := dec.Decode(&got); err != nil {
+			t.Errorf("%v: stdout is not JSON: %v\n%s", tt.args, err, stdout.String())
+			continue
+		}
+		if dec.More() {
+			t.Errorf("%v: stdout has more than one JSON document", tt.args)
+		}
+		if got.Error.Code != tt.code || got.Error.Message == "" {
+			t.Errorf("%v: error = %+v, want code %q", tt.args, got.Error, tt.code)
+		}
+		if stderr.Len() != 0 {
+			t.Errorf("%v: stderr = %q, want nothing", tt.args, stderr.String())
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	root := newRootCmd(testCreator())
+	root.SetOut(&stdout)
+	root.SetErr(&stderr)
+	if status := execute(context.Background(), root, []string{"bogus"}, &stdout, &stderr); status != 1 {
+		t.Errorf("status = %d, want 1", status)
+	}
+	if got := strings.Count(stderr.String(), `unknown command "bogus"`); got != 1 {
+		t.Errorf("stderr = %q, want the error printed once", stderr.String())
+	}
+}
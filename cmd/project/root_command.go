@@ -1,8 +1,13 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/JackDrogon/project/pkg/scaffold"
 	"github.com/spf13/cobra"
@@ -10,14 +15,39 @@ import (
 
 // newRootCmd builds the command tree with all subcommands registered explicitly.
 func newRootCmd(creator *scaffold.Creator) *cobra.Command {
+	var templateDirs []string
+	var output string
+
 	rootCmd := &cobra.Command{
 		Use:   "project",
 		Short: "project is a tool to create new project",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkOutputFormat(output); err != nil && legacyArchiveFile(cmd) == "" {
+				return err
+			}
+			silenceForJSON(cmd)
+			layers, err := templateLayers(templateDirs)
+			if err != nil {
+				return err
+			}
+			creator.Overlay(layers...)
+			return nil
+		},
 	}
 
+	rootCmd.PersistentFlags().StringArrayVar(&templateDirs, "template-dir", nil,
+		"Directory of local templates layered over the built-in ones (repeatable, first wins)")
+	rootCmd.PersistentFlags().StringVar(&output, "output", outputText,
+		"Output format: text, or json for a single JSON document on stdout")
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		silenceForJSON(cmd)
+		return withCode(codeUsage, err)
+	})
+
 	rootCmd.AddCommand(
 		newNewCmd(creator),
 		newListCmd(creator),
+		newTemplateCmd(creator),
 		newVersionCmd(),
 		newCompletionCmd(),
 	)
@@ -25,12 +55,79 @@ func newRootCmd(creator *scaffold.Creator) *cobra.Command {
 	return rootCmd
 }
 
+// exitInterrupted is the exit status after SIGINT or SIGTERM, following
+// the shell convention of 128 + SIGINT.
+const exitInterrupted = 130
+
 // Execute runs the root command.
 // If an error occurs during execution, it prints the error to stderr
-// and exits the program with status code 1.
+// and exits the program with status code 1. On SIGINT or SIGTERM the
+// command's context is canceled so it can stop child processes and remove
+// what it created; the program then exits with status 130. A second signal
+// terminates immediately. With --output json, errors are printed to stdout
+// as a JSON object with a stable code instead.
 func Execute(creator *scaffold.Creator) {
-	if err := newRootCmd(creator).Execute(); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	status := execute(ctx, newRootCmd(creator), os.Args[1:], os.Stdout, os.Stderr)
+	stop()
+	if status != 0 {
+		os.Exit(status)
+	}
+}
+
+// execute runs rootCmd with args and returns the exit status, printing any
+// error once: as JSON on stdout when args ask for --output json, else on
+// stderr. args are checked before cobra parses them so that errors cobra
+// raises before binding the flag, such as an unknown command, are JSON too.
+func execute(ctx context.Context, rootCmd *cobra.Command, args []string, stdout, stderr io.Writer) int {
+	asJSON := jsonRequested(args)
+	rootCmd.SilenceErrors = true
+	if asJSON {
+		rootCmd.SilenceUsage = true
+	}
+	rootCmd.SetArgs(args)
+	cmd, err := rootCmd.ExecuteContextC(ctx)
+	if err == nil && ctx.Err() == nil {
+		return 0
+	}
+
+	if errors.Is(err, errReported) && ctx.Err() == nil {
+		return 1
+	}
+	// The root command has no action of its own, so the errors it returns
+	// come from cobra not finding a subcommand.
+	if cmd == rootCmd && errorCode(err) == codeError {
+		err = withCode(codeUsage, err)
+	}
+
+	code, message, status := errorCode(err), fmt.Sprint(err), 1
+	if ctx.Err() != nil {
+		code, message, status = codeInterrupted, "Interrupted", exitInterrupted
+	}
+	if asJSON || jsonOutput(rootCmd) {
+		_ = writeJSON(stdout, newErrorJSON(code, message))
+	} else {
+		fmt.Fprintln(stderr, "Error:", message)
+	}
+	return status
+}
+
+// jsonRequested reports whether args set the global --output flag to json.
+func jsonRequested(args []string) bool {
+	for i, arg := range args {
+		switch {
+		case arg == "--":
+			return false
+		case arg == "--output="+outputJSON:
+			return true
+		case arg == "--output" && i+1 < len(args) && args[i+1] == outputJSON:
+			return true
+		}
 	}
+	return false
 }
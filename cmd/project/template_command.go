@@ -0,0 +1,468 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/JackDrogon/project/pkg/scaffold"
+	"github.com/JackDrogon/project/pkg/scaffold/golden"
+	"github.com/spf13/cobra"
+)
+
+// newTemplateCmd creates the "template" command group for template authors.
+func newTemplateCmd(creator *scaffold.Creator) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "template",
+		Short: "Tools for writing and publishing templates",
+	}
+	cmd.AddCommand(newTemplateLintCmd(creator), newTemplateTestCmd(creator), newTemplateVerifyCmd(creator), newTemplatePackCmd())
+	return cmd
+}
+
+// newTemplatePackCmd creates the "template pack" subcommand that bundles a
+// template directory into an archive with a checksum file.
+func newTemplatePackCmd() *cobra.Command {
+	var format string
+	var outputFile string
+	var name string
+
+	cmd := &cobra.Command{
+		Use:   "pack <dir>",
+		Short: "Check a template directory and pack it into an archive",
+		Long: "Check a template directory and pack it into an archive.\n\n" +
+			"The directory is laid out like pkg/templates/go. Every .tmpl file must parse, and the\n" +
+			"archive is written with a <archive>.sha256 checksum file beside it. Packing the same\n" +
+			"template always produces the same bytes.",
+		Args: usageArgs(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			archiveFormat, err := scaffold.ParseArchiveFormat(format)
+			if err != nil {
+				return withCode(codeUsage, err)
+			}
+			dir := args[0]
+			info, err := os.Stat(dir)
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() {
+				return usageErrorf("%s is not a directory", dir)
+			}
+			if name == "" {
+				abs, err := filepath.Abs(dir)
+				if err != nil {
+					return err
+				}
+				name = filepath.Base(abs)
+			}
+			if outputFile == "" {
+				outputFile = name + archiveFormat.Ext()
+			}
+			if inside, err := isWithin(outputFile, dir); err != nil {
+				return err
+			} else if inside {
+				return usageErrorf("archive %s would be inside the template %s and packed into the next archive; use --output-file to write it elsewhere", outputFile, dir)
+			}
+			cmd.SilenceUsage = true
+
+			var buf bytes.Buffer
+			if err := scaffold.PackTemplate(&buf, os.DirFS(dir), archiveFormat, name); err != nil {
+				return fmt.Errorf("template %s: %w", dir, err)
+			}
+			sum := sha256.Sum256(buf.Bytes())
+			digest := hex.EncodeToString(sum[:])
+			checksumFile := outputFile + ".sha256"
+
+			if err := writeFileAtomic(outputFile, func(w io.Writer) error {
+				_, err := w.Write(buf.Bytes())
+				return err
+			}); err != nil {
+				return err
+			}
+			// The sha256sum format, so `sha256sum -c` can verify the archive.
+			if err := writeFileAtomic(checksumFile, func(w io.Writer) error {
+				_, err := fmt.Fprintf(w, "%s  %s\n", digest, filepath.Base(outputFile))
+				return err
+			}); err != nil {
+				return err
+			}
+
+			if jsonOutput(cmd) {
+				return writeJSON(cmd.OutOrStdout(), packJSON{
+					Archive:  outputFile,
+					Checksum: checksumFile,
+					SHA256:   digest,
+					Format:   string(archiveFormat),
+				})
+			}
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Packed %s\nsha256 %s\n", outputFile, digest)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", string(scaffold.ArchiveTgz), "Archive format: tar, tgz, or zip")
+	cmd.Flags().StringVar(&outputFile, "output-file", "", "Archive file to write, outside the template directory (default <name>.<ext>)")
+	cmd.Flags().StringVar(&name, "name", "", "Top-level directory inside the archive (default the directory's name)")
+	return cmd
+}
+
+// isWithin reports whether the file name, which need not exist, is in dir
+// or below it, following symbolic links.
+func isWithin(name, dir string) (bool, error) {
+	dir, err := realPath(dir)
+	if err != nil {
+		return false, err
+	}
+	parent, err := realPath(filepath.Dir(name))
+	if err != nil {
+		return false, err
+	}
+	rel, err := filepath.Rel(dir, filepath.Join(parent, filepath.Base(name)))
+	return err == nil && filepath.IsLocal(rel), nil
+}
+
+// realPath returns the absolute path of name with symbolic links resolved,
+// or just the absolute path if name does not exist.
+func realPath(name string) (string, error) {
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		return resolved, nil
+	}
+	return abs, nil
+}
+
+// packJSON is the output of "template pack" with --output json.
+type packJSON struct {
+	Archive  string `json:"archive"`
+	Checksum string `json:"checksum_file"`
+	SHA256   string `json:"sha256"`
+	Format   string `json:"format"`
+}
+
+// newTemplateLintCmd creates the "template lint" subcommand that reports
+// common mistakes in a template directory or a built-in template.
+func newTemplateLintCmd(creator *scaffold.Creator) *cobra.Command {
+	var strict bool
+
+	cmd := &cobra.Command{
+		Use:   "lint <dir|lang>",
+		Short: "Check a template for common mistakes",
+		Long: "Check a template for common mistakes.\n\n" +
+			"The argument is a template directory or the name of an available template. lint reports\n" +
+			".tmpl files that fail to parse, references to undeclared variables, declared variables that\n" +
+			"are never used, files containing {{ without a .tmpl suffix, suspicious file modes, and CRLF\n" +
+			"line endings or byte order marks. It exits with status 1 if any errors are found, or any\n" +
+			"warnings with --strict.",
+		Args: usageArgs(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			target := args[0]
+			fsys, prefix, err := lintTarget(creator, target)
+			if err != nil {
+				return err
+			}
+			cmd.SilenceUsage = true
+
+			issues, err := scaffold.LintTemplate(fsys)
+			if err != nil {
+				return fmt.Errorf("template %s: %w", target, err)
+			}
+			var errs, warnings int
+			for _, issue := range issues {
+				if issue.Severity == scaffold.SeverityError {
+					errs++
+				} else {
+					warnings++
+				}
+			}
+
+			if jsonOutput(cmd) {
+				result := lintJSON{Template: target, Issues: []lintIssueJSON{}, Errors: errs, Warnings: warnings}
+				for _, issue := range issues {
+					result.Issues = append(result.Issues, lintIssueJSON{
+						Path:     issue.Path,
+						Line:     issue.Line,
+						Severity: string(issue.Severity),
+						Rule:     issue.Rule,
+						Message:  issue.Message,
+					})
+				}
+				if err := writeJSON(cmd.OutOrStdout(), result); err != nil {
+					return err
+				}
+			} else {
+				out := cmd.OutOrStdout()
+				for _, issue := range issues {
+					// Paths relative to the working directory, so editors can jump to them.
+					issue.Path = filepath.Join(prefix, filepath.FromSlash(issue.Path))
+					_, _ = fmt.Fprintln(out, issue)
+				}
+				_, _ = fmt.Fprintf(out, "%s: %d errors, %d warnings\n", target, errs, warnings)
+			}
+
+			if errs > 0 || (strict && warnings > 0) {
+				cmd.SilenceErrors = true
+				return errReported
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&strict, "strict", false, "Exit with status 1 on warnings too")
+	return cmd
+}
+
+// lintTarget resolves the argument of "template lint": a directory if one
+// exists, otherwise an available template. prefix is the directory to
+// prepend to issue paths in text output.
+func lintTarget(creator *scaffold.Creator, target string) (fsys fs.FS, prefix string, err error) {
+	if info, err := os.Stat(target); err == nil && info.IsDir() {
+		return os.DirFS(target), target, nil
+	}
+	fsys, err = creator.TemplateFS(target)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s is neither a template directory nor an available template: %w", target, err)
+	}
+	return fsys, "", nil
+}
+
+// newTemplateTestCmd creates the "template test" subcommand that renders a
+// template and compares it with golden directories; see package golden.
+func newTemplateTestCmd(creator *scaffold.Creator) *cobra.Command {
+	var goldenDir string
+	var update bool
+
+	cmd := &cobra.Command{
+		Use:   "test <dir|lang>",
+		Short: "Render a template and compare it with golden output",
+		Long: "Render a template and compare it with golden output.\n\n" +
+			"The argument is a template directory or the name of an available template. The golden\n" +
+			"directory lists cases, each a set of variables, in cases.yaml and holds the expected project\n" +
+			"for each case in a subdirectory named after it. --update rewrites those subdirectories with\n" +
+			"the rendered output. The command exits with status 1 if any case differs.",
+		Args: usageArgs(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			target := args[0]
+			creator, lang, err := templateCreator(creator, target)
+			if err != nil {
+				return err
+			}
+			if goldenDir == "" {
+				goldenDir = filepath.Join("testdata", lang)
+			}
+			cases, err := golden.LoadCases(goldenDir)
+			if err != nil {
+				return err
+			}
+			cmd.SilenceUsage = true
+
+			result := testJSON{Template: target, Golden: goldenDir, Cases: []testCaseJSON{}}
+			failed := false
+			for _, c := range cases {
+				mismatches, err := golden.Check(cmd.Context(), creator, lang, goldenDir, c, update)
+				if err != nil {
+					return fmt.Errorf("case %s: %w", c.Name, err)
+				}
+				status := "ok"
+				switch {
+				case update:
+					status = "updated"
+				case len(mismatches) > 0:
+					status = "fail"
+					failed = true
+				}
+				tc := testCaseJSON{Name: c.Name, Status: status, Mismatches: []mismatchJSON{}}
+				for _, m := range mismatches {
+					tc.Mismatches = append(tc.Mismatches, mismatchJSON{Path: m.Path, Kind: m.Kind, Diff: m.Diff})
+				}
+				result.Cases = append(result.Cases, tc)
+
+				if !jsonOutput(cmd) {
+					out := cmd.OutOrStdout()
+					_, _ = fmt.Fprintf(out, "%-7s %s\n", status, c.Name)
+					for _, m := range mismatches {
+						_, _ = fmt.Fprintln(out, m)
+					}
+				}
+			}
+
+			if jsonOutput(cmd) {
+				if err := writeJSON(cmd.OutOrStdout(), result); err != nil {
+					return err
+				}
+			}
+			if failed {
+				cmd.SilenceErrors = true
+				return errReported
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&goldenDir, "golden", "", "Golden directory with cases.yaml (default testdata/<name>)")
+	cmd.Flags().BoolVar(&update, "update", false, "Rewrite the golden output with the rendered projects")
+	return cmd
+}
+
+// newTemplateVerifyCmd creates the "template verify" subcommand that
+// generates a project and runs the checks its template declares.
+func newTemplateVerifyCmd(creator *scaffold.Creator) *cobra.Command {
+	var projectName string
+	var setValues []string
+	var valuesFile string
+	var noHooks bool
+
+	cmd := &cobra.Command{
+		Use:   "verify <dir|lang>",
+		Short: "Generate a project and check that it builds",
+		Long: "Generate a project and check that it builds.\n\n" +
+			"The argument is a template directory or the name of an available template. The project is\n" +
+			"generated in a temporary directory, hooks included, and the commands listed under verify in\n" +
+			"the template's template.yaml are run in it. A check whose required programs are not on PATH\n" +
+			"is skipped. The command exits with status 1 if any check fails.",
+		Args: usageArgs(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			vars, err := templateValues(valuesFile, setValues)
+			if err != nil {
+				return withCode(codeUsage, err)
+			}
+			target := args[0]
+			creator, lang, err := templateCreator(creator, target)
+			if err != nil {
+				return err
+			}
+			// Command output goes to stderr so stdout holds only the results.
+			creator.SetOutput(cmd.ErrOrStderr())
+			cmd.SilenceUsage = true
+
+			results, err := creator.Verify(cmd.Context(), scaffold.Options{
+				Lang:        lang,
+				ProjectName: projectName,
+				Vars:        vars,
+				NoHooks:     noHooks,
+			})
+			if err != nil {
+				return err
+			}
+
+			result := verifyJSON{Template: target, Checks: []checkJSON{}}
+			failed := false
+			for _, r := range results {
+				check := checkJSON{Name: r.Name, Command: r.Command, Status: string(r.Status), Missing: r.Missing}
+				if r.Err != nil {
+					check.Error = r.Err.Error()
+					failed = true
+				}
+				result.Checks = append(result.Checks, check)
+			}
+
+			if jsonOutput(cmd) {
+				if err := writeJSON(cmd.OutOrStdout(), result); err != nil {
+					return err
+				}
+			} else {
+				out := cmd.OutOrStdout()
+				if len(results) == 0 {
+					_, _ = fmt.Fprintf(out, "%s declares no verify checks\n", target)
+				}
+				for _, r := range results {
+					line := fmt.Sprintf("%-7s %s", r.Status, r.Name)
+					switch {
+					case r.Err != nil:
+						line += ": " + r.Err.Error()
+					case len(r.Missing) > 0:
+						line += " (" + strings.Join(r.Missing, ", ") + " not found)"
+					}
+					_, _ = fmt.Fprintln(out, line)
+				}
+			}
+			if failed {
+				cmd.SilenceErrors = true
+				return errReported
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&projectName, "name", "example", "Project name to generate")
+	cmd.Flags().StringArrayVar(&setValues, "set", nil, "Set a template variable as key=value (repeatable)")
+	cmd.Flags().StringVar(&valuesFile, "values", "", "YAML or JSON file with template variables")
+	cmd.Flags().BoolVar(&noHooks, "no-hooks", false, "Skip the template's pre and post hooks")
+	return cmd
+}
+
+// templateCreator resolves the argument of the template commands that
+// render a template: a directory if one exists, otherwise an available
+// template of creator. It returns the Creator to use and the template's
+// name in it.
+func templateCreator(creator *scaffold.Creator, target string) (*scaffold.Creator, string, error) {
+	if info, err := os.Stat(target); err == nil && info.IsDir() {
+		abs, err := filepath.Abs(target)
+		if err != nil {
+			return nil, "", err
+		}
+		lang := filepath.Base(abs)
+		return scaffold.NewCreator(scaffold.MountFS(lang, os.DirFS(target)), io.Discard), lang, nil
+	}
+	if _, err := creator.TemplateFS(target); err != nil {
+		return nil, "", fmt.Errorf("%s is neither a template directory nor an available template: %w", target, err)
+	}
+	return creator, target, nil
+}
+
+// verifyJSON is the output of "template verify" with --output json.
+type verifyJSON struct {
+	Template string      `json:"template"`
+	Checks   []checkJSON `json:"checks"`
+}
+
+type checkJSON struct {
+	Name    string   `json:"name"`
+	Command string   `json:"command"`
+	Status  string   `json:"status"` // passed, failed, or skipped
+	Missing []string `json:"missing,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// testJSON is the output of "template test" with --output json.
+type testJSON struct {
+	Template string         `json:"template"`
+	Golden   string         `json:"golden"`
+	Cases    []testCaseJSON `json:"cases"`
+}
+
+type testCaseJSON struct {
+	Name       string         `json:"name"`
+	Status     string         `json:"status"` // ok, fail, or updated
+	Mismatches []mismatchJSON `json:"mismatches"`
+}
+
+type mismatchJSON struct {
+	Path string `json:"path"`
+	Kind string `json:"kind"`
+	Diff string `json:"diff,omitempty"`
+}
+
+// lintJSON is the output of "template lint" with --output json.
+type lintJSON struct {
+	Template string          `json:"template"`
+	Issues   []lintIssueJSON `json:"issues"`
+	Errors   int             `json:"errors"`
+	Warnings int             `json:"warnings"`
+}
+
+type lintIssueJSON struct {
+	Path     string `json:"path"`
+	Line     int    `json:"line,omitempty"`
+	Severity string `json:"severity"`
+	Rule     string `json:"rule"`
+	Message  string `json:"message"`
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/JackDrogon/project/pkg/scaffold"
+)
+
+// runCmd executes the command line and returns stdout.
+func runCmd(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	var stdout bytes.Buffer
+	root := newRootCmd(testCreator())
+	root.SetOut(&stdout)
+	root.SetErr(&bytes.Buffer{})
+	root.SetArgs(args)
+	err := root.Execute()
+	return stdout.String(), err
+}
+
+func TestTemplatePack(t *testing.T) {
+	tmp := t.TempDir()
+	dir := filepath.Join(tmp, "svc")
+	if err := os.MkdirAll(filepath.Join(dir, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"template.yaml": "description: svc\n",
+		"main.go.tmpl":  "package main // {{.ProjectName}}\n",
+		"bin/run":       "#!/bin/sh\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, filepath.FromSlash(name)), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	archive := filepath.Join(tmp, "out.tgz")
+	if _, err := runCmd(t, "template", "pack", dir, "--output-file", archive); err != nil {
+		t.Fatalf("template pack error = %v", err)
+	}
+	first, err := os.ReadFile(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(first)
+	checksum, err := os.ReadFile(archive + ".sha256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := hex.EncodeToString(sum[:]) + "  out.tgz\n"; string(checksum) != want {
+		t.Errorf("checksum file = %q, want %q", checksum, want)
+	}
+
+	if _, err := runCmd(t, "template", "pack", dir, "--output-file", archive); err != nil {
+		t.Fatalf("second template pack error = %v", err)
+	}
+	second, err := os.ReadFile(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first, second) {
+		t.Error("packing the same directory twice should produce identical archives")
+	}
+
+	t.Run("output inside the template", func(t *testing.T) {
+		for _, out := range []string{filepath.Join(dir, "svc.tgz"), filepath.Join(dir, "bin", "svc.tgz")} {
+			_, err := runCmd(t, "template", "pack", dir, "--output-file", out)
+			if err == nil || errorCode(err) != codeUsage {
+				t.Errorf("template pack --output-file %s error = %v, want a usage error", out, err)
+			}
+			if _, err := os.Stat(out); !os.IsNotExist(err) {
+				t.Errorf("no archive should be written inside the template, stat err = %v", err)
+			}
+		}
+	})
+
+	t.Run("invalid template", func(t *testing.T) {
+		if err := os.WriteFile(filepath.Join(dir, "bad.tmpl"), []byte("{{"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		out := filepath.Join(tmp, "bad.tgz")
+		if _, err := runCmd(t, "template", "pack", dir, "--output-file", out); err == nil {
+			t.Fatal("template pack expected error, got nil")
+		}
+		if _, err := os.Stat(out); !os.IsNotExist(err) {
+			t.Errorf("no archive should be written for an invalid template, stat err = %v", err)
+		}
+	})
+}
+
+func TestTemplateLint(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"template.yaml": "variables:\n  - name: license\n",
+		"main.go.tmpl":  "package main // {{.ProjectName}} {{.license}}\n",
+		"notes.md":      "see {{.ProjectName}}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out, err := runCmd(t, "template", "lint", dir)
+	if err != nil {
+		t.Fatalf("template lint with only warnings error = %v", err)
+	}
+	if !strings.Contains(out, filepath.Join(dir, "notes.md")+":1: warning:") {
+		t.Errorf("template lint output should report notes.md, got:\n%s", out)
+	}
+	if _, err := runCmd(t, "template", "lint", "--strict", dir); !errors.Is(err, errReported) {
+		t.Errorf("template lint --strict error = %v, want errReported", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "bad.tmpl"), []byte("{{.Nope}}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out, err = runCmd(t, "--output", "json", "template", "lint", dir)
+	if !errors.Is(err, errReported) {
+		t.Fatalf("template lint with errors error = %v, want errReported", err)
+	}
+	var got lintJSON
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("output is not a single JSON document: %v\n%s", err, out)
+	}
+	if got.Errors != 1 || got.Warnings != 1 || len(got.Issues) != 2 {
+		t.Fatalf("template lint = %+v, want 1 error and 1 warning", got)
+	}
+	if issue := got.Issues[0]; issue.Path != "bad.tmpl" || issue.Line != 1 || issue.Rule != scaffold.RuleUndefinedVar {
+		t.Errorf("first issue = %+v, want undefined variable in bad.tmpl:1", issue)
+	}
+
+	t.Run("available template", func(t *testing.T) {
+		out, err := runCmd(t, "template", "lint", "go")
+		if err != nil {
+			t.Fatalf("template lint go error = %v", err)
+		}
+		if !strings.Contains(out, "go: 0 errors") {
+			t.Errorf("template lint go output = %q", out)
+		}
+		if _, err := runCmd(t, "template", "lint", "nosuch"); !errors.Is(err, scaffold.ErrUnsupportedLang) {
+			t.Errorf("template lint nosuch error = %v, want ErrUnsupportedLang", err)
+		}
+	})
+}
+
+func TestTemplateTest(t *testing.T) {
+	golden := t.TempDir()
+	if err := os.WriteFile(filepath.Join(golden, "cases.yaml"), []byte("cases:\n  - name: default\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := runCmd(t, "template", "test", "go", "--golden", golden, "--update"); err != nil {
+		t.Fatalf("template test --update error = %v", err)
+	}
+	out, err := runCmd(t, "template", "test", "go", "--golden", golden)
+	if err != nil {
+		t.Fatalf("template test error = %v", err)
+	}
+	if !strings.Contains(out, "ok      default") {
+		t.Errorf("template test output = %q", out)
+	}
+
+	if err := os.WriteFile(filepath.Join(golden, "default", "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out, err = runCmd(t, "--output", "json", "template", "test", "go", "--golden", golden)
+	if !errors.Is(err, errReported) {
+		t.Fatalf("template test with drift error = %v, want errReported", err)
+	}
+	var got testJSON
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("output is not a single JSON document: %v\n%s", err, out)
+	}
+	if len(got.Cases) != 1 || got.Cases[0].Status != "fail" || len(got.Cases[0].Mismatches) != 1 ||
+		got.Cases[0].Mismatches[0].Path != "main.go" {
+		t.Errorf("template test = %+v, want main.go to differ", got)
+	}
+}
+
+func TestTemplateVerify(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	dir := t.TempDir()
+	manifest := "verify:\n" +
+		"  - name: readme\n" +
+		"    run: test -f README.md\n" +
+		"  - name: missing\n" +
+		"    run: test -f nope\n" +
+		"  - name: toolchain\n" +
+		"    run: build\n" +
+		"    requires: [no-such-program-xyz]\n"
+	for name, content := range map[string]string{"template.yaml": manifest, "README.md.tmpl": "# {{.ProjectName}}\n"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out, err := runCmd(t, "--output", "json", "template", "verify", dir)
+	if !errors.Is(err, errReported) {
+		t.Fatalf("template verify error = %v, want errReported", err)
+	}
+	var got verifyJSON
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("output is not a single JSON document: %v\n%s", err, out)
+	}
+	var statuses []string
+	for _, c := range got.Checks {
+		statuses = append(statuses, c.Name+" "+c.Status)
+	}
+	if want := "readme passed,missing failed,toolchain skipped"; strings.Join(statuses, ",") != want {
+		t.Errorf("template verify checks = %v, want %s", statuses, want)
+	}
+
+	out, err = runCmd(t, "template", "verify", "go")
+	if err != nil {
+		t.Fatalf("template verify go error = %v", err)
+	}
+	if !strings.Contains(out, "declares no verify checks") {
+		t.Errorf("template verify go output = %q", out)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// templatePathEnv lists extra template directories, separated like $PATH.
+const templatePathEnv = "PROJECT_TEMPLATE_PATH"
+
+// templateSearchPath returns the local template directories to layer over
+// the embedded templates, highest precedence first: --template-dir flags in
+// the order given, then $PROJECT_TEMPLATE_PATH entries, then
+// $XDG_CONFIG_HOME/project/templates. Directories named explicitly by flag
+// must exist; the others are skipped when missing.
+func templateSearchPath(flagDirs []string) ([]string, error) {
+	var dirs []string
+	for _, dir := range flagDirs {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return nil, fmt.Errorf("template directory %q: %w", dir, err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("template directory %q is not a directory", dir)
+		}
+		dirs = append(dirs, dir)
+	}
+
+	var optional []string
+	optional = append(optional, filepath.SplitList(os.Getenv(templatePathEnv))...)
+	if configDir := xdgConfigHome(); configDir != "" {
+		optional = append(optional, filepath.Join(configDir, "project", "templates"))
+	}
+	for _, dir := range optional {
+		if dir == "" {
+			continue
+		}
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs, nil
+}
+
+// xdgConfigHome returns $XDG_CONFIG_HOME, falling back to ~/.config.
+func xdgConfigHome() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config")
+}
+
+// templateLayers opens each directory on the search path as a template tree.
+func templateLayers(flagDirs []string) ([]fs.FS, error) {
+	dirs, err := templateSearchPath(flagDirs)
+	if err != nil {
+		return nil, err
+	}
+	layers := make([]fs.FS, 0, len(dirs))
+	for _, dir := range dirs {
+		layers = append(layers, os.DirFS(dir))
+	}
+	return layers, nil
+}
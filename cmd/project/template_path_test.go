@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTemplateSearchPath(t *testing.T) {
+	tmp := t.TempDir()
+	// layer writes a go template whose description names the layer.
+	layer := func(dir, name string) string {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Join(dir, "go"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		manifest := []byte("description: " + name + "\n")
+		if err := os.WriteFile(filepath.Join(dir, "go", "template.yaml"), manifest, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return dir
+	}
+	flagDir := layer(filepath.Join(tmp, "flag"), "flag")
+	envDir := layer(filepath.Join(tmp, "env"), "env")
+	configHome := filepath.Join(tmp, "config")
+	layer(filepath.Join(configHome, "project", "templates"), "user")
+	empty := filepath.Join(tmp, "empty")
+
+	tests := []struct {
+		name       string
+		flag       bool
+		env        string
+		configHome string
+		want       string
+	}{
+		{"flag over env", true, envDir, configHome, "flag"},
+		{"env over user dir", false, envDir, configHome, "env"},
+		{"user dir over embedded", false, "", configHome, "user"},
+		{"missing env entries skipped", false, filepath.Join(tmp, "missing"), configHome, "user"},
+		{"embedded", false, "", empty, "Go"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(templatePathEnv, tt.env)
+			t.Setenv("XDG_CONFIG_HOME", tt.configHome)
+			args := []string{"list"}
+			if tt.flag {
+				args = append(args, "--template-dir", flagDir)
+			}
+			var got listJSON
+			if err := runJSON(t, testCreator(), &got, args...); err != nil {
+				t.Fatalf("list error = %v", err)
+			}
+			if len(got.Templates) != 1 || got.Templates[0].Description != tt.want {
+				t.Errorf("templates = %+v, want the %s layer", got.Templates, tt.want)
+			}
+		})
+	}
+
+	t.Run("missing flag directory", func(t *testing.T) {
+		t.Setenv(templatePathEnv, "")
+		t.Setenv("XDG_CONFIG_HOME", empty)
+		if _, err := runCmd(t, "--template-dir", filepath.Join(tmp, "missing"), "list"); err == nil {
+			t.Error("list with a missing --template-dir should fail")
+		}
+	})
+}
@@ -15,15 +15,33 @@ func newVersionCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "version",
 		Short: "show version",
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if jsonOutput(cmd) {
+				d := version.Get()
+				return writeJSON(cmd.OutOrStdout(), versionJSON{
+					Tag:       d.Tag,
+					Revision:  d.Revision,
+					Dirty:     d.Dirty,
+					GoVersion: d.GoVersion,
+				})
+			}
 			if verbose {
-				fmt.Println(version.Verbose())
+				_, _ = fmt.Fprintln(cmd.OutOrStdout(), version.Verbose())
 			} else {
-				fmt.Println(version.Info())
+				_, _ = fmt.Fprintln(cmd.OutOrStdout(), version.Info())
 			}
+			return nil
 		},
 	}
 
 	cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Show detailed version info")
 	return cmd
 }
+
+// versionJSON is the output of "version" with --output json.
+type versionJSON struct {
+	Tag       string `json:"tag"`
+	Revision  string `json:"revision"`
+	Dirty     bool   `json:"dirty"`
+	GoVersion string `json:"go_version"`
+}
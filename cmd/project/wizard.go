@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/JackDrogon/project/pkg/prompt"
+	"github.com/JackDrogon/project/pkg/scaffold"
+)
+
+// isTerminal reports whether f is attached to an interactive terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// runWizard fills in the options not given on the command line by asking
+// the user: language, project name, module path, author, and year.
+func runWizard(ctx context.Context, p *prompt.Prompter, creator *scaffold.Creator, opts *scaffold.Options) error {
+	if opts.Lang == "" {
+		langs, err := creator.ListLangs()
+		if err != nil {
+			return err
+		}
+		lang, err := p.Select("Language", langs, "")
+		if err != nil {
+			return err
+		}
+		opts.Lang = lang
+	}
+
+	if opts.ProjectName == "" {
+		name, err := p.Input("Project name", "", func(s string) error { return creator.ValidateName(opts.Lang, s) })
+		if err != nil {
+			return err
+		}
+		opts.ProjectName = name
+	}
+
+	defaults := creator.DefaultVars(ctx, *opts)
+
+	module, err := p.Input("Module path", defaults.ModulePath, func(s string) error {
+		if err := validateModuleInput(s); err != nil {
+			return err
+		}
+		return creator.ValidateModulePath(opts.Lang, s)
+	})
+	if err != nil {
+		return err
+	}
+	opts.ModulePath = module
+
+	author, err := p.Input("Author", defaults.Author, nil)
+	if err != nil {
+		return err
+	}
+	opts.Author = author
+
+	year, err := p.Input("Year", strconv.Itoa(defaults.Year), validateYearInput)
+	if err != nil {
+		return err
+	}
+	opts.Year, _ = strconv.Atoi(year)
+
+	return nil
+}
+
+func validateModuleInput(s string) error {
+	if strings.ContainsAny(s, " \t") {
+		return fmt.Errorf("module path must not contain whitespace")
+	}
+	return nil
+}
+
+func validateYearInput(s string) error {
+	year, err := strconv.Atoi(s)
+	if err != nil || year < 1970 || year > 9999 {
+		return fmt.Errorf("year must be a number between 1970 and 9999")
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/JackDrogon/project/pkg/git"
+	"github.com/JackDrogon/project/pkg/prompt"
+	"github.com/JackDrogon/project/pkg/scaffold"
+)
+
+func TestRunWizard(t *testing.T) {
+	creator := scaffold.NewCreator(fstest.MapFS{
+		"cpp/CMakeLists.txt": {Data: []byte("project()")},
+		"go/go.mod.tmpl":     {Data: []byte("module {{.ModulePath}}")},
+	}, &bytes.Buffer{})
+	creator.SetGit(&git.Fake{}) // no defaults from the machine's git config
+
+	t.Run("asks for everything with re-prompting", func(t *testing.T) {
+		input := strings.Join([]string{
+			"rust",                // not a choice
+			"2",                   // go
+			"1bad",                // invalid project name
+			"myapp",               // project name
+			"github.com/x/my app", // whitespace rejected
+			"github.com/x/myapp",
+			"",   // default author
+			"19", // invalid year
+			"2030",
+		}, "\n") + "\n"
+
+		var opts scaffold.Options
+		var out bytes.Buffer
+		if err := runWizard(context.Background(), prompt.New(strings.NewReader(input), &out), creator, &opts); err != nil {
+			t.Fatalf("runWizard() error = %v\noutput:\n%s", err, out.String())
+		}
+
+		if opts.Lang != "go" || opts.ProjectName != "myapp" || opts.ModulePath != "github.com/x/myapp" || opts.Year != 2030 {
+			t.Errorf("runWizard() opts = %+v", opts)
+		}
+		if opts.Author == "" {
+			t.Error("Author should default to a non-empty value")
+		}
+	})
+
+	t.Run("skips values given on the command line", func(t *testing.T) {
+		opts := scaffold.Options{Lang: "cpp", ProjectName: "demo"}
+		input := "\nalice\n\n"
+		if err := runWizard(context.Background(), prompt.New(strings.NewReader(input), &bytes.Buffer{}), creator, &opts); err != nil {
+			t.Fatalf("runWizard() error = %v", err)
+		}
+		if opts.Lang != "cpp" || opts.ModulePath != "demo" || opts.Author != "alice" {
+			t.Errorf("runWizard() opts = %+v", opts)
+		}
+	})
+
+	t.Run("closed input fails", func(t *testing.T) {
+		var opts scaffold.Options
+		err := runWizard(context.Background(), prompt.New(strings.NewReader(""), &bytes.Buffer{}), creator, &opts)
+		if !errors.Is(err, prompt.ErrInputClosed) {
+			t.Fatalf("runWizard() error = %v, want ErrInputClosed", err)
+		}
+	})
+}
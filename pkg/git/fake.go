@@ -0,0 +1,239 @@
+package git
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"sync"
+)
+
+// Fake is an in-memory Backend for tests. It records what is done to each
+// repository instead of running git, so code that creates repositories can
+// be tested on machines without git.
+type Fake struct {
+	// Config holds the global configuration every repository sees, such
+	// as user.name and user.email. Commits fail with ErrNoIdentity when
+	// the author or committer, configured or given, lacks a name or email.
+	Config map[string]string
+	// Errs makes an operation fail: keys are "init", "add", "commit",
+	// "unstage", "remote", "branch", or "config".
+	Errs map[string]error
+
+	mu     sync.Mutex
+	opened []*FakeRepository
+	inited []*FakeRepository
+}
+
+// Open implements Backend. Opening the same directory again returns the
+// same repository.
+func (f *Fake) Open(dir string) Repository {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, r := range f.opened {
+		if r.Dir == dir {
+			return r
+		}
+	}
+	r := &FakeRepository{Dir: dir, fake: f}
+	f.opened = append(f.opened, r)
+	return r
+}
+
+// Root implements Backend: dir is in the initialized repository whose
+// directory contains it.
+func (f *Fake) Root(_ context.Context, dir string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, r := range f.inited {
+		if within(dir, r.Dir) {
+			return r.Dir, nil
+		}
+	}
+	return "", &Error{Args: []string{"rev-parse", "--show-toplevel"}, Err: fmt.Errorf("exit status 128"), cause: ErrNotRepository}
+}
+
+// within reports whether path is dir or below it.
+func within(path, dir string) bool {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && filepath.IsLocal(rel)
+}
+
+// Repositories returns the repositories initialized so far, in order.
+func (f *Fake) Repositories() []*FakeRepository {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*FakeRepository(nil), f.inited...)
+}
+
+// FakeRepository is a repository of a Fake. Its fields record the
+// repository's state and must only be read once the code under test is
+// done with it.
+type FakeRepository struct {
+	Dir         string
+	Initialized bool
+	Branch      string
+	Remotes     map[string]string
+	Staged      []string // paths passed to Add and not yet committed or unstaged
+	Commits     []FakeCommit
+
+	fake *Fake
+}
+
+// FakeCommit is a commit recorded by a FakeRepository.
+type FakeCommit struct {
+	ID        string
+	Author    Signature
+	Committer Signature
+	Staged    []string // the staged paths the commit recorded
+	CommitOptions
+}
+
+func (r *FakeRepository) fail(op string) error { return r.fake.Errs[op] }
+
+func (r *FakeRepository) Init(_ context.Context, opts InitOptions) error {
+	if err := r.fail("init"); err != nil {
+		return err
+	}
+	r.fake.mu.Lock()
+	defer r.fake.mu.Unlock()
+	if !r.Initialized {
+		r.fake.inited = append(r.fake.inited, r)
+	}
+	r.Initialized = true
+	r.Branch = opts.InitialBranch
+	if r.Branch == "" {
+		r.Branch = r.fake.Config["init.defaultBranch"]
+	}
+	if r.Branch == "" {
+		r.Branch = "master"
+	}
+	return nil
+}
+
+func (r *FakeRepository) Add(_ context.Context, paths ...string) error {
+	if err := r.fail("add"); err != nil {
+		return err
+	}
+	r.fake.mu.Lock()
+	defer r.fake.mu.Unlock()
+	if !r.Initialized {
+		return errNotRepository(r.Dir)
+	}
+	r.Staged = append(r.Staged, paths...)
+	return nil
+}
+
+func (r *FakeRepository) Unstage(_ context.Context, paths ...string) error {
+	if err := r.fail("unstage"); err != nil {
+		return err
+	}
+	r.fake.mu.Lock()
+	defer r.fake.mu.Unlock()
+	r.Staged, _ = r.split(paths)
+	return nil
+}
+
+// split divides the staged paths into those outside and those within
+// paths, relative to the work tree.
+func (r *FakeRepository) split(paths []string) (outside, inside []string) {
+	for _, staged := range r.Staged {
+		in := false
+		for _, p := range paths {
+			in = in || within(filepath.Join(r.Dir, staged), filepath.Join(r.Dir, p))
+		}
+		if in {
+			inside = append(inside, staged)
+		} else {
+			outside = append(outside, staged)
+		}
+	}
+	return outside, inside
+}
+
+func (r *FakeRepository) Commit(_ context.Context, opts CommitOptions) (string, error) {
+	if err := r.fail("commit"); err != nil {
+		return "", err
+	}
+	r.fake.mu.Lock()
+	defer r.fake.mu.Unlock()
+	if !r.Initialized {
+		return "", errNotRepository(r.Dir)
+	}
+
+	configured := Signature{Name: r.fake.Config["user.name"], Email: r.fake.Config["user.email"]}
+	c := FakeCommit{Author: configured, Committer: configured, CommitOptions: opts}
+	remaining := []string(nil)
+	c.Staged = r.Staged
+	if len(opts.Paths) > 0 {
+		remaining, c.Staged = r.split(opts.Paths)
+	}
+	if opts.Author != nil {
+		c.Author = *opts.Author
+	}
+	if opts.Committer != nil {
+		c.Committer = *opts.Committer
+	}
+	if c.Author.Name == "" || c.Author.Email == "" || c.Committer.Name == "" || c.Committer.Email == "" {
+		return "", &Error{Args: []string{"commit"}, Err: fmt.Errorf("exit status 128"), cause: ErrNoIdentity}
+	}
+
+	sum := sha1.Sum([]byte(fmt.Sprintf("%s\n%d\n%s", r.Dir, len(r.Commits), opts.Message)))
+	c.ID = hex.EncodeToString(sum[:])
+	r.Commits = append(r.Commits, c)
+	r.Staged = remaining
+	return c.ID, nil
+}
+
+func (r *FakeRepository) SetRemote(_ context.Context, name, url string) error {
+	if err := r.fail("remote"); err != nil {
+		return err
+	}
+	r.fake.mu.Lock()
+	defer r.fake.mu.Unlock()
+	if !r.Initialized {
+		return errNotRepository(r.Dir)
+	}
+	if r.Remotes == nil {
+		r.Remotes = make(map[string]string)
+	}
+	r.Remotes[name] = url
+	return nil
+}
+
+func (r *FakeRepository) CurrentBranch(context.Context) (string, error) {
+	if err := r.fail("branch"); err != nil {
+		return "", err
+	}
+	r.fake.mu.Lock()
+	defer r.fake.mu.Unlock()
+	if !r.Initialized {
+		return "", errNotRepository(r.Dir)
+	}
+	return r.Branch, nil
+}
+
+func (r *FakeRepository) Config(_ context.Context, key string) (string, error) {
+	if err := r.fail("config"); err != nil {
+		return "", err
+	}
+	r.fake.mu.Lock()
+	defer r.fake.mu.Unlock()
+	if value, ok := r.fake.Config[key]; ok {
+		return value, nil
+	}
+	return "", &Error{Args: []string{"config", "--get", key}, Err: fmt.Errorf("exit status 1"), cause: ErrNotSet}
+}
+
+func errNotRepository(dir string) error {
+	return &Error{Args: []string{"-C", dir}, Err: fmt.Errorf("not a git repository: %s", dir)}
+}
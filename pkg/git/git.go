@@ -1,17 +1,121 @@
+// Package git runs git for the rest of the program. Run, RunEnv, and
+// Output execute arbitrary commands; Repository is the narrower interface
+// project creation needs, with an implementation that shells out to git and
+// an in-memory Fake for tests. Failures are returned as *Error, which
+// matches ErrNotFound, ErrNoIdentity, ErrHookRejected, ErrNotSet, or
+// ErrNotRepository with errors.Is when the cause is known.
 package git
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"os"
 	"os/exec"
+	"strings"
+	"time"
 )
 
-// Run executes a git command in the given directory.
-func Run(dir string, args ...string) error {
-	cmd := exec.Command("git", args...)
-	cmd.Dir = dir
+// Causes of git failures, matched with errors.Is.
+var (
+	// ErrNotFound means the git executable is not installed or not on PATH.
+	ErrNotFound = errors.New("git not found")
+	// ErrNoIdentity means git has no user name or email to commit with.
+	ErrNoIdentity = errors.New("git identity not configured")
+	// ErrHookRejected means a commit hook, such as pre-commit, failed.
+	ErrHookRejected = errors.New("git hook rejected the commit")
+	// ErrNotSet means a config key has no value.
+	ErrNotSet = errors.New("git config key not set")
+	// ErrNotRepository means a directory is not in a git work tree.
+	ErrNotRepository = errors.New("not in a git repository")
+)
+
+// Error is a failed git command.
+type Error struct {
+	Args   []string // arguments after "git"
+	Output string   // what git printed, trimmed; stderr only for Output
+	Err    error    // the error from running the command
+	cause  error    // one of the Err* sentinels, if known
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("git %s failed: %v", e.Args[0], e.Err)
+	if e.cause != nil {
+		msg = fmt.Sprintf("git %s failed: %v", e.Args[0], e.cause)
+	}
+	if e.Output != "" {
+		msg += "\n" + e.Output
+	}
+	return msg
+}
+
+// Unwrap returns the cause, if known, and the underlying error.
+func (e *Error) Unwrap() []error {
+	if e.cause != nil {
+		return []error{e.cause, e.Err}
+	}
+	return []error{e.Err}
+}
+
+// newError builds the error for a failed git command, recognizing a
+// missing git executable.
+func newError(args []string, output []byte, err error) *Error {
+	e := &Error{Args: args, Output: strings.TrimSpace(string(output)), Err: err}
+	if errors.Is(err, exec.ErrNotFound) {
+		e.cause = ErrNotFound
+	}
+	return e
+}
+
+// Run executes a git command in the given directory. If ctx is done before
+// the command finishes, git is killed and ctx's error is returned.
+func Run(ctx context.Context, dir string, args ...string) error {
+	return RunEnv(ctx, dir, nil, args...)
+}
+
+// RunEnv is like Run with extra environment variables, such as
+// GIT_INDEX_FILE, added to the current environment.
+func RunEnv(ctx context.Context, dir string, env []string, args ...string) error {
+	cmd := command(ctx, dir, env, args)
 	output, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return fmt.Errorf("git %s: %w", args[0], ctx.Err())
+	}
 	if err != nil {
-		return fmt.Errorf("git %s failed: %w\n%s", args[0], err, string(output))
+		return newError(args, output, err)
 	}
 	return nil
 }
+
+// Output executes a git command in the given directory and returns its
+// standard output with surrounding whitespace trimmed.
+func Output(ctx context.Context, dir string, args ...string) (string, error) {
+	return OutputEnv(ctx, dir, nil, args...)
+}
+
+// OutputEnv is like Output with extra environment variables.
+func OutputEnv(ctx context.Context, dir string, env []string, args ...string) (string, error) {
+	cmd := command(ctx, dir, env, args)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if ctx.Err() != nil {
+		return "", fmt.Errorf("git %s: %w", args[0], ctx.Err())
+	}
+	if err != nil {
+		return "", newError(args, stderr.Bytes(), err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func command(ctx context.Context, dir string, env, args []string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	// Don't wait forever on children that keep the output pipe open.
+	cmd.WaitDelay = time.Second
+	return cmd
+}
@@ -0,0 +1,245 @@
+package git
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+)
+
+// Backend opens repositories. Exec runs the git executable; Fake keeps
+// repositories in memory for tests.
+type Backend interface {
+	// Open returns the repository whose work tree is dir. The repository
+	// need not exist yet; Init creates it.
+	Open(dir string) Repository
+	// Root returns the top of the work tree dir is in, or ErrNotRepository
+	// if it is in none.
+	Root(ctx context.Context, dir string) (string, error)
+}
+
+// Repository is a git repository in a work tree.
+type Repository interface {
+	// Init creates the repository.
+	Init(ctx context.Context, opts InitOptions) error
+	// Add stages paths, relative to the work tree.
+	Add(ctx context.Context, paths ...string) error
+	// Unstage undoes Add for paths, leaving the files alone.
+	Unstage(ctx context.Context, paths ...string) error
+	// Commit records the staged changes and returns the new commit id.
+	// It fails with ErrNoIdentity when git has no identity to commit with
+	// and opts does not supply one, and with ErrHookRejected when a commit
+	// hook rejects it.
+	Commit(ctx context.Context, opts CommitOptions) (string, error)
+	// SetRemote adds the remote name, or changes its URL if it exists.
+	SetRemote(ctx context.Context, name, url string) error
+	// CurrentBranch returns the branch HEAD points to, which need not have
+	// any commits yet.
+	CurrentBranch(ctx context.Context) (string, error)
+	// Config returns the effective value of key, such as user.name, or
+	// ErrNotSet.
+	Config(ctx context.Context, key string) (string, error)
+}
+
+// InitOptions configures Repository.Init.
+type InitOptions struct {
+	InitialBranch string // empty uses git's init.defaultBranch
+}
+
+// CommitOptions configures Repository.Commit.
+type CommitOptions struct {
+	Message    string
+	Author     *Signature // nil uses git's configured identity
+	Committer  *Signature // nil uses git's configured identity
+	Signoff    bool
+	Sign       bool   // sign with git's configured GPG or SSH key
+	SigningKey string // sign with this key instead; implies Sign
+	// Paths, when set, limits the commit to these paths, relative to the
+	// work tree; anything else staged is left staged.
+	Paths []string
+}
+
+// Signature is the name and email git records for an author or committer.
+type Signature struct {
+	Name  string
+	Email string
+}
+
+func (s Signature) String() string { return s.Name + " <" + s.Email + ">" }
+
+// env returns the environment variables that make git use s for role,
+// "AUTHOR" or "COMMITTER".
+func (s Signature) env(role string) []string {
+	return []string{"GIT_" + role + "_NAME=" + s.Name, "GIT_" + role + "_EMAIL=" + s.Email}
+}
+
+// Exec is the Backend that runs the git executable.
+type Exec struct{}
+
+// Open implements Backend.
+func (Exec) Open(dir string) Repository { return &execRepository{dir: dir} }
+
+// Root implements Backend.
+func (Exec) Root(ctx context.Context, dir string) (string, error) {
+	root, err := Output(ctx, dir, "rev-parse", "--show-toplevel")
+	var e *Error
+	if errors.As(err, &e) && e.cause == nil {
+		// rev-parse fails this way only outside a work tree, such as in a
+		// plain directory or inside .git.
+		e.cause = ErrNotRepository
+	}
+	return root, err
+}
+
+type execRepository struct {
+	dir string
+}
+
+func (r *execRepository) Init(ctx context.Context, opts InitOptions) error {
+	args := []string{"init"}
+	if opts.InitialBranch != "" {
+		args = append(args, "--initial-branch="+opts.InitialBranch)
+	}
+	return Run(ctx, r.dir, args...)
+}
+
+func (r *execRepository) Add(ctx context.Context, paths ...string) error {
+	return Run(ctx, r.dir, append([]string{"add", "--"}, paths...)...)
+}
+
+func (r *execRepository) Unstage(ctx context.Context, paths ...string) error {
+	if _, err := Output(ctx, r.dir, "rev-parse", "--verify", "--quiet", "HEAD"); err != nil {
+		// Nothing is committed yet, so there is nothing to reset to.
+		return Run(ctx, r.dir, append([]string{"rm", "--cached", "-r", "--quiet", "--ignore-unmatch", "--"}, paths...)...)
+	}
+	return Run(ctx, r.dir, append([]string{"reset", "--quiet", "--"}, paths...)...)
+}
+
+func (r *execRepository) Commit(ctx context.Context, opts CommitOptions) (string, error) {
+	var env []string
+	if opts.Author != nil {
+		env = append(env, opts.Author.env("AUTHOR")...)
+	}
+	if opts.Committer != nil {
+		env = append(env, opts.Committer.env("COMMITTER")...)
+	}
+	args := []string{"commit", "--quiet", "-m", opts.Message}
+	if opts.Signoff {
+		args = append(args, "--signoff")
+	}
+	switch {
+	case opts.SigningKey != "":
+		args = append(args, "--gpg-sign="+opts.SigningKey)
+	case opts.Sign:
+		args = append(args, "--gpg-sign")
+	}
+	if len(opts.Paths) > 0 {
+		args = append(append(args, "--only", "--"), opts.Paths...)
+	}
+
+	// git's messages are translated, so the cause of a failure is found
+	// from git's trace of the hooks it ran, and by asking git, rather than
+	// by reading them.
+	trace, err := os.CreateTemp("", "project-git-trace-")
+	if err != nil {
+		return "", err
+	}
+	_ = trace.Close()
+	defer os.Remove(trace.Name())
+
+	if err := RunEnv(ctx, r.dir, append(env, "GIT_TRACE2_EVENT="+trace.Name()), args...); err != nil {
+		var e *Error
+		if !errors.As(err, &e) || e.cause != nil {
+			return "", err
+		}
+		switch {
+		case !r.hasIdentity(ctx, env):
+			e.cause = ErrNoIdentity
+		case hookFailed(trace.Name()):
+			e.cause = ErrHookRejected
+		}
+		return "", e
+	}
+	return Output(ctx, r.dir, "rev-parse", "HEAD")
+}
+
+// hasIdentity reports whether git can name both the author and committer
+// of a commit made with env.
+func (r *execRepository) hasIdentity(ctx context.Context, env []string) bool {
+	for _, v := range []string{"GIT_AUTHOR_IDENT", "GIT_COMMITTER_IDENT"} {
+		if _, err := OutputEnv(ctx, r.dir, env, "var", v); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// commitHooks are the hooks that can reject a commit.
+var commitHooks = map[string]bool{"pre-commit": true, "prepare-commit-msg": true, "commit-msg": true}
+
+// hookFailed reports whether the trace2 event log at name records a
+// commit hook that exited with a non-zero status. Hooks that run git write
+// to the same log, so children are told apart by session and id.
+func hookFailed(name string) bool {
+	f, err := os.Open(name)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	type child struct {
+		sid string
+		id  int
+	}
+	hooks := make(map[child]bool)
+	dec := json.NewDecoder(f)
+	for {
+		var event struct {
+			Event    string `json:"event"`
+			SID      string `json:"sid"`
+			ChildID  int    `json:"child_id"`
+			Class    string `json:"child_class"`
+			HookName string `json:"hook_name"`
+			Code     int    `json:"code"`
+		}
+		if err := dec.Decode(&event); err != nil {
+			return false
+		}
+		c := child{event.SID, event.ChildID}
+		switch event.Event {
+		case "child_start":
+			if event.Class == "hook" && commitHooks[event.HookName] {
+				hooks[c] = true
+			}
+		case "child_exit":
+			if hooks[c] && event.Code != 0 {
+				return true
+			}
+		}
+	}
+}
+
+func (r *execRepository) SetRemote(ctx context.Context, name, url string) error {
+	if _, err := Output(ctx, r.dir, "remote", "get-url", name); err == nil {
+		return Run(ctx, r.dir, "remote", "set-url", name, url)
+	}
+	return Run(ctx, r.dir, "remote", "add", name, url)
+}
+
+func (r *execRepository) CurrentBranch(ctx context.Context) (string, error) {
+	return Output(ctx, r.dir, "symbolic-ref", "--short", "HEAD")
+}
+
+func (r *execRepository) Config(ctx context.Context, key string) (string, error) {
+	value, err := Output(ctx, r.dir, "config", "--get", key)
+	var e *Error
+	if errors.As(err, &e) && e.cause == nil {
+		// git config exits with status 1, and nothing else, for a missing key.
+		var exit *exec.ExitError
+		if errors.As(e.Err, &exit) && exit.ExitCode() == 1 {
+			e.cause = ErrNotSet
+		}
+	}
+	return value, err
+}
@@ -0,0 +1,250 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// isolate runs git as on a fresh machine: no configuration and no identity.
+func isolate(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", home)
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(home, "gitconfig"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	for _, name := range []string{"GIT_AUTHOR_NAME", "GIT_AUTHOR_EMAIL", "GIT_COMMITTER_NAME", "GIT_COMMITTER_EMAIL", "EMAIL"} {
+		t.Setenv(name, "")
+	}
+	// Without a domain, git can't guess an email from the hostname either.
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "user.useConfigOnly")
+	t.Setenv("GIT_CONFIG_VALUE_0", "true")
+}
+
+func TestExec(t *testing.T) {
+	isolate(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	repo := Exec{}.Open(dir)
+
+	if _, err := (Exec{}).Root(ctx, dir); !errors.Is(err, ErrNotRepository) {
+		t.Errorf("Root() before Init error = %v, want ErrNotRepository", err)
+	}
+	if err := repo.Init(ctx, InitOptions{InitialBranch: "trunk"}); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if root, err := (Exec{}).Root(ctx, sub); err != nil || !sameFile(root, dir) {
+		t.Errorf("Root(sub) = %q, %v, want %s", root, err, dir)
+	}
+	if branch, err := repo.CurrentBranch(ctx); err != nil || branch != "trunk" {
+		t.Errorf("CurrentBranch() = %q, %v, want trunk", branch, err)
+	}
+	for _, url := range []string{"https://example.com/a.git", "https://example.com/b.git"} {
+		if err := repo.SetRemote(ctx, "origin", url); err != nil {
+			t.Fatalf("SetRemote(%s) error = %v", url, err)
+		}
+	}
+	if url, err := repo.Config(ctx, "remote.origin.url"); err != nil || url != "https://example.com/b.git" {
+		t.Errorf("Config(remote.origin.url) = %q, %v", url, err)
+	}
+	if _, err := repo.Config(ctx, "user.name"); !errors.Is(err, ErrNotSet) {
+		t.Errorf("Config(user.name) error = %v, want ErrNotSet", err)
+	}
+
+	for _, name := range []string{"README", "sub/other"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("hi\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.Add(ctx, "."); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := repo.Unstage(ctx, "sub"); err != nil {
+		t.Fatalf("Unstage() before the first commit error = %v", err)
+	}
+	if _, err := repo.Commit(ctx, CommitOptions{Message: "init"}); !errors.Is(err, ErrNoIdentity) {
+		t.Fatalf("Commit() without an identity error = %v, want ErrNoIdentity", err)
+	}
+
+	sig := &Signature{Name: "Jane Doe", Email: "jane@example.com"}
+	id, err := repo.Commit(ctx, CommitOptions{Message: "init", Author: sig, Committer: sig})
+	if err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	if files, _ := Output(ctx, dir, "ls-files"); files != "README" {
+		t.Errorf("committed files = %q, want README only", files)
+	}
+	if head, err := Output(ctx, dir, "log", "-1", "--format=%H|%an <%ae>|%cn|%s"); err != nil ||
+		head != id+"|Jane Doe <jane@example.com>|Jane Doe|init" {
+		t.Errorf("HEAD = %q, %v, want commit %s", head, err, id)
+	}
+}
+
+func TestExec_CommitPaths(t *testing.T) {
+	isolate(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	repo := Exec{}.Open(dir)
+	if err := repo.Init(ctx, InitOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a", "b"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.Add(ctx, "a", "b"); err != nil {
+		t.Fatal(err)
+	}
+
+	sig := &Signature{Name: "Jane Doe", Email: "jane@example.com"}
+	if _, err := repo.Commit(ctx, CommitOptions{Message: "a", Author: sig, Committer: sig, Paths: []string{"a"}}); err != nil {
+		t.Fatalf("Commit(a) error = %v", err)
+	}
+	if files, _ := Output(ctx, dir, "ls-tree", "--name-only", "HEAD"); files != "a" {
+		t.Errorf("committed files = %q, want a", files)
+	}
+	if staged, _ := Output(ctx, dir, "diff", "--cached", "--name-only"); staged != "b" {
+		t.Errorf("staged after commit = %q, want b", staged)
+	}
+	if err := repo.Unstage(ctx, "b"); err != nil {
+		t.Fatalf("Unstage() error = %v", err)
+	}
+	if staged, _ := Output(ctx, dir, "diff", "--cached", "--name-only"); staged != "" {
+		t.Errorf("staged after Unstage = %q, want nothing", staged)
+	}
+}
+
+// sameFile reports whether a and b name the same directory, which git may
+// report with symbolic links resolved.
+func sameFile(a, b string) bool {
+	ai, err1 := os.Stat(a)
+	bi, err2 := os.Stat(b)
+	return err1 == nil && err2 == nil && os.SameFile(ai, bi)
+}
+
+func TestExec_HookRejected(t *testing.T) {
+	isolate(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	repo := Exec{}.Open(dir)
+	if err := repo.Init(ctx, InitOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	hook := filepath.Join(dir, ".git", "hooks", "pre-commit")
+	if err := os.WriteFile(hook, []byte("#!/bin/sh\nexit 1\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	sig := &Signature{Name: "Jane Doe", Email: "jane@example.com"}
+	_, err := repo.Commit(ctx, CommitOptions{Message: "init", Author: sig, Committer: sig})
+	if !errors.Is(err, ErrHookRejected) {
+		t.Errorf("Commit() error = %v, want ErrHookRejected", err)
+	}
+	var e *Error
+	if !errors.As(err, &e) || e.Args[0] != "commit" {
+		t.Errorf("Commit() error = %#v, want a *Error for commit", err)
+	}
+}
+
+func TestExec_SignFailureWithHooks(t *testing.T) {
+	isolate(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	repo := Exec{}.Open(dir)
+	if err := repo.Init(ctx, InitOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	hook := filepath.Join(dir, ".git", "hooks", "pre-commit")
+	if err := os.WriteFile(hook, []byte("#!/bin/sh\nexit 0\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := Run(ctx, dir, "config", "gpg.program", "false"); err != nil {
+		t.Fatal(err)
+	}
+
+	sig := &Signature{Name: "Jane Doe", Email: "jane@example.com"}
+	_, err := repo.Commit(ctx, CommitOptions{Message: "init", Author: sig, Committer: sig, Sign: true})
+	if err == nil {
+		t.Fatal("Commit() with a failing signer expected error, got nil")
+	}
+	if errors.Is(err, ErrHookRejected) || errors.Is(err, ErrNoIdentity) {
+		t.Errorf("Commit() error = %v, want it unclassified since the hook passed", err)
+	}
+}
+
+func TestRun_NotFound(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	err := Run(context.Background(), "", "status")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Run() without git error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestFake(t *testing.T) {
+	ctx := context.Background()
+	fake := &Fake{Config: map[string]string{"user.name": "CI"}}
+	repo := fake.Open("demo")
+	if fake.Open("demo") != repo {
+		t.Error("Open() should return the same repository for a directory")
+	}
+	if _, err := fake.Root(ctx, "demo/sub"); !errors.Is(err, ErrNotRepository) {
+		t.Errorf("Root() before Init error = %v, want ErrNotRepository", err)
+	}
+	if err := repo.Add(ctx, "."); err == nil {
+		t.Error("Add() before Init expected error, got nil")
+	}
+	if err := repo.Init(ctx, InitOptions{InitialBranch: "main"}); err != nil {
+		t.Fatal(err)
+	}
+	if root, err := fake.Root(ctx, "demo/sub"); err != nil || root != "demo" {
+		t.Errorf("Root(demo/sub) = %q, %v, want demo", root, err)
+	}
+	if err := repo.SetRemote(ctx, "origin", "https://example.com/demo.git"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Add(ctx, "."); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Config(ctx, "user.email"); !errors.Is(err, ErrNotSet) {
+		t.Errorf("Config(user.email) error = %v, want ErrNotSet", err)
+	}
+	if _, err := repo.Commit(ctx, CommitOptions{Message: "init"}); !errors.Is(err, ErrNoIdentity) {
+		t.Errorf("Commit() with only user.name error = %v, want ErrNoIdentity", err)
+	}
+	sig := &Signature{Name: "CI"}
+	if _, err := repo.Commit(ctx, CommitOptions{Message: "init", Author: sig, Committer: sig}); !errors.Is(err, ErrNoIdentity) {
+		t.Errorf("Commit() with an empty email error = %v, want ErrNoIdentity", err)
+	}
+	sig.Email = "ci@example.com"
+	id, err := repo.Commit(ctx, CommitOptions{Message: "init", Author: sig, Committer: sig})
+	if err != nil || len(id) != 40 {
+		t.Fatalf("Commit() = %q, %v", id, err)
+	}
+
+	r := fake.Repositories()[0]
+	if r.Branch != "main" || r.Remotes["origin"] != "https://example.com/demo.git" {
+		t.Errorf("repository = %+v", r)
+	}
+	if len(r.Commits) != 1 || r.Commits[0].ID != id || r.Commits[0].Message != "init" || len(r.Commits[0].Staged) != 1 {
+		t.Errorf("commits = %+v", r.Commits)
+	}
+
+	boom := errors.New("boom")
+	fake.Errs = map[string]error{"commit": boom}
+	if _, err := repo.Commit(ctx, CommitOptions{Author: sig, Committer: sig}); err != boom {
+		t.Errorf("Commit() with an injected error = %v, want %v", err, boom)
+	}
+}
@@ -0,0 +1,166 @@
+package prompt
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ErrInputClosed is returned when the input ends before an answer is given.
+var ErrInputClosed = errors.New("input closed before an answer was given")
+
+// Prompter asks questions on an input stream and writes prompts to out.
+// Invalid answers are reported and the question is asked again.
+type Prompter struct {
+	ctx context.Context
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// New returns a Prompter reading answers from in and writing to out.
+func New(in io.Reader, out io.Writer) *Prompter {
+	return NewContext(context.Background(), in, out)
+}
+
+// NewContext is like New, but a pending question is abandoned with ctx's
+// error once ctx is done, e.g. when the user presses Ctrl-C.
+func NewContext(ctx context.Context, in io.Reader, out io.Writer) *Prompter {
+	return &Prompter{ctx: ctx, in: bufio.NewReader(in), out: out}
+}
+
+// Input asks for a line of text. An empty answer selects def; validate, if
+// non-nil, must accept the answer or the question is repeated.
+func (p *Prompter) Input(label, def string, validate func(string) error) (string, error) {
+	for {
+		if def != "" {
+			_, _ = fmt.Fprintf(p.out, "%s [%s]: ", label, def)
+		} else {
+			_, _ = fmt.Fprintf(p.out, "%s: ", label)
+		}
+
+		answer, err := p.readLine()
+		if err != nil {
+			return "", err
+		}
+		if answer == "" {
+			answer = def
+		}
+		if answer == "" {
+			_, _ = fmt.Fprintln(p.out, "  a value is required")
+			continue
+		}
+		if validate != nil {
+			if err := validate(answer); err != nil {
+				_, _ = fmt.Fprintf(p.out, "  %v\n", err)
+				continue
+			}
+		}
+		return answer, nil
+	}
+}
+
+// Select asks the user to pick one of options, by number or by name.
+// An empty answer selects def when it is one of the options.
+func (p *Prompter) Select(label string, options []string, def string) (string, error) {
+	if len(options) == 0 {
+		return "", fmt.Errorf("%s: nothing to choose from", label)
+	}
+
+	_, _ = fmt.Fprintf(p.out, "%s:\n", label)
+	for i, option := range options {
+		_, _ = fmt.Fprintf(p.out, "  %d) %s\n", i+1, option)
+	}
+
+	answer, err := p.Input("Choose", def, func(answer string) error {
+		if n, err := strconv.Atoi(answer); err == nil {
+			if n < 1 || n > len(options) {
+				return fmt.Errorf("choose a number between 1 and %d", len(options))
+			}
+			return nil
+		}
+		for _, option := range options {
+			if option == answer {
+				return nil
+			}
+		}
+		return fmt.Errorf("%q is not one of the choices", answer)
+	})
+	if err != nil {
+		return "", err
+	}
+	if n, err := strconv.Atoi(answer); err == nil {
+		return options[n-1], nil
+	}
+	return answer, nil
+}
+
+// Confirm asks a yes/no question. An empty answer selects def.
+func (p *Prompter) Confirm(label string, def bool) (bool, error) {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+	for {
+		_, _ = fmt.Fprintf(p.out, "%s [%s]: ", label, hint)
+		answer, err := p.readLine()
+		if err != nil {
+			return false, err
+		}
+		switch strings.ToLower(answer) {
+		case "":
+			return def, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+		_, _ = fmt.Fprintln(p.out, "  please answer y or n")
+	}
+}
+
+// readLine reads one trimmed line, giving up when p.ctx is done. The read
+// itself cannot be interrupted, so it runs in its own goroutine; once
+// abandoned, the Prompter must not be used again.
+func (p *Prompter) readLine() (string, error) {
+	if p.ctx.Done() == nil {
+		return p.read()
+	}
+	if err := p.ctx.Err(); err != nil {
+		return "", err
+	}
+	type result struct {
+		line string
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		line, err := p.read()
+		done <- result{line, err}
+	}()
+	select {
+	case r := <-done:
+		return r.line, r.err
+	case <-p.ctx.Done():
+		return "", p.ctx.Err()
+	}
+}
+
+// read reads one trimmed line. A final line without a newline is accepted;
+// end of input with nothing read is ErrInputClosed.
+func (p *Prompter) read() (string, error) {
+	line, err := p.in.ReadString('\n')
+	if err != nil {
+		if errors.Is(err, io.EOF) && line != "" {
+			return strings.TrimSpace(line), nil
+		}
+		if errors.Is(err, io.EOF) {
+			return "", ErrInputClosed
+		}
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
@@ -0,0 +1,131 @@
+package prompt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestInput(t *testing.T) {
+	notFoo := func(s string) error {
+		if s == "foo" {
+			return fmt.Errorf("foo is not allowed")
+		}
+		return nil
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		def      string
+		validate func(string) error
+		want     string
+		wantErr  error
+	}{
+		{"answer", "bar\n", "", nil, "bar", nil},
+		{"trims spaces", "  bar  \n", "", nil, "bar", nil},
+		{"empty takes default", "\n", "def", nil, "def", nil},
+		{"no trailing newline", "bar", "", nil, "bar", nil},
+		{"empty without default reprompts", "\nbar\n", "", nil, "bar", nil},
+		{"invalid reprompts", "foo\nbar\n", "", notFoo, "bar", nil},
+		{"input closed", "", "", nil, "", ErrInputClosed},
+		{"input closed after invalid", "foo\n", "", notFoo, "", ErrInputClosed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			got, err := New(strings.NewReader(tt.input), &out).Input("Name", tt.def, tt.validate)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Input() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Input() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelect(t *testing.T) {
+	options := []string{"cpp", "go"}
+
+	tests := []struct {
+		name  string
+		input string
+		def   string
+		want  string
+	}{
+		{"by number", "2\n", "", "go"},
+		{"by name", "cpp\n", "", "cpp"},
+		{"default", "\n", "go", "go"},
+		{"out of range reprompts", "3\n1\n", "", "cpp"},
+		{"unknown name reprompts", "rust\ngo\n", "", "go"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			got, err := New(strings.NewReader(tt.input), &out).Select("Language", options, tt.def)
+			if err != nil {
+				t.Fatalf("Select() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Select() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("no options", func(t *testing.T) {
+		if _, err := New(strings.NewReader("1\n"), &bytes.Buffer{}).Select("Language", nil, ""); err == nil {
+			t.Fatal("Select() expected error, got nil")
+		}
+	})
+}
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		def   bool
+		want  bool
+	}{
+		{"yes", "y\n", false, true},
+		{"no", "no\n", true, false},
+		{"default true", "\n", true, true},
+		{"default false", "\n", false, false},
+		{"invalid reprompts", "maybe\nYES\n", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(strings.NewReader(tt.input), &bytes.Buffer{}).Confirm("Continue?", tt.def)
+			if err != nil {
+				t.Fatalf("Confirm() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Confirm() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewContext_Canceled(t *testing.T) {
+	// The pipe is never written to, so the read blocks until canceled.
+	r, w := io.Pipe()
+	defer w.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := NewContext(ctx, r, &bytes.Buffer{})
+	go cancel()
+
+	if _, err := p.Input("Name", "", nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("Input() error = %v, want %v", err, context.Canceled)
+	}
+	if _, err := p.Confirm("Again?", false); !errors.Is(err, context.Canceled) {
+		t.Errorf("Confirm() after cancel error = %v, want %v", err, context.Canceled)
+	}
+}
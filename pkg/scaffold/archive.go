@@ -0,0 +1,164 @@
+package scaffold
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"time"
+)
+
+// ArchiveFormat selects the container a project is written to instead of
+// a directory.
+type ArchiveFormat string
+
+const (
+	// ArchiveTar is an uncompressed tar archive.
+	ArchiveTar ArchiveFormat = "tar"
+	// ArchiveTgz is a gzip-compressed tar archive.
+	ArchiveTgz ArchiveFormat = "tgz"
+	// ArchiveZip is a zip archive.
+	ArchiveZip ArchiveFormat = "zip"
+)
+
+// ParseArchiveFormat parses an --output-format flag value.
+func ParseArchiveFormat(s string) (ArchiveFormat, error) {
+	switch f := ArchiveFormat(s); f {
+	case ArchiveTar, ArchiveTgz, ArchiveZip:
+		return f, nil
+	case "tar.gz":
+		return ArchiveTgz, nil
+	}
+	return "", fmt.Errorf("unknown output format %q: must be tar, tgz, or zip", s)
+}
+
+// Ext returns the conventional file extension for the format.
+func (f ArchiveFormat) Ext() string {
+	if f == ArchiveTgz {
+		return ".tar.gz"
+	}
+	return "." + string(f)
+}
+
+// archiveModTime is stamped on every entry so the same project always
+// produces the same archive. It is the earliest time zip can represent.
+var archiveModTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// WriteArchive writes every directory and regular file in fsys to w in the
+// given format, with entry names under prefix (usually the project name).
+// File permission bits are preserved; other metadata is fixed so the output is
+// reproducible.
+func WriteArchive(w io.Writer, fsys fs.FS, format ArchiveFormat, prefix string) error {
+	switch format {
+	case ArchiveTar:
+		return writeTar(w, fsys, prefix)
+	case ArchiveTgz:
+		gz := gzip.NewWriter(w)
+		if err := writeTar(gz, fsys, prefix); err != nil {
+			return err
+		}
+		return gz.Close()
+	case ArchiveZip:
+		return writeZip(w, fsys, prefix)
+	}
+	return fmt.Errorf("unknown output format %q", format)
+}
+
+// archiveEntry is one file or directory to be archived.
+type archiveEntry struct {
+	name  string // slash-separated, under the prefix; directories end in "/"
+	mode  fs.FileMode
+	isDir bool
+	src   string // path in the source filesystem
+}
+
+// archiveEntries lists fsys in lexical order, rooted at prefix.
+func archiveEntries(fsys fs.FS, prefix string) ([]archiveEntry, error) {
+	var entries []archiveEntry
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			// Directories are created 0755 on disk too, whatever the source says.
+			entries = append(entries, archiveEntry{name: path.Join(prefix, name) + "/", mode: 0755, isDir: true, src: name})
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return fmt.Errorf("cannot archive %s: not a regular file", name)
+		}
+		entries = append(entries, archiveEntry{name: path.Join(prefix, name), mode: fileMode(d), src: name})
+		return nil
+	})
+	return entries, err
+}
+
+func writeTar(w io.Writer, fsys fs.FS, prefix string) error {
+	entries, err := archiveEntries(fsys, prefix)
+	if err != nil {
+		return err
+	}
+
+	tw := tar.NewWriter(w)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: int64(e.mode), ModTime: archiveModTime}
+		if e.isDir {
+			hdr.Typeflag = tar.TypeDir
+			if err := tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+			continue
+		}
+
+		data, err := fs.ReadFile(fsys, e.src)
+		if err != nil {
+			return err
+		}
+		hdr.Typeflag = tar.TypeReg
+		hdr.Size = int64(len(data))
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+func writeZip(w io.Writer, fsys fs.FS, prefix string) error {
+	entries, err := archiveEntries(fsys, prefix)
+	if err != nil {
+		return err
+	}
+
+	zw := zip.NewWriter(w)
+	for _, e := range entries {
+		hdr := &zip.FileHeader{Name: e.name, Method: zip.Deflate, Modified: archiveModTime}
+		if e.isDir {
+			hdr.Method = zip.Store
+			hdr.SetMode(fs.ModeDir | e.mode)
+			if _, err := zw.CreateHeader(hdr); err != nil {
+				return err
+			}
+			continue
+		}
+
+		data, err := fs.ReadFile(fsys, e.src)
+		if err != nil {
+			return err
+		}
+		hdr.SetMode(e.mode)
+		fw, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
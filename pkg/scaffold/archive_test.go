@@ -0,0 +1,167 @@
+package scaffold
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// archivedEntry is an entry read back from an archive.
+type archivedEntry struct {
+	mode fs.FileMode
+	data string
+}
+
+func readArchive(t *testing.T, format ArchiveFormat, data []byte) map[string]archivedEntry {
+	t.Helper()
+	got := make(map[string]archivedEntry)
+
+	if format == ArchiveZip {
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatalf("zip.NewReader() error = %v", err)
+		}
+		for _, f := range zr.File {
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			content, _ := io.ReadAll(rc)
+			_ = rc.Close()
+			got[f.Name] = archivedEntry{mode: f.Mode().Perm(), data: string(content)}
+		}
+		return got
+	}
+
+	var r io.Reader = bytes.NewReader(data)
+	if format == ArchiveTgz {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			t.Fatalf("gzip.NewReader() error = %v", err)
+		}
+		r = gz
+	}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("tar Next() error = %v", err)
+		}
+		content, _ := io.ReadAll(tr)
+		got[hdr.Name] = archivedEntry{mode: fs.FileMode(hdr.Mode).Perm(), data: string(content)}
+	}
+	return got
+}
+
+func TestParseArchiveFormat(t *testing.T) {
+	for in, want := range map[string]ArchiveFormat{"tar": ArchiveTar, "tgz": ArchiveTgz, "tar.gz": ArchiveTgz, "zip": ArchiveZip} {
+		got, err := ParseArchiveFormat(in)
+		if err != nil || got != want {
+			t.Errorf("ParseArchiveFormat(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseArchiveFormat("rar"); err == nil {
+		t.Error("ParseArchiveFormat(rar) expected error, got nil")
+	}
+}
+
+func TestWriteArchive(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.go":       {Data: []byte("package main\n")},
+		"dev-tools/fmt": {Data: []byte("#!/bin/sh\n"), Mode: 0755},
+		"include":       {Mode: fs.ModeDir},
+	}
+
+	for _, format := range []ArchiveFormat{ArchiveTar, ArchiveTgz, ArchiveZip} {
+		t.Run(string(format), func(t *testing.T) {
+			var first, second bytes.Buffer
+			if err := WriteArchive(&first, fsys, format, "demo"); err != nil {
+				t.Fatalf("WriteArchive() error = %v", err)
+			}
+			if err := WriteArchive(&second, fsys, format, "demo"); err != nil {
+				t.Fatalf("WriteArchive() error = %v", err)
+			}
+			if !bytes.Equal(first.Bytes(), second.Bytes()) {
+				t.Error("WriteArchive() output is not reproducible")
+			}
+
+			got := readArchive(t, format, first.Bytes())
+			want := map[string]archivedEntry{
+				"demo/":              {mode: 0755},
+				"demo/dev-tools/":    {mode: 0755},
+				"demo/dev-tools/fmt": {mode: 0755, data: "#!/bin/sh\n"},
+				"demo/include/":      {mode: 0755},
+				"demo/main.go":       {mode: 0644, data: "package main\n"},
+			}
+			if len(got) != len(want) {
+				t.Errorf("archive entries = %v, want %v", got, want)
+			}
+			for name, w := range want {
+				if got[name] != w {
+					t.Errorf("%s = %+v, want %+v", name, got[name], w)
+				}
+			}
+		})
+	}
+}
+
+func TestCreate_Archive(t *testing.T) {
+	fsys := fstest.MapFS{
+		"go/main.go.tmpl": {Data: []byte("package main // {{.ProjectName}}")},
+		"go/run.sh":       {Data: []byte("#!/bin/sh\n"), Mode: 0755},
+	}
+
+	t.Run("without git", func(t *testing.T) {
+		dir := chdirTemp(t)
+
+		var archive, out bytes.Buffer
+		opts := Options{Lang: "go", ProjectName: "demo", Archive: ArchiveTgz, ArchiveOut: &archive}
+		if err := NewCreator(fsys, &out).Create(context.Background(), opts); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		got := readArchive(t, ArchiveTgz, archive.Bytes())
+		if got["demo/main.go"].data != "package main // demo" {
+			t.Errorf("demo/main.go = %+v", got["demo/main.go"])
+		}
+		if got["demo/run.sh"].mode != 0755 {
+			t.Errorf("demo/run.sh mode = %v, want 0755", got["demo/run.sh"].mode)
+		}
+		if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+			t.Errorf("Create() with an archive should not write to disk, found %v", entries)
+		}
+	})
+
+	t.Run("with git", func(t *testing.T) {
+		requireGit(t)
+		dir := chdirTemp(t)
+
+		var archive bytes.Buffer
+		opts := Options{Lang: "go", ProjectName: "demo", Archive: ArchiveZip, ArchiveOut: &archive, ArchiveGit: true}
+		if err := NewCreator(fsys, &bytes.Buffer{}).Create(context.Background(), opts); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		got := readArchive(t, ArchiveZip, archive.Bytes())
+		if _, ok := got["demo/.git/HEAD"]; !ok {
+			t.Error("archive should include demo/.git/HEAD")
+		}
+		for name := range got {
+			if strings.Contains(name, ".staging-") {
+				t.Errorf("entry %s leaks the staging directory name", name)
+			}
+		}
+		if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+			t.Errorf("staging directory should be removed, found %v", entries)
+		}
+	})
+}
@@ -0,0 +1,120 @@
+package scaffold
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ModulePrefixKey is the git config key that sets the prefix of derived
+// module paths, e.g. "github.com/myorg" to make project myapp's module
+// github.com/myorg/myapp.
+const ModulePrefixKey = "project.modulePrefix"
+
+// detected holds the defaults read from git for a directory.
+type detected struct {
+	name   string // user.name
+	email  string // user.email
+	prefix string // module path prefix
+	// origin is the remote URL prefix was derived from, or "" when it was
+	// set with ModulePrefixKey.
+	origin string
+}
+
+// prefixSource describes where d.prefix came from, for error messages.
+func (d detected) prefixSource() string {
+	if d.origin != "" {
+		return fmt.Sprintf("the origin remote %s", d.origin)
+	}
+	return "git config " + ModulePrefixKey
+}
+
+// DefaultVars returns the built-in variables for opts: the values set in
+// opts, and defaults for the rest. Author and AuthorEmail default to git's
+// user.name and user.email. ModulePath defaults to the project name under
+// the ModulePrefixKey prefix or, failing that, under the owner of the
+// origin remote of the repository the project is created in; with
+// neither, it is the bare project name.
+func (c *Creator) DefaultVars(ctx context.Context, opts Options) TemplateVars {
+	vars := NewTemplateVars(opts.ProjectName, opts.ModulePath)
+	d := c.detect(ctx, filepath.Dir(filepath.Clean(opts.ProjectName)))
+	if opts.ModulePath == "" && d.prefix != "" {
+		vars.ModulePath = path.Join(d.prefix, opts.ProjectName)
+	}
+	for _, author := range []string{opts.Author, d.name} {
+		if author != "" {
+			vars.Author = author
+			break
+		}
+	}
+	vars.AuthorEmail = opts.AuthorEmail
+	if vars.AuthorEmail == "" {
+		vars.AuthorEmail = d.email
+	}
+	if opts.Year != 0 {
+		vars.Year = opts.Year
+	}
+	return vars
+}
+
+// detect reads the defaults git provides in dir. They are best effort:
+// without git, or with nothing configured, they are empty.
+func (c *Creator) detect(ctx context.Context, dir string) detected {
+	if d, ok := c.detected[dir]; ok {
+		return d
+	}
+	repo := c.git.Open(dir)
+	get := func(key string) string {
+		value, _ := repo.Config(ctx, key)
+		return strings.TrimSpace(value)
+	}
+	d := detected{name: get("user.name"), email: get("user.email")}
+	d.prefix = strings.Trim(get(ModulePrefixKey), "/")
+	if d.prefix == "" {
+		origin := get("remote.origin.url")
+		if d.prefix = remotePrefix(origin); d.prefix != "" {
+			d.origin = origin
+		}
+	}
+	if ctx.Err() != nil {
+		return d
+	}
+	if c.detected == nil {
+		c.detected = make(map[string]detected)
+	}
+	c.detected[dir] = d
+	return d
+}
+
+// remotePrefix returns the module path prefix for a repository cloned from
+// remote: its host and owner, such as github.com/myorg for
+// git@github.com:myorg/mono.git. Remotes without both, such as local
+// paths, give "".
+func remotePrefix(remote string) string {
+	var host, p string
+	if u, err := url.Parse(remote); err == nil && u.Scheme != "" && u.Host != "" {
+		switch u.Scheme {
+		case "https", "http", "ssh", "git", "git+ssh", "ssh+git":
+			host, p = u.Hostname(), u.Path
+		}
+	} else if at, rest, ok := strings.Cut(remote, ":"); ok && !strings.Contains(at, "/") {
+		// scp-like syntax: [user@]host:path
+		_, host, _ = strings.Cut(at, "@")
+		if host == "" {
+			host = at
+		}
+		p = rest
+	}
+	if host == "" || !strings.Contains(host, ".") {
+		return ""
+	}
+	p = strings.TrimSuffix(strings.Trim(p, "/"), ".git")
+	owner := path.Dir(p)
+	if owner == "." || owner == "/" || strings.HasPrefix(owner, ".") {
+		return ""
+	}
+	return strings.ToLower(host) + "/" + owner
+}
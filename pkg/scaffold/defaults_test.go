@@ -0,0 +1,89 @@
+package scaffold
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/JackDrogon/project/pkg/git"
+)
+
+func TestRemotePrefix(t *testing.T) {
+	tests := map[string]string{
+		"git@github.com:myorg/mono.git":               "github.com/myorg",
+		"https://github.com/myorg/mono.git":           "github.com/myorg",
+		"https://GitHub.com/MyOrg/mono":               "github.com/MyOrg",
+		"ssh://git@gitlab.example.com:2222/a/b/c.git": "gitlab.example.com/a/b",
+		"github.com:myorg/mono.git":                   "github.com/myorg",
+		"https://github.com/mono.git":                 "",
+		"git@github.com:mono.git":                     "",
+		"/srv/git/mono.git":                           "",
+		"file:///srv/git/myorg/mono.git":              "",
+		"../mono":                                     "",
+		"":                                            "",
+	}
+	for remote, want := range tests {
+		if got := remotePrefix(remote); got != want {
+			t.Errorf("remotePrefix(%q) = %q, want %q", remote, got, want)
+		}
+	}
+}
+
+func TestDefaultVars(t *testing.T) {
+	fsys := fstest.MapFS{"go/go.mod.tmpl": {Data: []byte("module {{.ModulePath}}\n")}}
+	ctx := context.Background()
+	newCreator := func(config map[string]string) *Creator {
+		c := NewCreator(fsys, &bytes.Buffer{})
+		c.SetGit(&git.Fake{Config: config})
+		return c
+	}
+
+	t.Run("from git config", func(t *testing.T) {
+		c := newCreator(map[string]string{
+			"user.name":         "Jane Doe",
+			"user.email":        "jane@example.com",
+			ModulePrefixKey:     "github.com/acme/",
+			"remote.origin.url": "git@github.com:other/mono.git",
+		})
+		vars := c.DefaultVars(ctx, Options{ProjectName: "myapp"})
+		if vars.ModulePath != "github.com/acme/myapp" || vars.Author != "Jane Doe" || vars.AuthorEmail != "jane@example.com" {
+			t.Errorf("DefaultVars() = %+v", vars)
+		}
+	})
+
+	t.Run("from origin", func(t *testing.T) {
+		c := newCreator(map[string]string{"remote.origin.url": "https://github.com/myorg/mono.git"})
+		if vars := c.DefaultVars(ctx, Options{ProjectName: "myapp"}); vars.ModulePath != "github.com/myorg/myapp" {
+			t.Errorf("ModulePath = %q, want github.com/myorg/myapp", vars.ModulePath)
+		}
+	})
+
+	t.Run("options win", func(t *testing.T) {
+		c := newCreator(map[string]string{"user.name": "Jane Doe", "user.email": "jane@example.com", ModulePrefixKey: "github.com/acme"})
+		opts := Options{ProjectName: "myapp", ModulePath: "example.com/m", Author: "Bob", AuthorEmail: "bob@example.com", Year: 2020}
+		vars := c.DefaultVars(ctx, opts)
+		if vars.ModulePath != "example.com/m" || vars.Author != "Bob" || vars.AuthorEmail != "bob@example.com" || vars.Year != 2020 {
+			t.Errorf("DefaultVars() = %+v", vars)
+		}
+	})
+
+	t.Run("nothing configured", func(t *testing.T) {
+		vars := newCreator(nil).DefaultVars(ctx, Options{ProjectName: "myapp"})
+		if vars.ModulePath != "myapp" || vars.Author == "" || vars.AuthorEmail != "" {
+			t.Errorf("DefaultVars() = %+v", vars)
+		}
+	})
+
+	t.Run("rendered", func(t *testing.T) {
+		c := newCreator(map[string]string{ModulePrefixKey: "github.com/acme"})
+		files, err := c.Render(ctx, Options{Lang: "go", ProjectName: "myapp"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data, _ := fs.ReadFile(files, "go.mod"); string(data) != "module github.com/acme/myapp\n" {
+			t.Errorf("go.mod = %q", data)
+		}
+	})
+}
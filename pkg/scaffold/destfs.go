@@ -0,0 +1,236 @@
+package scaffold
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WritableFS is a destination for rendered projects. Names are slash
+// separated and relative to the destination root, as in io/fs.
+type WritableFS interface {
+	MkdirAll(name string, perm fs.FileMode) error
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+}
+
+// DiskFS is a WritableFS rooted at a directory on the local disk.
+type DiskFS struct {
+	root string
+}
+
+// NewDiskFS returns a WritableFS that writes under root.
+func NewDiskFS(root string) *DiskFS {
+	return &DiskFS{root: root}
+}
+
+func (d *DiskFS) path(op, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return filepath.Join(d.root, filepath.FromSlash(name)), nil
+}
+
+// MkdirAll implements WritableFS.
+func (d *DiskFS) MkdirAll(name string, perm fs.FileMode) error {
+	p, err := d.path("mkdir", name)
+	if err != nil {
+		return err
+	}
+	return os.MkdirAll(p, perm)
+}
+
+// WriteFile implements WritableFS.
+func (d *DiskFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	p, err := d.path("write", name)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p, data, perm)
+}
+
+// MemFS is an in-memory WritableFS that can be read back as an fs.FS.
+// It is safe for concurrent use.
+type MemFS struct {
+	mu      sync.RWMutex
+	entries map[string]*memEntry
+}
+
+type memEntry struct {
+	data  []byte
+	mode  fs.FileMode
+	isDir bool
+}
+
+// NewMemFS returns an empty in-memory filesystem.
+func NewMemFS() *MemFS {
+	return &MemFS{entries: map[string]*memEntry{
+		".": {mode: fs.ModeDir | 0755, isDir: true},
+	}}
+}
+
+// MkdirAll implements WritableFS.
+func (m *MemFS) MkdirAll(name string, perm fs.FileMode) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrInvalid}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.mkdirAll(name, perm)
+}
+
+func (m *MemFS) mkdirAll(name string, perm fs.FileMode) error {
+	if e, ok := m.entries[name]; ok {
+		if !e.isDir {
+			return &fs.PathError{Op: "mkdir", Path: name, Err: errors.New("not a directory")}
+		}
+		return nil
+	}
+	if err := m.mkdirAll(path.Dir(name), perm); err != nil {
+		return err
+	}
+	m.entries[name] = &memEntry{mode: fs.ModeDir | perm.Perm(), isDir: true}
+	return nil
+}
+
+// WriteFile implements WritableFS. Missing parent directories are created.
+func (m *MemFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	if !fs.ValidPath(name) || name == "." {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrInvalid}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e, ok := m.entries[name]; ok && e.isDir {
+		return &fs.PathError{Op: "write", Path: name, Err: errors.New("is a directory")}
+	}
+	if err := m.mkdirAll(path.Dir(name), 0755); err != nil {
+		return err
+	}
+	m.entries[name] = &memEntry{data: bytes.Clone(data), mode: perm.Perm()}
+	return nil
+}
+
+// Open implements fs.FS.
+func (m *MemFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	e, ok := m.entries[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	info := memInfo{name: path.Base(name), entry: e}
+	if !e.isDir {
+		return &memFile{info: info, r: bytes.NewReader(e.data)}, nil
+	}
+
+	var children []fs.DirEntry
+	prefix := name + "/"
+	if name == "." {
+		prefix = ""
+	}
+	for p, child := range m.entries {
+		if p == "." || !strings.HasPrefix(p, prefix) {
+			continue
+		}
+		rest := p[len(prefix):]
+		if rest == "" || strings.Contains(rest, "/") {
+			continue
+		}
+		children = append(children, fs.FileInfoToDirEntry(memInfo{name: rest, entry: child}))
+	}
+	sort.Slice(children, func(i, j int) bool { return children[i].Name() < children[j].Name() })
+	return &memDir{info: info, entries: children}, nil
+}
+
+type memInfo struct {
+	name  string
+	entry *memEntry
+}
+
+func (i memInfo) Name() string       { return i.name }
+func (i memInfo) Size() int64        { return int64(len(i.entry.data)) }
+func (i memInfo) Mode() fs.FileMode  { return i.entry.mode }
+func (i memInfo) ModTime() time.Time { return time.Time{} }
+func (i memInfo) IsDir() bool        { return i.entry.isDir }
+func (i memInfo) Sys() any           { return nil }
+
+type memFile struct {
+	info memInfo
+	r    *bytes.Reader
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *memFile) Read(b []byte) (int, error) { return f.r.Read(b) }
+func (f *memFile) Close() error               { return nil }
+
+type memDir struct {
+	info    memInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *memDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *memDir) Close() error               { return nil }
+
+func (d *memDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: errors.New("is a directory")}
+}
+
+func (d *memDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(rest) {
+		n = len(rest)
+	}
+	d.offset += n
+	return rest[:n], nil
+}
+
+// CopyFS writes every directory and file in src to dst, preserving file
+// permissions. It is used to write a project rendered in memory to disk.
+func CopyFS(dst WritableFS, src fs.FS) error {
+	return fs.WalkDir(src, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if name == "." {
+				return nil
+			}
+			return dst.MkdirAll(name, 0755)
+		}
+		data, err := fs.ReadFile(src, name)
+		if err != nil {
+			return err
+		}
+		return dst.WriteFile(name, data, fileMode(d))
+	})
+}
+
+// fileMode returns the permissions to write an entry with, defaulting to
+// 0644 when the source has none (as with embed.FS).
+func fileMode(entry fs.DirEntry) fs.FileMode {
+	if info, err := entry.Info(); err == nil {
+		if perm := info.Mode().Perm(); perm != 0 {
+			return perm
+		}
+	}
+	return 0644
+}
@@ -0,0 +1,159 @@
+package scaffold
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+// faultFS is a WritableFS that records every write and fails on one name.
+type faultFS struct {
+	*MemFS
+	failOn string
+	writes []string
+}
+
+var errFault = errors.New("injected fault")
+
+func (f *faultFS) MkdirAll(name string, perm fs.FileMode) error {
+	f.writes = append(f.writes, name+"/")
+	if name == f.failOn {
+		return errFault
+	}
+	return f.MemFS.MkdirAll(name, perm)
+}
+
+func (f *faultFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	f.writes = append(f.writes, name)
+	if name == f.failOn {
+		return errFault
+	}
+	return f.MemFS.WriteFile(name, data, perm)
+}
+
+func TestMemFS(t *testing.T) {
+	m := NewMemFS()
+	if err := m.MkdirAll("empty/dir", 0755); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	if err := m.WriteFile("src/main.go", []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := m.WriteFile("build.sh", []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	t.Run("passes fstest", func(t *testing.T) {
+		if err := fstest.TestFS(m, "src/main.go", "build.sh", "empty/dir"); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("keeps modes", func(t *testing.T) {
+		info, err := fs.Stat(m, "build.sh")
+		if err != nil {
+			t.Fatalf("Stat() error = %v", err)
+		}
+		if info.Mode().Perm() != 0755 {
+			t.Errorf("build.sh mode = %v, want 0755", info.Mode().Perm())
+		}
+	})
+
+	t.Run("copies written data", func(t *testing.T) {
+		data := []byte("v1")
+		if err := m.WriteFile("copy.txt", data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		data[1] = '2'
+		got, _ := fs.ReadFile(m, "copy.txt")
+		if string(got) != "v1" {
+			t.Errorf("copy.txt = %q, want %q", got, "v1")
+		}
+	})
+
+	t.Run("rejects file and directory clashes", func(t *testing.T) {
+		if err := m.WriteFile("src", nil, 0644); err == nil {
+			t.Error("WriteFile(src) over a directory expected error, got nil")
+		}
+		if err := m.MkdirAll("build.sh/sub", 0755); err == nil {
+			t.Error("MkdirAll(build.sh/sub) under a file expected error, got nil")
+		}
+	})
+
+	t.Run("rejects invalid paths", func(t *testing.T) {
+		for _, name := range []string{"../escape", "/abs", "a//b"} {
+			if err := m.WriteFile(name, nil, 0644); err == nil {
+				t.Errorf("WriteFile(%q) expected error, got nil", name)
+			}
+		}
+	})
+}
+
+func TestDiskFS_RejectsEscapes(t *testing.T) {
+	d := NewDiskFS(t.TempDir())
+	if err := d.WriteFile("../escape", nil, 0644); err == nil {
+		t.Error("WriteFile(../escape) expected error, got nil")
+	}
+	if err := d.MkdirAll("/abs", 0755); err == nil {
+		t.Error("MkdirAll(/abs) expected error, got nil")
+	}
+}
+
+func TestCopyFS(t *testing.T) {
+	m := NewMemFS()
+	_ = m.WriteFile("a/b.txt", []byte("b"), 0644)
+	_ = m.WriteFile("run.sh", []byte("#!/bin/sh\n"), 0755)
+	_ = m.MkdirAll("empty", 0755)
+
+	dest := t.TempDir()
+	if err := CopyFS(NewDiskFS(dest), m); err != nil {
+		t.Fatalf("CopyFS() error = %v", err)
+	}
+	if got, err := os.ReadFile(filepath.Join(dest, "a", "b.txt")); err != nil || string(got) != "b" {
+		t.Errorf("a/b.txt = %q, %v; want %q", got, err, "b")
+	}
+	if info, err := os.Stat(filepath.Join(dest, "run.sh")); err != nil || info.Mode().Perm() != 0755 {
+		t.Errorf("run.sh stat = %v, %v; want mode 0755", info, err)
+	}
+	if info, err := os.Stat(filepath.Join(dest, "empty")); err != nil || !info.IsDir() {
+		t.Errorf("empty dir missing: %v", err)
+	}
+}
+
+func TestRenderTree_StopsOnWriteError(t *testing.T) {
+	fsys := fstest.MapFS{
+		"lang/a.txt":     {Data: []byte("a")},
+		"lang/b.txt":     {Data: []byte("b")},
+		"lang/sub/c.txt": {Data: []byte("c")},
+	}
+	dst := &faultFS{MemFS: NewMemFS(), failOn: "b.txt"}
+
+	err := renderTree(context.Background(), &bytes.Buffer{}, fsys, "lang", dst, "out", TemplateVars{}, nil)
+	if !errors.Is(err, errFault) {
+		t.Fatalf("renderTree() error = %v, want %v", err, errFault)
+	}
+	want := []string{"a.txt", "b.txt"}
+	if len(dst.writes) != len(want) || dst.writes[0] != want[0] || dst.writes[1] != want[1] {
+		t.Errorf("writes = %v, want %v", dst.writes, want)
+	}
+}
+
+func TestRenderTree_Canceled(t *testing.T) {
+	fsys := fstest.MapFS{"lang/a.txt": {Data: []byte("a")}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	dst := &faultFS{MemFS: NewMemFS()}
+	err := renderTree(ctx, &bytes.Buffer{}, fsys, "lang", dst, "out", TemplateVars{}, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("renderTree() error = %v, want %v", err, context.Canceled)
+	}
+	if len(dst.writes) != 0 {
+		t.Errorf("writes = %v, want none", dst.writes)
+	}
+}
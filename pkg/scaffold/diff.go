@@ -0,0 +1,187 @@
+package scaffold
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxDiffCells bounds the LCS table so diffing large files stays cheap;
+// beyond it the changed region is shown as a single replacement.
+const maxDiffCells = 4 << 20
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+// UnifiedDiff returns a unified diff turning a into b, labelled with name.
+// It returns an empty string when the contents are equal.
+func UnifiedDiff(name string, a, b []byte) string {
+	if string(a) == string(b) {
+		return ""
+	}
+	ops := diffLines(splitLines(string(a)), splitLines(string(b)))
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s (existing)\n+++ %s (template)\n", name, name)
+
+	for start := 0; start < len(ops); {
+		// Find the next change and the extent of its hunk.
+		for start < len(ops) && ops[start].kind == ' ' {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+		from := max(start-diffContext, 0)
+		end := start
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].kind == ' ' {
+				run++
+			}
+			if run == len(ops) || run-end > 2*diffContext {
+				break
+			}
+			end = run
+		}
+		to := min(end+diffContext, len(ops))
+
+		aStart, bStart, aLen, bLen := hunkRange(ops, from, to)
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", aStart, aLen, bStart, bLen)
+		for _, op := range ops[from:to] {
+			out.WriteByte(op.kind)
+			out.WriteString(op.line)
+			out.WriteByte('\n')
+		}
+		start = to
+	}
+	return out.String()
+}
+
+type diffOp struct {
+	kind byte // ' ', '-', or '+'
+	line string
+}
+
+// hunkRange computes the @@ header numbers for ops[from:to]. An empty side
+// is numbered by the line before it, as in diff -u.
+func hunkRange(ops []diffOp, from, to int) (aStart, bStart, aLen, bLen int) {
+	for _, op := range ops[:from] {
+		if op.kind != '+' {
+			aStart++
+		}
+		if op.kind != '-' {
+			bStart++
+		}
+	}
+	for _, op := range ops[from:to] {
+		if op.kind != '+' {
+			aLen++
+		}
+		if op.kind != '-' {
+			bLen++
+		}
+	}
+	if aLen > 0 {
+		aStart++
+	}
+	if bLen > 0 {
+		bStart++
+	}
+	return aStart, bStart, aLen, bLen
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// diffLines computes a line diff using the longest common subsequence of
+// the region between the common prefix and suffix.
+func diffLines(a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []diffOp
+	for i := 0; i < prefix; i++ {
+		ops = append(ops, diffOp{' ', a[i]})
+	}
+
+	am, bm := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	ai, bi := prefix, prefix
+	emit := func(kind byte) {
+		switch kind {
+		case ' ':
+			ops = append(ops, diffOp{' ', a[ai]})
+			ai++
+			bi++
+		case '-':
+			ops = append(ops, diffOp{'-', a[ai]})
+			ai++
+		case '+':
+			ops = append(ops, diffOp{'+', b[bi]})
+			bi++
+		}
+	}
+
+	if (len(am)+1)*(len(bm)+1) > maxDiffCells {
+		for range am {
+			emit('-')
+		}
+		for range bm {
+			emit('+')
+		}
+	} else {
+		// lcs[i][j] is the LCS length of am[i:] and bm[j:].
+		lcs := make([][]int, len(am)+1)
+		for i := range lcs {
+			lcs[i] = make([]int, len(bm)+1)
+		}
+		for i := len(am) - 1; i >= 0; i-- {
+			for j := len(bm) - 1; j >= 0; j-- {
+				if am[i] == bm[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else {
+					lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+				}
+			}
+		}
+		i, j := 0, 0
+		for i < len(am) && j < len(bm) {
+			switch {
+			case am[i] == bm[j]:
+				emit(' ')
+				i++
+				j++
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				emit('-')
+				i++
+			default:
+				emit('+')
+				j++
+			}
+		}
+		for ; i < len(am); i++ {
+			emit('-')
+		}
+		for ; j < len(bm); j++ {
+			emit('+')
+		}
+	}
+
+	for i := len(a) - suffix; i < len(a); i++ {
+		ops = append(ops, diffOp{' ', a[i]})
+	}
+	return ops
+}
@@ -0,0 +1,52 @@
+package scaffold
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{"equal", "a\nb\n", "a\nb\n", ""},
+		{
+			"change in middle",
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			"1\n2\n3\n4\nfive\n6\n7\n8\n9\n",
+			"--- f (existing)\n+++ f (template)\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			"append to empty",
+			"",
+			"x\n",
+			"--- f (existing)\n+++ f (template)\n@@ -0,0 +1,1 @@\n+x\n",
+		},
+		{
+			"separate hunks",
+			"a\n1\n2\n3\n4\n5\n6\n7\n8\nb\n",
+			"A\n1\n2\n3\n4\n5\n6\n7\n8\nB\n",
+			"--- f (existing)\n+++ f (template)\n@@ -1,4 +1,4 @@\n-a\n+A\n 1\n 2\n 3\n@@ -7,4 +7,4 @@\n 6\n 7\n 8\n-b\n+B\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := UnifiedDiff("f", []byte(tt.a), []byte(tt.b))
+			if got != tt.want {
+				t.Errorf("UnifiedDiff() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnifiedDiff_LargeInput(t *testing.T) {
+	a := strings.Repeat("a\n", 5000)
+	b := strings.Repeat("b\n", 5000)
+	got := UnifiedDiff("big", []byte(a), []byte(b))
+	if strings.Count(got, "\n-a") != 5000 || strings.Count(got, "\n+b") != 5000 {
+		t.Errorf("large diff should replace every line")
+	}
+}
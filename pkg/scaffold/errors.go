@@ -0,0 +1,29 @@
+package scaffold
+
+import "errors"
+
+// Errors that callers can test for with errors.Is, e.g. to map a failure to
+// an exit status or a machine-readable code. The errors returned carry
+// their own, more specific messages.
+var (
+	ErrInvalidName       = errors.New("invalid project name")
+	ErrUnsupportedLang   = errors.New("unsupported language")
+	ErrInvalidVariable   = errors.New("invalid template variable")
+	ErrDestinationExists = errors.New("destination already exists")
+	ErrHookFailed        = errors.New("hook failed")
+)
+
+// markedError keeps err's message while matching mark with errors.Is.
+type markedError struct {
+	err  error
+	mark error
+}
+
+func (e *markedError) Error() string        { return e.err.Error() }
+func (e *markedError) Unwrap() error        { return e.err }
+func (e *markedError) Is(target error) bool { return target == e.mark }
+
+// markErr tags err as an instance of mark.
+func markErr(err, mark error) error {
+	return &markedError{err: err, mark: mark}
+}
@@ -0,0 +1,331 @@
+package scaffold
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+)
+
+// templateFuncs returns the function library available to .tmpl files.
+// Argument order follows the usual text/template convention of putting the
+// piped value last, so {{.ProjectName | replace "-" "_"}} works.
+func templateFuncs(vars TemplateVars) template.FuncMap {
+	return template.FuncMap{
+		// Case conversion
+		"lower":     strings.ToLower,
+		"upper":     strings.ToUpper,
+		"title":     titleCase,
+		"snake":     snakeCase,
+		"kebab":     kebabCase,
+		"camel":     camelCase,
+		"pascal":    pascalCase,
+		"screaming": screamingCase,
+		"ident":     identifier,
+
+		// Strings
+		"trim":       strings.TrimSpace,
+		"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+		"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+		"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+		"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+		"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+		"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+		"repeat":     func(n int, s string) string { return strings.Repeat(s, n) },
+		"split":      func(sep, s string) []string { return strings.Split(s, sep) },
+		"join":       joinList,
+		"quote":      func(v any) string { return fmt.Sprintf("%q", fmt.Sprint(v)) },
+		"squote":     shellQuote,
+		"indent":     indent,
+		"nindent":    func(n int, s string) string { return "\n" + indent(n, s) },
+
+		// Dates
+		"now":  time.Now,
+		"date": func(layout string, t time.Time) string { return t.Format(layout) },
+
+		// Defaults
+		"default":  defaultValue,
+		"coalesce": coalesce,
+		"empty":    isEmpty,
+
+		// Lists
+		"list":  func(items ...any) []any { return items },
+		"first": first,
+		"last":  last,
+		"has":   has,
+
+		// Environment
+		"env": func(name string) (string, error) {
+			if !vars.AllowEnv {
+				return "", fmt.Errorf("env %q: environment access is disabled; enable it with --allow-env", name)
+			}
+			return os.Getenv(name), nil
+		},
+	}
+}
+
+// splitWords breaks s into words at non-alphanumeric characters and at
+// case changes, keeping acronyms together: "HTTPServer-v2" is
+// ["HTTP", "Server", "v2"].
+func splitWords(s string) []string {
+	var words []string
+	var current []rune
+	runes := []rune(s)
+
+	flush := func() {
+		if len(current) > 0 {
+			words = append(words, string(current))
+			current = nil
+		}
+	}
+
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			flush()
+			continue
+		}
+		if len(current) > 0 && unicode.IsUpper(r) {
+			prev := current[len(current)-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				flush()
+			}
+		}
+		current = append(current, r)
+	}
+	flush()
+	return words
+}
+
+func capitalize(word string) string {
+	runes := []rune(strings.ToLower(word))
+	if len(runes) > 0 {
+		runes[0] = unicode.ToUpper(runes[0])
+	}
+	return string(runes)
+}
+
+func titleCase(s string) string {
+	words := strings.Fields(s)
+	for i, word := range words {
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		words[i] = string(runes)
+	}
+	return strings.Join(words, " ")
+}
+
+func joinWords(s, sep string, transform func(int, string) string) string {
+	words := splitWords(s)
+	for i, word := range words {
+		words[i] = transform(i, word)
+	}
+	return strings.Join(words, sep)
+}
+
+func snakeCase(s string) string {
+	return joinWords(s, "_", func(_ int, w string) string { return strings.ToLower(w) })
+}
+
+func kebabCase(s string) string {
+	return joinWords(s, "-", func(_ int, w string) string { return strings.ToLower(w) })
+}
+
+func screamingCase(s string) string {
+	return joinWords(s, "_", func(_ int, w string) string { return strings.ToUpper(w) })
+}
+
+func pascalCase(s string) string {
+	return joinWords(s, "", func(_ int, w string) string { return capitalize(w) })
+}
+
+func camelCase(s string) string {
+	return joinWords(s, "", func(i int, w string) string {
+		if i == 0 {
+			return strings.ToLower(w)
+		}
+		return capitalize(w)
+	})
+}
+
+// keywords lists reserved words per language for identifier sanitization.
+var keywords = map[string][]string{
+	"go": {"break", "case", "chan", "const", "continue", "default", "defer", "else",
+		"fallthrough", "for", "func", "go", "goto", "if", "import", "interface", "map",
+		"package", "range", "return", "select", "struct", "switch", "type", "var"},
+	"cpp": {"alignas", "alignof", "and", "and_eq", "asm", "auto", "bitand", "bitor", "bool",
+		"break", "case", "catch", "char", "char8_t", "char16_t", "char32_t", "class", "compl",
+		"concept", "const", "consteval", "constexpr", "constinit", "const_cast", "continue",
+		"co_await", "co_return", "co_yield", "decltype", "default", "delete", "do", "double",
+		"dynamic_cast", "else", "enum", "explicit", "export", "extern", "false", "float",
+		"for", "friend", "goto", "if", "inline", "int", "long", "mutable", "namespace", "new",
+		"noexcept", "not", "not_eq", "nullptr", "operator", "or", "or_eq", "private",
+		"protected", "public", "register", "reinterpret_cast", "requires", "return", "short",
+		"signed", "sizeof", "static", "static_assert", "static_cast", "struct", "switch",
+		"template", "this", "thread_local", "throw", "true", "try", "typedef", "typeid",
+		"typename", "union", "unsigned", "using", "virtual", "void", "volatile", "wchar_t",
+		"while", "xor", "xor_eq"},
+	"rust": {"as", "async", "await", "break", "const", "continue", "crate", "dyn", "else",
+		"enum", "extern", "false", "fn", "for", "if", "impl", "in", "let", "loop", "match",
+		"mod", "move", "mut", "pub", "ref", "return", "self", "Self", "static", "struct",
+		"super", "trait", "true", "type", "unsafe", "use", "where", "while",
+		// reserved for future use
+		"abstract", "become", "box", "do", "final", "macro", "override", "priv", "try",
+		"typeof", "unsized", "virtual", "yield"},
+	"python": {"False", "None", "True", "and", "as", "assert", "async", "await", "break",
+		"class", "continue", "def", "del", "elif", "else", "except", "finally", "for", "from",
+		"global", "if", "import", "in", "is", "lambda", "nonlocal", "not", "or", "pass",
+		"raise", "return", "try", "while", "with", "yield"},
+}
+
+// identifier turns s into a valid identifier for lang ("go", "cpp", "c",
+// "rust", or "python"): invalid characters become underscores, a leading
+// digit is prefixed with an underscore, and keywords get a trailing one.
+func identifier(lang, s string) (string, error) {
+	switch lang {
+	case "c", "c++":
+		lang = "cpp"
+	case "go", "cpp", "rust", "python":
+	default:
+		return "", fmt.Errorf("ident: unsupported language %q", lang)
+	}
+
+	var b strings.Builder
+	for _, r := range s {
+		if r == '_' || (r <= unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r))) {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+	id := b.String()
+	if id == "" || unicode.IsDigit(rune(id[0])) {
+		id = "_" + id
+	}
+	if isKeyword(lang, id) {
+		return id + "_", nil
+	}
+	return id, nil
+}
+
+// isKeyword reports whether id is a reserved word in lang.
+func isKeyword(lang, id string) bool {
+	for _, kw := range keywords[lang] {
+		if id == kw {
+			return true
+		}
+	}
+	return false
+}
+
+// shellQuote quotes v as a single POSIX shell word. An embedded single
+// quote closes the quoted string, is added escaped with a backslash, and
+// opens a new one.
+func shellQuote(v any) string {
+	return "'" + strings.ReplaceAll(fmt.Sprint(v), "'", `'\''`) + "'"
+}
+
+func indent(n int, s string) string {
+	pad := strings.Repeat(" ", n)
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = pad + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// isEmpty reports whether v is nil or the zero value of its kind, treating
+// empty strings, slices, and maps as empty.
+func isEmpty(v any) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return rv.Len() == 0
+	default:
+		return rv.IsZero()
+	}
+}
+
+func defaultValue(def, v any) any {
+	if isEmpty(v) {
+		return def
+	}
+	return v
+}
+
+func coalesce(values ...any) any {
+	for _, v := range values {
+		if !isEmpty(v) {
+			return v
+		}
+	}
+	return nil
+}
+
+// toList converts any slice or array to []any.
+func toList(v any) ([]any, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("expected a list, got %T", v)
+	}
+	list := make([]any, rv.Len())
+	for i := range list {
+		list[i] = rv.Index(i).Interface()
+	}
+	return list, nil
+}
+
+func joinList(sep string, v any) (string, error) {
+	list, err := toList(v)
+	if err != nil {
+		return "", fmt.Errorf("join: %w", err)
+	}
+	parts := make([]string, len(list))
+	for i, item := range list {
+		parts[i] = fmt.Sprint(item)
+	}
+	return strings.Join(parts, sep), nil
+}
+
+func first(v any) (any, error) {
+	list, err := toList(v)
+	if err != nil {
+		return nil, fmt.Errorf("first: %w", err)
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+	return list[0], nil
+}
+
+func last(v any) (any, error) {
+	list, err := toList(v)
+	if err != nil {
+		return nil, fmt.Errorf("last: %w", err)
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+	return list[len(list)-1], nil
+}
+
+func has(item, v any) (bool, error) {
+	list, err := toList(v)
+	if err != nil {
+		return false, fmt.Errorf("has: %w", err)
+	}
+	for _, x := range list {
+		if reflect.DeepEqual(x, item) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,131 @@
+package scaffold
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRenderTemplate_Funcs(t *testing.T) {
+	t.Setenv("PROJECT_TEST_ENV", "from-env")
+
+	vars := TemplateVars{
+		ProjectName: "my-app",
+		ModulePath:  "github.com/user/my-app",
+		Author:      "alice",
+		Year:        2025,
+		Extra: map[string]any{
+			"tags":  []any{"cli", "tool"},
+			"empty": "",
+			"text":  "line1\nline2",
+		},
+		AllowEnv: true,
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr bool
+	}{
+		{"lower", `{{"My-App" | lower}}`, "my-app", false},
+		{"upper", `{{.ProjectName | upper}}`, "MY-APP", false},
+		{"title", `{{"hello big world" | title}}`, "Hello Big World", false},
+		{"snake", `{{.ProjectName | snake}}`, "my_app", false},
+		{"snake from camel", `{{"myHTTPServer" | snake}}`, "my_http_server", false},
+		{"kebab", `{{"MyApp_v2" | kebab}}`, "my-app-v2", false},
+		{"camel", `{{.ProjectName | camel}}`, "myApp", false},
+		{"pascal", `{{.ProjectName | pascal}}`, "MyApp", false},
+		{"pascal keeps words", `{{"json.parser tool" | pascal}}`, "JsonParserTool", false},
+		{"screaming", `{{.ProjectName | screaming}}`, "MY_APP", false},
+		{"ident go", `{{ident "go" .ProjectName}}`, "my_app", false},
+		{"ident leading digit", `{{ident "cpp" "3d-engine"}}`, "_3d_engine", false},
+		{"ident keyword", `{{ident "cpp" "class"}}`, "class_", false},
+		{"ident python keyword", `{{"import" | ident "python"}}`, "import_", false},
+		{"ident unknown language", `{{ident "cobol" "x"}}`, "", true},
+		{"trim", `{{"  x  " | trim}}`, "x", false},
+		{"trimPrefix", `{{.ModulePath | trimPrefix "github.com/"}}`, "user/my-app", false},
+		{"trimSuffix", `{{.ProjectName | trimSuffix "-app"}}`, "my", false},
+		{"replace", `{{.ProjectName | replace "-" "_"}}`, "my_app", false},
+		{"contains", `{{if contains "app" .ProjectName}}yes{{end}}`, "yes", false},
+		{"hasPrefix", `{{if hasPrefix "github.com" .ModulePath}}gh{{end}}`, "gh", false},
+		{"hasSuffix", `{{if hasSuffix ".git" .ModulePath}}git{{else}}no{{end}}`, "no", false},
+		{"repeat", `{{"=" | repeat 3}}`, "===", false},
+		{"split and join", `{{.ModulePath | split "/" | join "."}}`, "github.com.user.my-app", false},
+		{"join list var", `{{.tags | join ", "}}`, "cli, tool", false},
+		{"join non-list", `{{.ProjectName | join ","}}`, "", true},
+		{"quote", `{{.ProjectName | quote}}`, `"my-app"`, false},
+		{"quote escapes", `{{"say \"hi\"" | quote}}`, `"say \"hi\""`, false},
+		{"squote", `{{.Year | squote}}`, "'2025'", false},
+		{"squote escapes", `{{"O'Brien" | squote}}`, `'O'\''Brien'`, false},
+		{"indent", `{{.text | indent 2}}`, "  line1\n  line2", false},
+		{"nindent", `{{.text | nindent 4}}`, "\n    line1\n    line2", false},
+		{"date", `{{now | date "2006"}}`, strconv.Itoa(time.Now().Year()), false},
+		{"default used", `{{.empty | default "none"}}`, "none", false},
+		{"default skipped", `{{.Author | default "none"}}`, "alice", false},
+		{"coalesce", `{{coalesce .empty "" .Author}}`, "alice", false},
+		{"empty", `{{if empty .empty}}empty{{end}}`, "empty", false},
+		{"list and first", `{{list "a" "b" | first}}`, "a", false},
+		{"last", `{{.tags | last}}`, "tool", false},
+		{"has", `{{if has "cli" .tags}}cli{{end}}`, "cli", false},
+		{"env", `{{env "PROJECT_TEST_ENV"}}`, "from-env", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderTemplate([]byte(tt.content), vars)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("RenderTemplate() expected error, got nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("RenderTemplate() unexpected error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("RenderTemplate() = %q, want %q", string(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderTemplate_EnvDisabledByDefault(t *testing.T) {
+	t.Setenv("PROJECT_TEST_ENV", "secret")
+
+	_, err := RenderTemplate([]byte(`{{env "PROJECT_TEST_ENV"}}`), TemplateVars{ProjectName: "demo"})
+	if err == nil {
+		t.Fatal("RenderTemplate() expected error when env access is disabled, got nil")
+	}
+}
+
+func TestSplitWords(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"my-app", []string{"my", "app"}},
+		{"my_app.v2", []string{"my", "app", "v2"}},
+		{"MyApp", []string{"My", "App"}},
+		{"HTTPServer", []string{"HTTP", "Server"}},
+		{"parseURL", []string{"parse", "URL"}},
+		{"v2Beta", []string{"v2", "Beta"}},
+		{"--", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got := splitWords(tt.input)
+			if len(got) != len(tt.want) {
+				t.Fatalf("splitWords(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("splitWords(%q) = %q, want %q", tt.input, got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package scaffold
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/JackDrogon/project/pkg/git"
+)
+
+// Default commit messages, used when Options.CommitMessage is empty, for a
+// new repository and for a project added to an enclosing one.
+const (
+	defaultCommitMessage       = "Initial commit"
+	defaultParentCommitMessage = "Add {{.ProjectName}}"
+)
+
+// GitMode chooses where a new project's files are committed.
+type GitMode string
+
+const (
+	// GitAuto uses GitParent when the project is created inside a git work
+	// tree and GitNested otherwise.
+	GitAuto GitMode = ""
+	// GitNested initializes a repository in the project directory.
+	GitNested GitMode = "nested"
+	// GitParent stages the project in the enclosing repository instead,
+	// leaving the commit to the user unless Options.Commit is set.
+	GitParent GitMode = "parent"
+	// GitNone leaves git alone, like Options.NoGit.
+	GitNone GitMode = "none"
+)
+
+// ParseGitMode parses a --git flag value.
+func ParseGitMode(s string) (GitMode, error) {
+	switch m := GitMode(s); m {
+	case GitNested, GitParent, GitNone:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown git mode %q: must be nested, parent, or none", s)
+}
+
+// nestedGit reports whether a repository is initialized in the project
+// directory itself.
+func (o Options) nestedGit() bool {
+	return !o.NoGit && (o.Git == GitAuto || o.Git == GitNested)
+}
+
+// resolveGit settles opts.Git for creating a project directory: NoGit
+// becomes GitNone, and GitAuto becomes GitParent inside a git work tree and
+// GitNested anywhere else, including when git is not installed. With
+// GitParent it records the enclosing work tree and refuses options that
+// need a new repository, and commit options without opts.Commit.
+func (c *Creator) resolveGit(ctx context.Context, opts Options) (Options, error) {
+	if opts.NoGit {
+		opts.Git = GitNone
+	}
+	if opts.Git == GitNone || opts.Git == GitNested {
+		return opts, nil
+	}
+
+	parent := filepath.Dir(filepath.Clean(opts.ProjectName))
+	root, err := c.git.Root(ctx, parent)
+	if err != nil {
+		if opts.Git == GitAuto && (errors.Is(err, git.ErrNotRepository) || errors.Is(err, git.ErrNotFound)) {
+			opts.Git = GitNested
+			return opts, nil
+		}
+		if errors.Is(err, git.ErrNotRepository) {
+			return opts, fmt.Errorf("cannot add %s to an enclosing git repository: %s is not in one", opts.ProjectName, parent)
+		}
+		return opts, err
+	}
+
+	opts.Git = GitParent
+	opts.gitRoot = root
+	if opts.InitialBranch != "" || len(opts.Remotes) > 0 {
+		return opts, fmt.Errorf("%s would be added to the git repository at %s, which already has its branches and remotes; "+
+			"use --git=nested to create a repository of its own", opts.ProjectName, root)
+	}
+	if !opts.Commit && !opts.NoCommit && (opts.CommitMessage != "" || opts.CommitAuthor != "" || opts.Signoff || opts.Sign || opts.SigningKey != "") {
+		return opts, fmt.Errorf("%s would only be staged in the git repository at %s, so the commit options have no effect; "+
+			"use --commit to commit it, or --git=nested to create a repository of its own", opts.ProjectName, root)
+	}
+	return opts, nil
+}
+
+// relPath returns path relative to the work tree root, resolving symbolic
+// links in both since git reports the root with them resolved.
+func relPath(root, path string) (string, error) {
+	var err error
+	if root, err = filepath.EvalSymlinks(root); err != nil {
+		return "", err
+	}
+	if path, err = filepath.EvalSymlinks(path); err != nil {
+		return "", err
+	}
+	if path, err = filepath.Abs(path); err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("%s is not inside the git repository at %s", path, root)
+	}
+	return rel, nil
+}
+
+// Remote is a git remote added to a new repository.
+type Remote struct {
+	Name string
+	URL  string
+}
+
+// ParseRemote parses a name=url pair, such as those given with --remote.
+func ParseRemote(s string) (Remote, error) {
+	name, url, ok := strings.Cut(s, "=")
+	name, url = strings.TrimSpace(name), strings.TrimSpace(url)
+	if !ok || name == "" || url == "" {
+		return Remote{}, fmt.Errorf("invalid remote %q: expected name=url", s)
+	}
+	if strings.ContainsAny(name, " \t/:") || strings.HasPrefix(name, "-") {
+		return Remote{}, fmt.Errorf("invalid remote name %q", name)
+	}
+	return Remote{Name: name, URL: url}, nil
+}
+
+// identityRe matches a git identity such as "Jane Doe <jane@example.com>".
+var identityRe = regexp.MustCompile(`^([^<>]*[^<>\s])\s*<([^<>]*[^<>\s][^<>]*)>$`)
+
+// ParseIdentity parses "Name <email>", the form git uses for --author. The
+// email must not be empty.
+func ParseIdentity(s string) (git.Signature, error) {
+	m := identityRe.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return git.Signature{}, fmt.Errorf("invalid identity %q: expected \"Name <email>\"", s)
+	}
+	return git.Signature{Name: strings.TrimSpace(m[1]), Email: strings.TrimSpace(m[2])}, nil
+}
+
+// fallbackIdentity returns the identity to commit as when git has none, as
+// on CI machines without user.name and user.email: whatever git has
+// configured, completed with the template's author and author email. It
+// fails with ErrNoIdentity rather than commit without a name or email.
+func fallbackIdentity(ctx context.Context, repo git.Repository, author, email string) (git.Signature, error) {
+	var id git.Signature
+	for key, field := range map[string]*string{"user.name": &id.Name, "user.email": &id.Email} {
+		value, err := repo.Config(ctx, key)
+		if err != nil && !errors.Is(err, git.ErrNotSet) {
+			return git.Signature{}, err
+		}
+		*field = value
+	}
+	if id.Name == "" {
+		id.Name = author
+	}
+	if id.Email == "" {
+		id.Email = email
+	}
+	switch {
+	case id.Name == "":
+		return git.Signature{}, fmt.Errorf(`%w and there is no author to commit as; set --author "Name <email>"`, git.ErrNoIdentity)
+	case id.Email == "":
+		return git.Signature{}, fmt.Errorf(`%w and there is no author email to commit with; set --author "Name <email>"`, git.ErrNoIdentity)
+	}
+	return id, nil
+}
+
+// commitMessage renders opts.CommitMessage with the project variables.
+func commitMessage(opts Options, vars TemplateVars) (string, error) {
+	text := opts.CommitMessage
+	switch {
+	case text == "" && opts.Git == GitParent:
+		text = defaultParentCommitMessage
+	case text == "":
+		return defaultCommitMessage, nil
+	}
+	msg, err := RenderTemplate([]byte(text), vars)
+	if err != nil {
+		return "", fmt.Errorf("commit message: %w", err)
+	}
+	if strings.TrimSpace(string(msg)) == "" {
+		return "", errors.New("commit message is empty")
+	}
+	return string(msg), nil
+}
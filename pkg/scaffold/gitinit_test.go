@@ -0,0 +1,443 @@
+package scaffold
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/JackDrogon/project/pkg/git"
+)
+
+func TestParseRemote(t *testing.T) {
+	r, err := ParseRemote("origin=git@github.com:acme/demo.git")
+	if err != nil || r != (Remote{Name: "origin", URL: "git@github.com:acme/demo.git"}) {
+		t.Errorf("ParseRemote() = %+v, %v", r, err)
+	}
+	for _, s := range []string{"origin", "=url", "origin=", "a b=url", "-x=url", "up/stream=url"} {
+		if _, err := ParseRemote(s); err == nil {
+			t.Errorf("ParseRemote(%q) expected error, got nil", s)
+		}
+	}
+}
+
+func TestParseIdentity(t *testing.T) {
+	tests := map[string]git.Signature{
+		"Jane Doe <jane@example.com>": {Name: "Jane Doe", Email: "jane@example.com"},
+		"  bot<bot@ci>  ":             {Name: "bot", Email: "bot@ci"},
+	}
+	for s, want := range tests {
+		got, err := ParseIdentity(s)
+		if err != nil || got != want {
+			t.Errorf("ParseIdentity(%q) = %+v, %v, want %+v", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"", "jane", "<jane@example.com>", "Jane <a> <b>", "Jane <jane", "Nobody <>", "Nobody < >"} {
+		if _, err := ParseIdentity(s); err == nil {
+			t.Errorf("ParseIdentity(%q) expected error, got nil", s)
+		}
+	}
+}
+
+// gitLog returns the formatted log of the repository at dir.
+func gitLog(t *testing.T, dir, format string) string {
+	t.Helper()
+	out, err := exec.Command("git", "-C", dir, "log", "--format="+format).Output()
+	if err != nil {
+		t.Fatalf("git log: %v", err)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestCreate_GitOptions(t *testing.T) {
+	fsys := fstest.MapFS{"go/main.go": {Data: []byte("package main\n")}}
+
+	t.Run("commit", func(t *testing.T) {
+		requireGit(t)
+		chdirTemp(t)
+		opts := Options{
+			Lang:          "go",
+			ProjectName:   "demo",
+			Year:          2024,
+			InitialBranch: "trunk",
+			CommitMessage: "chore: scaffold {{.ProjectName}} in {{.Year}}",
+			CommitAuthor:  "Jane Doe <jane@example.com>",
+			Remotes:       []Remote{{Name: "origin", URL: "https://example.com/demo.git"}},
+		}
+		if err := NewCreator(fsys, &bytes.Buffer{}).Create(context.Background(), opts); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		if got := gitLog(t, "demo", "%an <%ae>|%s"); got != "Jane Doe <jane@example.com>|chore: scaffold demo in 2024" {
+			t.Errorf("commit = %q", got)
+		}
+		out, err := exec.Command("git", "-C", "demo", "rev-parse", "--abbrev-ref", "HEAD").Output()
+		if err != nil || strings.TrimSpace(string(out)) != "trunk" {
+			t.Errorf("branch = %q, %v, want trunk", out, err)
+		}
+		out, err = exec.Command("git", "-C", "demo", "remote", "get-url", "origin").Output()
+		if err != nil || strings.TrimSpace(string(out)) != "https://example.com/demo.git" {
+			t.Errorf("origin = %q, %v", out, err)
+		}
+	})
+
+	t.Run("no commit", func(t *testing.T) {
+		requireGit(t)
+		chdirTemp(t)
+		var report Report
+		opts := Options{Lang: "go", ProjectName: "demo", NoCommit: true, Report: &report}
+		if err := NewCreator(fsys, &bytes.Buffer{}).Create(context.Background(), opts); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		if err := exec.Command("git", "-C", "demo", "rev-parse", "--verify", "HEAD").Run(); err == nil {
+			t.Error("no commit should be made with NoCommit")
+		}
+		if report.Commit != "" {
+			t.Errorf("report.Commit = %q, want none", report.Commit)
+		}
+	})
+
+	t.Run("no git", func(t *testing.T) {
+		chdirTemp(t)
+		opts := Options{Lang: "go", ProjectName: "demo", NoGit: true}
+		if err := NewCreator(fsys, &bytes.Buffer{}).Create(context.Background(), opts); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		if _, err := os.Stat(filepath.Join("demo", ".git")); !os.IsNotExist(err) {
+			t.Errorf("no repository should be initialized with NoGit, stat err = %v", err)
+		}
+		if _, err := os.Stat(filepath.Join("demo", "main.go")); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		dir := chdirTemp(t)
+		for _, opts := range []Options{
+			{Lang: "go", ProjectName: "demo", CommitAuthor: "jane"},
+			{Lang: "go", ProjectName: "demo", CommitMessage: "{{.Nope"},
+			{Lang: "go", ProjectName: "demo", CommitMessage: "{{if false}}x{{end}}"},
+		} {
+			if err := NewCreator(fsys, &bytes.Buffer{}).Create(context.Background(), opts); err == nil {
+				t.Errorf("Create(%+v) expected error, got nil", opts)
+			}
+		}
+		if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+			t.Errorf("invalid git options should fail before anything is written, found %v", entries)
+		}
+	})
+}
+
+func TestCreate_NoGitIdentity(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	// A CI machine: no user.name or user.email anywhere git looks.
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", home)
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(home, "gitconfig"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	for _, name := range []string{"GIT_AUTHOR_NAME", "GIT_AUTHOR_EMAIL", "GIT_COMMITTER_NAME", "GIT_COMMITTER_EMAIL", "EMAIL"} {
+		t.Setenv(name, "")
+	}
+	chdirTemp(t)
+
+	fsys := fstest.MapFS{"go/main.go": {Data: []byte("package main\n")}}
+	opts := Options{Lang: "go", ProjectName: "demo", Author: "Template Author", AuthorEmail: "author@example.com"}
+	if err := NewCreator(fsys, &bytes.Buffer{}).Create(context.Background(), opts); err != nil {
+		t.Fatalf("Create() without a git identity error = %v", err)
+	}
+	want := "Template Author <author@example.com>|Template Author <author@example.com>"
+	if got := gitLog(t, "demo", "%an <%ae>|%cn <%ce>"); got != want {
+		t.Errorf("commit identities = %q, want the template author", got)
+	}
+
+	opts = Options{Lang: "go", ProjectName: "noemail", Author: "Template Author"}
+	if err := NewCreator(fsys, &bytes.Buffer{}).Create(context.Background(), opts); !errors.Is(err, git.ErrNoIdentity) {
+		t.Errorf("Create() without any email error = %v, want ErrNoIdentity", err)
+	}
+	if _, err := os.Stat("noemail"); !os.IsNotExist(err) {
+		t.Errorf("project should be removed when there is no email to commit with, stat err = %v", err)
+	}
+}
+
+func TestCreate_FakeGit(t *testing.T) {
+	fsys := fstest.MapFS{"go/main.go": {Data: []byte("package main\n")}}
+	// The fake must be all Create needs: no git on PATH.
+	t.Setenv("PATH", t.TempDir())
+
+	t.Run("commit", func(t *testing.T) {
+		chdirTemp(t)
+		fake := &git.Fake{Config: map[string]string{"user.name": "CI", "user.email": "ci@example.com"}}
+		creator := NewCreator(fsys, &bytes.Buffer{})
+		creator.SetGit(fake)
+		var report Report
+		opts := Options{
+			Lang:          "go",
+			ProjectName:   "demo",
+			InitialBranch: "trunk",
+			Signoff:       true,
+			Remotes:       []Remote{{Name: "origin", URL: "https://example.com/demo.git"}},
+			Report:        &report,
+		}
+		if err := creator.Create(context.Background(), opts); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		repos := fake.Repositories()
+		if len(repos) != 1 {
+			t.Fatalf("repositories = %d, want 1", len(repos))
+		}
+		r := repos[0]
+		if r.Branch != "trunk" || r.Remotes["origin"] != "https://example.com/demo.git" {
+			t.Errorf("repository = %+v", r)
+		}
+		if len(r.Commits) != 1 {
+			t.Fatalf("commits = %+v, want one", r.Commits)
+		}
+		c := r.Commits[0]
+		if c.Message != defaultCommitMessage || !c.Signoff || c.Author.String() != "CI <ci@example.com>" {
+			t.Errorf("commit = %+v", c)
+		}
+		if report.Commit != c.ID {
+			t.Errorf("report.Commit = %q, want %q", report.Commit, c.ID)
+		}
+	})
+
+	t.Run("identity fallback", func(t *testing.T) {
+		chdirTemp(t)
+		fake := &git.Fake{Config: map[string]string{"user.email": "ci@example.com"}}
+		creator := NewCreator(fsys, &bytes.Buffer{})
+		creator.SetGit(fake)
+		opts := Options{Lang: "go", ProjectName: "demo", Author: "Template Author"}
+		if err := creator.Create(context.Background(), opts); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		c := fake.Repositories()[0].Commits[0]
+		if c.Author.String() != "Template Author <ci@example.com>" || c.Committer != c.Author {
+			t.Errorf("commit identities = %v, %v", c.Author, c.Committer)
+		}
+	})
+
+	t.Run("template author email", func(t *testing.T) {
+		chdirTemp(t)
+		fake := &git.Fake{}
+		creator := NewCreator(fsys, &bytes.Buffer{})
+		creator.SetGit(fake)
+		opts := Options{Lang: "go", ProjectName: "demo", Author: "Template Author", AuthorEmail: "author@example.com"}
+		if err := creator.Create(context.Background(), opts); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		c := fake.Repositories()[0].Commits[0]
+		if c.Author.Email == "" || c.Author.String() != "Template Author <author@example.com>" || c.Committer != c.Author {
+			t.Errorf("commit identities = %v, %v", c.Author, c.Committer)
+		}
+	})
+
+	t.Run("no email", func(t *testing.T) {
+		chdirTemp(t)
+		creator := NewCreator(fsys, &bytes.Buffer{})
+		creator.SetGit(&git.Fake{})
+		err := creator.Create(context.Background(), Options{Lang: "go", ProjectName: "demo", Author: "Template Author"})
+		if !errors.Is(err, git.ErrNoIdentity) || !strings.Contains(err.Error(), "--author") {
+			t.Errorf("Create() error = %v, want ErrNoIdentity suggesting --author", err)
+		}
+	})
+
+	t.Run("hook rejected", func(t *testing.T) {
+		chdirTemp(t)
+		rejected := fmt.Errorf("pre-commit: %w", git.ErrHookRejected)
+		creator := NewCreator(fsys, &bytes.Buffer{})
+		creator.SetGit(&git.Fake{Errs: map[string]error{"commit": rejected}})
+		err := creator.Create(context.Background(), Options{Lang: "go", ProjectName: "demo"})
+		if !errors.Is(err, git.ErrHookRejected) {
+			t.Errorf("Create() error = %v, want ErrHookRejected", err)
+		}
+	})
+}
+
+func TestCreate_GitParent(t *testing.T) {
+	fsys := fstest.MapFS{"go/main.go": {Data: []byte("package main\n")}}
+	requireGit(t)
+
+	// newParent makes the temporary working directory a repository with
+	// other work staged, which must not end up in the project's commit.
+	newParent := func(t *testing.T) {
+		t.Helper()
+		chdirTemp(t)
+		for _, args := range [][]string{{"init", "--quiet"}, {"commit", "--quiet", "--allow-empty", "-m", "root"}} {
+			if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+				t.Fatalf("git %v: %v\n%s", args, err, out)
+			}
+		}
+		if err := os.WriteFile("other.txt", []byte("wip\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := exec.Command("git", "add", "other.txt").Run(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	status := func(t *testing.T) string {
+		t.Helper()
+		out, err := exec.Command("git", "status", "--porcelain").Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.TrimSpace(string(out))
+	}
+
+	t.Run("auto", func(t *testing.T) {
+		newParent(t)
+		var report Report
+		opts := Options{Lang: "go", ProjectName: "demo", Report: &report}
+		if err := NewCreator(fsys, &bytes.Buffer{}).Create(context.Background(), opts); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		if _, err := os.Stat(filepath.Join("demo", ".git")); !os.IsNotExist(err) {
+			t.Errorf("no nested repository should be created inside a work tree, stat err = %v", err)
+		}
+		if info, err := os.Stat("demo"); err != nil {
+			t.Error(err)
+		} else if want := mkdirMode(t); info.Mode().Perm() != want {
+			t.Errorf("project directory mode = %v, want %v as from mkdir", info.Mode().Perm(), want)
+		}
+		if got := gitLog(t, ".", "%s"); got != "root" {
+			t.Errorf("log = %q, want no commit without Commit", got)
+		}
+		if got := status(t); got != "A  demo/main.go\nA  other.txt" {
+			t.Errorf("status = %q, want the project staged", got)
+		}
+		if report.Git != GitParent || report.Repository == "" || report.Commit != "" || !report.Staged {
+			t.Errorf("report = %+v", report)
+		}
+	})
+
+	t.Run("commit", func(t *testing.T) {
+		newParent(t)
+		var report Report
+		opts := Options{Lang: "go", ProjectName: "demo", Commit: true, Report: &report}
+		if err := NewCreator(fsys, &bytes.Buffer{}).Create(context.Background(), opts); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		if got := gitLog(t, ".", "%s"); got != "Add demo\nroot" {
+			t.Errorf("log = %q", got)
+		}
+		if got := status(t); got != "A  other.txt" {
+			t.Errorf("status = %q, want only the unrelated file still staged", got)
+		}
+		if report.Commit == "" || report.Staged {
+			t.Errorf("report = %+v", report)
+		}
+	})
+
+	t.Run("no commit", func(t *testing.T) {
+		newParent(t)
+		opts := Options{Lang: "go", ProjectName: "demo", NoCommit: true}
+		if err := NewCreator(fsys, &bytes.Buffer{}).Create(context.Background(), opts); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		if got := status(t); got != "A  demo/main.go\nA  other.txt" {
+			t.Errorf("status = %q, want the project staged", got)
+		}
+	})
+
+	t.Run("nested", func(t *testing.T) {
+		newParent(t)
+		opts := Options{Lang: "go", ProjectName: "demo", Git: GitNested, Remotes: []Remote{{Name: "origin", URL: "x"}}}
+		if err := NewCreator(fsys, &bytes.Buffer{}).Create(context.Background(), opts); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		if got := gitLog(t, "demo", "%s"); got != defaultCommitMessage {
+			t.Errorf("nested log = %q", got)
+		}
+	})
+
+	t.Run("hook rejected", func(t *testing.T) {
+		newParent(t)
+		if err := os.WriteFile(filepath.Join(".git", "hooks", "pre-commit"), []byte("#!/bin/sh\nexit 1\n"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := NewCreator(fsys, &bytes.Buffer{}).Create(context.Background(), Options{Lang: "go", ProjectName: "staged"}); err != nil {
+			t.Fatalf("staging alone should not run commit hooks, Create() error = %v", err)
+		}
+		err := NewCreator(fsys, &bytes.Buffer{}).Create(context.Background(), Options{Lang: "go", ProjectName: "demo", Commit: true})
+		if !errors.Is(err, git.ErrHookRejected) {
+			t.Fatalf("Create() error = %v, want ErrHookRejected", err)
+		}
+		if _, err := os.Stat("demo"); !os.IsNotExist(err) {
+			t.Errorf("project should be removed after a rejected commit, stat err = %v", err)
+		}
+		if got := status(t); got != "A  other.txt\nA  staged/main.go" {
+			t.Errorf("status = %q, want the project unstaged", got)
+		}
+	})
+}
+
+func TestCreate_GitParentFake(t *testing.T) {
+	fsys := fstest.MapFS{"go/main.go": {Data: []byte("package main\n")}}
+
+	t.Run("outside a repository", func(t *testing.T) {
+		chdirTemp(t)
+		creator := NewCreator(fsys, &bytes.Buffer{})
+		creator.SetGit(&git.Fake{})
+		err := creator.Create(context.Background(), Options{Lang: "go", ProjectName: "demo", Git: GitParent})
+		if err == nil || !strings.Contains(err.Error(), "not in one") {
+			t.Errorf("Create() error = %v, want not in a repository", err)
+		}
+	})
+
+	t.Run("new repository options", func(t *testing.T) {
+		dir := chdirTemp(t)
+		fake := &git.Fake{}
+		if err := fake.Open(dir).Init(context.Background(), git.InitOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		creator := NewCreator(fsys, &bytes.Buffer{})
+		creator.SetGit(fake)
+		err := creator.Create(context.Background(), Options{Lang: "go", ProjectName: "demo", InitialBranch: "trunk"})
+		if err == nil || !strings.Contains(err.Error(), "--git=nested") {
+			t.Errorf("Create() error = %v, want guidance to use --git=nested", err)
+		}
+		if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+			t.Errorf("nothing should be written, found %v", entries)
+		}
+	})
+
+	t.Run("commit options without commit", func(t *testing.T) {
+		dir := chdirTemp(t)
+		fake := &git.Fake{}
+		if err := fake.Open(dir).Init(context.Background(), git.InitOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		creator := NewCreator(fsys, &bytes.Buffer{})
+		creator.SetGit(fake)
+		err := creator.Create(context.Background(), Options{Lang: "go", ProjectName: "demo", Signoff: true})
+		if err == nil || !strings.Contains(err.Error(), "--commit") {
+			t.Errorf("Create() error = %v, want guidance to use --commit", err)
+		}
+	})
+
+	t.Run("merge", func(t *testing.T) {
+		dir := chdirTemp(t)
+		if err := os.Mkdir("demo", 0o755); err != nil {
+			t.Fatal(err)
+		}
+		fake := &git.Fake{}
+		if err := fake.Open(dir).Init(context.Background(), git.InitOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		creator := NewCreator(fsys, &bytes.Buffer{})
+		creator.SetGit(fake)
+		if err := creator.Create(context.Background(), Options{Lang: "go", ProjectName: "demo", Merge: true}); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		if repos := fake.Repositories(); len(repos) != 1 || len(repos[0].Staged)+len(repos[0].Commits) != 0 {
+			t.Errorf("a merge inside a repository should leave it to the user, got %+v", repos)
+		}
+	})
+}
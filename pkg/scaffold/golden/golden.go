@@ -0,0 +1,304 @@
+// Package golden tests templates against golden directories. Each case
+// renders a template in memory with a set of variables and compares the
+// result, file by file, with a directory holding the expected project.
+//
+// A golden directory lists its cases in cases.yaml and keeps each case's
+// expected output in a subdirectory named after it:
+//
+//	testdata/go/
+//	  cases.yaml
+//	  default/
+//	    go.mod
+//	    main.go
+//
+// Files the template copies verbatim, neither rendered from a .tmpl file
+// nor given a templated name, are left out of golden directories: the
+// template itself already pins their content. Check compares one case with
+// its golden output or rewrites it. Package
+// scaffoldtest runs the cases from Go tests, and "project template test"
+// from the command line.
+package golden
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/JackDrogon/project/pkg/scaffold"
+	"gopkg.in/yaml.v3"
+)
+
+// CasesFile lists the cases of a golden directory.
+const CasesFile = "cases.yaml"
+
+// Defaults that keep golden output the same on every machine and in every
+// year. They apply when a case leaves the field empty; an empty module
+// path is the project name, whatever git is configured with.
+const (
+	DefaultProjectName = "example"
+	DefaultAuthor      = "Example Author"
+	DefaultAuthorEmail = "author@example.com"
+	DefaultYear        = 2000
+)
+
+// Case is one set of variables to render a template with.
+type Case struct {
+	Name        string         `yaml:"name"`    // subdirectory of the golden directory
+	ProjectName string         `yaml:"project"` // default DefaultProjectName
+	ModulePath  string         `yaml:"module"`  // default ProjectName
+	Author      string         `yaml:"author"`  // default DefaultAuthor
+	AuthorEmail string         `yaml:"email"`   // default DefaultAuthorEmail
+	Year        int            `yaml:"year"`    // default DefaultYear
+	Vars        map[string]any `yaml:"vars"`
+}
+
+// Options returns the options to render lang with for c.
+func (c Case) Options(lang string) scaffold.Options {
+	opts := scaffold.Options{
+		Lang:        lang,
+		ProjectName: c.ProjectName,
+		ModulePath:  c.ModulePath,
+		Author:      c.Author,
+		AuthorEmail: c.AuthorEmail,
+		Year:        c.Year,
+		Vars:        c.Vars,
+	}
+	if opts.ProjectName == "" {
+		opts.ProjectName = DefaultProjectName
+	}
+	if opts.ModulePath == "" {
+		opts.ModulePath = opts.ProjectName
+	}
+	if opts.Author == "" {
+		opts.Author = DefaultAuthor
+	}
+	if opts.AuthorEmail == "" {
+		opts.AuthorEmail = DefaultAuthorEmail
+	}
+	if opts.Year == 0 {
+		opts.Year = DefaultYear
+	}
+	return opts
+}
+
+// LoadCases reads the cases of the golden directory dir.
+func LoadCases(dir string) ([]Case, error) {
+	data, err := os.ReadFile(filepath.Join(dir, CasesFile))
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Cases []Case `yaml:"cases"`
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&file); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", filepath.Join(dir, CasesFile), err)
+	}
+	if len(file.Cases) == 0 {
+		return nil, fmt.Errorf("%s has no cases", filepath.Join(dir, CasesFile))
+	}
+
+	seen := make(map[string]bool)
+	for _, c := range file.Cases {
+		switch {
+		case c.Name == "" || c.Name == CasesFile || !filepath.IsLocal(c.Name) || filepath.Base(c.Name) != c.Name:
+			return nil, fmt.Errorf("invalid %s: case name %q must be a plain directory name", CasesFile, c.Name)
+		case seen[c.Name]:
+			return nil, fmt.Errorf("invalid %s: duplicate case %q", CasesFile, c.Name)
+		}
+		seen[c.Name] = true
+	}
+	return file.Cases, nil
+}
+
+// Kinds of Mismatch.
+const (
+	Missing    = "missing"    // in the golden directory but not rendered
+	Unexpected = "unexpected" // rendered but not in the golden directory
+	Content    = "content"    // rendered with different content
+	Mode       = "mode"       // rendered with a different executable bit
+)
+
+// Mismatch is a difference between the rendered project and its golden
+// directory.
+type Mismatch struct {
+	Path string // slash path relative to the project root
+	Kind string
+	Diff string // unified diff from golden to rendered, for Content
+}
+
+// String describes the mismatch, followed by the diff if there is one.
+func (m Mismatch) String() string {
+	var s string
+	switch m.Kind {
+	case Missing:
+		s = m.Path + ": in golden output but not rendered"
+	case Unexpected:
+		s = m.Path + ": rendered but not in golden output"
+	case Mode:
+		s = m.Path + ": executable bit differs from golden output"
+	default:
+		s = m.Path + ": content differs from golden output"
+	}
+	if m.Diff != "" {
+		s += "\n" + strings.TrimSuffix(m.Diff, "\n")
+	}
+	return s
+}
+
+// Compare returns the differences between the files of got and those of
+// the directory golden, ordered by path. Directories are compared only
+// through the files they contain, since git does not track empty ones.
+// Paths in verbatim are skipped on both sides.
+func Compare(got fs.FS, golden string, verbatim map[string]bool) ([]Mismatch, error) {
+	want, err := files(os.DirFS(golden), verbatim)
+	if err != nil {
+		return nil, err
+	}
+	have, err := files(got, verbatim)
+	if err != nil {
+		return nil, err
+	}
+
+	var mismatches []Mismatch
+	for name, w := range want {
+		h, ok := have[name]
+		switch {
+		case !ok:
+			mismatches = append(mismatches, Mismatch{Path: name, Kind: Missing})
+		case !bytes.Equal(w.data, h.data):
+			m := Mismatch{Path: name, Kind: Content}
+			if isBinary(w.data) || isBinary(h.data) {
+				m.Diff = "binary files differ"
+			} else {
+				m.Diff = scaffold.UnifiedDiff(name, w.data, h.data)
+			}
+			mismatches = append(mismatches, m)
+		case w.exec != h.exec:
+			mismatches = append(mismatches, Mismatch{Path: name, Kind: Mode})
+		}
+	}
+	for name := range have {
+		if _, ok := want[name]; !ok {
+			mismatches = append(mismatches, Mismatch{Path: name, Kind: Unexpected})
+		}
+	}
+	sort.Slice(mismatches, func(i, j int) bool { return mismatches[i].Path < mismatches[j].Path })
+	return mismatches, nil
+}
+
+// Update replaces the directory golden with the files of got, except the
+// paths in verbatim. Files are written with mode 0755 if any executable bit
+// is set and 0644 otherwise.
+func Update(got fs.FS, golden string, verbatim map[string]bool) error {
+	have, err := files(got, verbatim)
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(golden); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(golden, 0755); err != nil {
+		return err
+	}
+	for name, f := range have {
+		path := filepath.Join(golden, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		perm := fs.FileMode(0644)
+		if f.exec {
+			perm = 0755
+		}
+		if err := os.WriteFile(path, f.data, perm); err != nil {
+			return err
+		}
+		// Goldens are committed, so their modes must not depend on the umask.
+		if err := os.Chmod(path, perm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Check renders case c of template lang with creator and compares the
+// result with the golden directory dir/c.Name. With update set, it
+// rewrites the golden output instead and reports no mismatches.
+func Check(ctx context.Context, creator *scaffold.Creator, lang, dir string, c Case, update bool) ([]Mismatch, error) {
+	opts := c.Options(lang)
+	opts.Report = &scaffold.Report{}
+	got, err := creator.Render(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	verbatim, err := verbatimFiles(opts)
+	if err != nil {
+		return nil, err
+	}
+	golden := filepath.Join(dir, c.Name)
+	if update {
+		return nil, Update(got, golden, verbatim)
+	}
+	if _, err := os.Stat(golden); errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("golden directory %s does not exist; run with -update to create it", golden)
+	}
+	return Compare(got, golden, verbatim)
+}
+
+// verbatimFiles returns the slash paths, relative to the project root, of
+// the files opts.Report lists as copied without rendering their content or
+// name.
+func verbatimFiles(opts scaffold.Options) (map[string]bool, error) {
+	verbatim := make(map[string]bool)
+	for _, f := range opts.Report.Files {
+		if f.Rendered || f.Mode.IsDir() || strings.Contains(f.Source, "{{") {
+			continue
+		}
+		rel, err := filepath.Rel(opts.ProjectName, f.Dest)
+		if err != nil {
+			return nil, err
+		}
+		verbatim[filepath.ToSlash(rel)] = true
+	}
+	return verbatim, nil
+}
+
+// file is a regular file as Compare sees it.
+type file struct {
+	data []byte
+	exec bool
+}
+
+// files reads every regular file of fsys by slash path, except those in
+// skip.
+func files(fsys fs.FS, skip map[string]bool) (map[string]file, error) {
+	out := make(map[string]file)
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || skip[name] {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		out[name] = file{data: data, exec: info.Mode()&0111 != 0}
+		return nil
+	})
+	return out, err
+}
+
+// isBinary reports whether data looks like a binary file.
+func isBinary(data []byte) bool {
+	return bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0
+}
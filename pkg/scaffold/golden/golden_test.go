@@ -0,0 +1,132 @@
+package golden
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/JackDrogon/project/pkg/scaffold"
+)
+
+func testCreator() *scaffold.Creator {
+	return scaffold.NewCreator(fstest.MapFS{
+		"svc/template.yaml":  {Data: []byte("variables:\n  - name: port\n    type: int\n    default: 8080\n")},
+		"svc/main.go.tmpl":   {Data: []byte("package main // {{.ProjectName}} {{.Author}} {{.Year}} :{{.port}}\n")},
+		"svc/run.sh.tmpl":    {Data: []byte("#!/bin/sh\n"), Mode: 0755},
+		"svc/docs/a.md.tmpl": {Data: []byte("a\n")},
+		"svc/LICENSE":        {Data: []byte("copied verbatim\n")},
+	}, io.Discard)
+}
+
+func writeCases(t *testing.T, dir, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, CasesFile), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadCases(t *testing.T) {
+	dir := t.TempDir()
+	writeCases(t, dir, "cases:\n  - name: default\n  - name: custom\n    project: api\n    vars:\n      port: 9090\n")
+	cases, err := LoadCases(dir)
+	if err != nil {
+		t.Fatalf("LoadCases() error = %v", err)
+	}
+	want := []Case{{Name: "default"}, {Name: "custom", ProjectName: "api", Vars: map[string]any{"port": 9090}}}
+	if !reflect.DeepEqual(cases, want) {
+		t.Errorf("LoadCases() = %+v, want %+v", cases, want)
+	}
+
+	for _, content := range []string{
+		"cases: []\n",
+		"cases:\n  - name: ''\n",
+		"cases:\n  - name: a/b\n",
+		"cases:\n  - name: ..\n",
+		"cases:\n  - name: a\n  - name: a\n",
+		"cases:\n  - name: a\n    projet: typo\n",
+	} {
+		writeCases(t, dir, content)
+		if _, err := LoadCases(dir); err == nil {
+			t.Errorf("LoadCases(%q) expected error, got nil", content)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	ctx := context.Background()
+	creator := testCreator()
+	dir := t.TempDir()
+	c := Case{Name: "default", Vars: map[string]any{"port": "9090"}}
+
+	if _, err := Check(ctx, creator, "svc", dir, c, false); err == nil || !strings.Contains(err.Error(), "-update") {
+		t.Errorf("Check() without golden output error = %v, want a hint to run with -update", err)
+	}
+
+	if _, err := Check(ctx, creator, "svc", dir, c, true); err != nil {
+		t.Fatalf("Check(update) error = %v", err)
+	}
+	golden := filepath.Join(dir, "default")
+	main, err := os.ReadFile(filepath.Join(golden, "main.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "package main // example Example Author 2000 :9090\n"; string(main) != want {
+		t.Errorf("golden main.go = %q, want %q", main, want)
+	}
+	if info, err := os.Stat(filepath.Join(golden, "run.sh")); err != nil || info.Mode().Perm() != 0755 {
+		t.Errorf("golden run.sh mode = %v (err %v), want 0755", info.Mode(), err)
+	}
+	if _, err := os.Stat(filepath.Join(golden, "LICENSE")); !os.IsNotExist(err) {
+		t.Errorf("verbatim LICENSE should not be in the golden output, stat err = %v", err)
+	}
+
+	mismatches, err := Check(ctx, creator, "svc", dir, c, false)
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if len(mismatches) != 0 {
+		t.Fatalf("Check() right after update = %v, want no mismatches", mismatches)
+	}
+
+	// Drift the golden output in every way Compare reports.
+	if err := os.WriteFile(filepath.Join(golden, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(filepath.Join(golden, "run.sh"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(golden, "docs", "a.md")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(golden, "stale.txt"), []byte("x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mismatches, err = Check(ctx, creator, "svc", dir, c, false)
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	var got []string
+	for _, m := range mismatches {
+		got = append(got, m.Path+" "+m.Kind)
+	}
+	want := []string{"docs/a.md unexpected", "main.go content", "run.sh mode", "stale.txt missing"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Check() mismatches = %v, want %v", got, want)
+	}
+	if diff := mismatches[1].Diff; !strings.Contains(diff, "+package main // example") {
+		t.Errorf("content mismatch diff = %q", diff)
+	}
+
+	// Updating again removes what is no longer rendered.
+	if _, err := Check(ctx, creator, "svc", dir, c, true); err != nil {
+		t.Fatalf("Check(update) error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(golden, "stale.txt")); !os.IsNotExist(err) {
+		t.Errorf("stale.txt should be removed by update, stat err = %v", err)
+	}
+}
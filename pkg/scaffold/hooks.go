@@ -0,0 +1,204 @@
+package scaffold
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultHookTimeout bounds a hook that does not set its own timeout.
+const defaultHookTimeout = 5 * time.Minute
+
+// Hooks lists the commands a template runs around generation. Pre hooks
+// run in the empty project directory before any file is written; post
+// hooks run once the files are written and moved to the destination, and
+// before git is initialized, so their output (e.g. go.sum) is part of the
+// initial commit.
+type Hooks struct {
+	Pre  []Hook `yaml:"pre"`
+	Post []Hook `yaml:"post"`
+}
+
+// Hook is a shell command run in the project directory. Run is passed to
+// the shell as written, not rendered as a template, so values supplied with
+// --set cannot inject commands; every template variable is exported to the
+// command's environment as PROJECT_<NAME> instead, e.g. PROJECT_NAME and
+// PROJECT_MODULE_PATH.
+// A hook that fails or outlives Timeout aborts generation unless it is
+// Optional, in which case a warning is printed instead.
+type Hook struct {
+	Run      string        `yaml:"run"`
+	Timeout  time.Duration `yaml:"timeout"`
+	Optional bool          `yaml:"optional"`
+}
+
+func (h Hook) validate() error {
+	if strings.TrimSpace(h.Run) == "" {
+		return errors.New("hook has an empty run command")
+	}
+	if h.Timeout < 0 {
+		return fmt.Errorf("hook %q has a negative timeout", h.Run)
+	}
+	return checkNoTemplate("hook", h.Run)
+}
+
+// checkNoTemplate rejects template actions in a command, which would reach
+// the shell unexpanded.
+func checkNoTemplate(kind, run string) error {
+	if strings.Contains(run, "{{") {
+		return fmt.Errorf("%s %q uses template syntax, which is not expanded in commands; use the PROJECT_* environment variables instead", kind, run)
+	}
+	return nil
+}
+
+func (h Hook) timeout() time.Duration {
+	if h.Timeout == 0 {
+		return defaultHookTimeout
+	}
+	return h.Timeout
+}
+
+// runHooks runs hooks in order in dir, streaming their output to w. stage
+// names the hooks in messages, e.g. "pre" or "post".
+func runHooks(ctx context.Context, w io.Writer, stage string, hooks []Hook, dir string, vars TemplateVars) error {
+	env := append(os.Environ(), hookEnv(vars)...)
+	for _, h := range hooks {
+		_, _ = fmt.Fprintf(w, "  run %s\n", h.Run)
+		err := runHook(ctx, w, h.Run, h.timeout(), dir, env)
+		if err == nil {
+			continue
+		}
+		err = markErr(fmt.Errorf("%s hook %q failed: %w", stage, h.Run, err), ErrHookFailed)
+		if !h.Optional || ctx.Err() != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(w, "Warning: %v; continuing because the hook is optional\n", err)
+	}
+	return nil
+}
+
+func runHook(parent context.Context, w io.Writer, command string, timeout time.Duration, dir string, env []string) error {
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+
+	name, args := "sh", []string{"-c", command}
+	if runtime.GOOS == "windows" {
+		name, args = "cmd", []string{"/C", command}
+	}
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.Env = env
+	out := &indentWriter{w: w, indent: "    "}
+	cmd.Stdout = out
+	cmd.Stderr = out
+	// Don't wait forever on children that keep the output pipe open.
+	cmd.WaitDelay = time.Second
+
+	err := cmd.Run()
+	out.flush()
+	if err := parent.Err(); err != nil {
+		return err
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("timed out after %s", timeout)
+	}
+	return err
+}
+
+// hookEnv returns the template variables as PROJECT_<NAME>=value pairs in
+// a stable order. Lists are joined with commas.
+func hookEnv(vars TemplateVars) []string {
+	data := vars.data()
+	names := make([]string, 0, len(data))
+	for name := range data {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	env := make([]string, 0, len(names))
+	for _, name := range names {
+		env = append(env, hookEnvName(name)+"="+hookEnvValue(data[name]))
+	}
+	return env
+}
+
+// hookEnvName maps a variable name to its environment name, avoiding a
+// doubled prefix: ProjectName becomes PROJECT_NAME, license PROJECT_LICENSE.
+func hookEnvName(name string) string {
+	upper := screamingCase(name)
+	if strings.HasPrefix(upper, "PROJECT_") {
+		return upper
+	}
+	return "PROJECT_" + upper
+}
+
+// checkHookEnvNames fails when two of names, or one of them and a built-in
+// variable, map to the same environment name, so that one would silently
+// replace the other in the hooks' environment.
+func checkHookEnvNames(names []string) error {
+	owners := make(map[string]string, len(builtinVars)+len(names))
+	for _, name := range builtinVars {
+		owners[hookEnvName(name)] = name
+	}
+	for _, name := range names {
+		env := hookEnvName(name)
+		if owner, ok := owners[env]; ok && owner != name {
+			return fmt.Errorf("variables %q and %q are both passed to hooks as %s; rename %q", owner, name, env, name)
+		}
+		owners[env] = name
+	}
+	return nil
+}
+
+func hookEnvValue(value any) string {
+	if list, ok := value.([]any); ok {
+		parts := make([]string, len(list))
+		for i, item := range list {
+			parts[i] = fmt.Sprint(item)
+		}
+		return strings.Join(parts, ",")
+	}
+	if list, ok := value.([]string); ok {
+		return strings.Join(list, ",")
+	}
+	return fmt.Sprint(value)
+}
+
+// indentWriter prefixes every line written to w with indent, so hook
+// output stands apart from the Creator's own progress lines.
+type indentWriter struct {
+	w      io.Writer
+	indent string
+	buf    bytes.Buffer
+}
+
+func (iw *indentWriter) Write(p []byte) (int, error) {
+	iw.buf.Write(p)
+	for {
+		line, err := iw.buf.ReadBytes('\n')
+		if err != nil {
+			// Keep the partial line for the next write.
+			iw.buf.Write(line)
+			return len(p), nil
+		}
+		if _, err := fmt.Fprintf(iw.w, "%s%s", iw.indent, line); err != nil {
+			return len(p), err
+		}
+	}
+}
+
+// flush writes any trailing partial line.
+func (iw *indentWriter) flush() {
+	if iw.buf.Len() > 0 {
+		_, _ = fmt.Fprintf(iw.w, "%s%s\n", iw.indent, iw.buf.Bytes())
+		iw.buf.Reset()
+	}
+}
@@ -0,0 +1,246 @@
+package scaffold
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func requireSh(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+}
+
+func TestParseManifest_Hooks(t *testing.T) {
+	m, err := ParseManifest([]byte(`
+hooks:
+  pre:
+    - run: echo start
+  post:
+    - run: go mod tidy
+      timeout: 90s
+      optional: true
+`))
+	if err != nil {
+		t.Fatalf("ParseManifest() error = %v", err)
+	}
+	if len(m.Hooks.Pre) != 1 || m.Hooks.Pre[0].timeout() != defaultHookTimeout {
+		t.Errorf("pre hooks = %+v, want one with the default timeout", m.Hooks.Pre)
+	}
+	want := Hook{Run: "go mod tidy", Timeout: 90 * time.Second, Optional: true}
+	if len(m.Hooks.Post) != 1 || m.Hooks.Post[0] != want {
+		t.Errorf("post hooks = %+v, want [%+v]", m.Hooks.Post, want)
+	}
+
+	for name, yaml := range map[string]string{
+		"empty run":        "hooks:\n  post:\n    - run: ' '\n",
+		"negative timeout": "hooks:\n  pre:\n    - run: ls\n      timeout: -1s\n",
+		"template syntax":  "hooks:\n  post:\n    - run: 'echo {{.ProjectName}}'\n",
+		"bad duration":     "hooks:\n  post:\n    - run: ls\n      timeout: soon\n",
+	} {
+		if _, err := ParseManifest([]byte(yaml)); err == nil {
+			t.Errorf("%s: ParseManifest() expected error, got nil", name)
+		}
+	}
+}
+
+func TestHookEnvName(t *testing.T) {
+	tests := map[string]string{
+		"ProjectName": "PROJECT_NAME",
+		"ModulePath":  "PROJECT_MODULE_PATH",
+		"Year":        "PROJECT_YEAR",
+		"license":     "PROJECT_LICENSE",
+		"cmakeMin":    "PROJECT_CMAKE_MIN",
+	}
+	for in, want := range tests {
+		if got := hookEnvName(in); got != want {
+			t.Errorf("hookEnvName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestRunHooks(t *testing.T) {
+	requireSh(t)
+	vars := TemplateVars{
+		ProjectName: "demo",
+		ModulePath:  "example.com/demo",
+		Extra:       map[string]any{"features": []any{"docker", "ci"}},
+	}
+
+	t.Run("exports variables", func(t *testing.T) {
+		var out bytes.Buffer
+		hooks := []Hook{{Run: `echo "$PROJECT_NAME $PROJECT_MODULE_PATH $PROJECT_FEATURES"; printf partial`}}
+		if err := runHooks(context.Background(), &out, "post", hooks, t.TempDir(), vars); err != nil {
+			t.Fatalf("runHooks() error = %v", err)
+		}
+		want := "  run echo \"$PROJECT_NAME $PROJECT_MODULE_PATH $PROJECT_FEATURES\"; printf partial\n" +
+			"    demo example.com/demo docker,ci\n" +
+			"    partial\n"
+		if out.String() != want {
+			t.Errorf("output = %q, want %q", out.String(), want)
+		}
+	})
+
+	t.Run("values are not shell code", func(t *testing.T) {
+		dir := t.TempDir()
+		var out bytes.Buffer
+		evil := TemplateVars{ProjectName: "demo", Extra: map[string]any{"evil": "$(touch pwned)"}}
+		if err := runHooks(context.Background(), &out, "post", []Hook{{Run: `echo "$PROJECT_EVIL"`}}, dir, evil); err != nil {
+			t.Fatalf("runHooks() error = %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "pwned")); err == nil {
+			t.Error("a variable's value was run as a command")
+		}
+		if !strings.Contains(out.String(), "    $(touch pwned)\n") {
+			t.Errorf("output = %q, want the value echoed verbatim", out.String())
+		}
+	})
+
+	t.Run("runs in dir", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := runHooks(context.Background(), &bytes.Buffer{}, "post", []Hook{{Run: "touch marker"}}, dir, vars); err != nil {
+			t.Fatalf("runHooks() error = %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "marker")); err != nil {
+			t.Errorf("marker missing: %v", err)
+		}
+	})
+
+	t.Run("failure stops later hooks", func(t *testing.T) {
+		dir := t.TempDir()
+		hooks := []Hook{{Run: "exit 3"}, {Run: "touch marker"}}
+		err := runHooks(context.Background(), &bytes.Buffer{}, "pre", hooks, dir, vars)
+		if err == nil || !strings.Contains(err.Error(), `pre hook "exit 3" failed`) {
+			t.Fatalf("runHooks() error = %v, want pre hook failure", err)
+		}
+		if !errors.Is(err, ErrHookFailed) {
+			t.Errorf("runHooks() error = %v, want errors.Is ErrHookFailed", err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "marker")); err == nil {
+			t.Error("hooks after a failure should not run")
+		}
+	})
+
+	t.Run("optional failure warns", func(t *testing.T) {
+		var out bytes.Buffer
+		hooks := []Hook{{Run: "exit 1", Optional: true}, {Run: "echo after"}}
+		if err := runHooks(context.Background(), &out, "post", hooks, t.TempDir(), vars); err != nil {
+			t.Fatalf("runHooks() error = %v", err)
+		}
+		if !strings.Contains(out.String(), "Warning:") || !strings.Contains(out.String(), "after") {
+			t.Errorf("output = %q, want a warning and the next hook's output", out.String())
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		hooks := []Hook{{Run: "sleep 5", Timeout: 50 * time.Millisecond}}
+		start := time.Now()
+		err := runHooks(context.Background(), &bytes.Buffer{}, "post", hooks, t.TempDir(), vars)
+		if err == nil || !strings.Contains(err.Error(), "timed out after 50ms") {
+			t.Fatalf("runHooks() error = %v, want timeout", err)
+		}
+		if elapsed := time.Since(start); elapsed > 3*time.Second {
+			t.Errorf("runHooks() took %s after the timeout", elapsed)
+		}
+	})
+}
+
+func TestCreate_Hooks(t *testing.T) {
+	requireSh(t)
+	fsys := fstest.MapFS{
+		"go/template.yaml": {Data: []byte(`
+hooks:
+  pre:
+    - run: test ! -e main.go && echo empty > pre.txt
+  post:
+    - run: test -e main.go && echo $PROJECT_NAME > post.txt
+`)},
+		"go/main.go": {Data: []byte("package main")},
+	}
+	failing := fstest.MapFS{
+		"go/template.yaml": {Data: []byte("hooks:\n  post:\n    - run: exit 1\n")},
+		"go/main.go":       {Data: []byte("package main")},
+	}
+
+	t.Run("post hook output is committed", func(t *testing.T) {
+		requireGit(t)
+		chdirTemp(t)
+
+		if err := NewCreator(fsys, &bytes.Buffer{}).Create(context.Background(), Options{Lang: "go", ProjectName: "demo"}); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		for name, want := range map[string]string{"pre.txt": "empty\n", "post.txt": "demo\n"} {
+			got, err := os.ReadFile(filepath.Join("demo", name))
+			if err != nil || string(got) != want {
+				t.Errorf("%s = %q, %v; want %q", name, got, err, want)
+			}
+		}
+		out, err := exec.Command("git", "-C", "demo", "ls-files").Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(out), "post.txt") {
+			t.Errorf("post.txt should be committed, ls-files = %q", out)
+		}
+	})
+
+	t.Run("post hooks run in the destination", func(t *testing.T) {
+		dir := chdirTemp(t)
+		pwd := fstest.MapFS{
+			"go/template.yaml": {Data: []byte("hooks:\n  post:\n    - run: pwd -P > pwd.txt\n")},
+			"go/main.go":       {Data: []byte("package main")},
+		}
+		if err := NewCreator(pwd, &bytes.Buffer{}).Create(context.Background(), Options{Lang: "go", ProjectName: "demo", NoGit: true}); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		want, err := filepath.EvalSymlinks(filepath.Join(dir, "demo"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.TrimSpace(readString(t, filepath.Join("demo", "pwd.txt"))); got != want {
+			t.Errorf("post hook ran in %q, want %q", got, want)
+		}
+	})
+
+	t.Run("no hooks", func(t *testing.T) {
+		requireGit(t)
+		chdirTemp(t)
+
+		if err := NewCreator(failing, &bytes.Buffer{}).Create(context.Background(), Options{Lang: "go", ProjectName: "demo", NoHooks: true}); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	})
+
+	t.Run("failure leaves nothing behind", func(t *testing.T) {
+		dir := chdirTemp(t)
+
+		if err := NewCreator(failing, &bytes.Buffer{}).Create(context.Background(), Options{Lang: "go", ProjectName: "demo"}); err == nil {
+			t.Fatal("Create() expected error, got nil")
+		}
+		if _, err := os.Stat("demo"); !os.IsNotExist(err) {
+			t.Errorf("destination should not exist, stat err = %v", err)
+		}
+		assertNoStaging(t, dir)
+	})
+
+	t.Run("dry run lists hooks", func(t *testing.T) {
+		chdirTemp(t)
+
+		var out bytes.Buffer
+		if err := NewCreator(fsys, &out).Create(context.Background(), Options{Lang: "go", ProjectName: "demo", DryRun: true}); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		if !strings.Contains(out.String(), "  run test -e main.go && echo $PROJECT_NAME > post.txt (post hook)") {
+			t.Errorf("dry run output = %q, want the post hook", out.String())
+		}
+	})
+}
@@ -0,0 +1,294 @@
+package scaffold
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// Severity grades a lint issue. Errors make a template unusable or fail
+// at render time; warnings point at likely mistakes.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Lint rules, reported in LintIssue.Rule.
+const (
+	RuleManifest      = "manifest"
+	RuleParse         = "parse"
+	RuleUndefinedVar  = "undefined-variable"
+	RuleUnusedVar     = "unused-variable"
+	RuleMissingSuffix = "missing-tmpl-suffix"
+	RuleFileMode      = "file-mode"
+	RuleLineEndings   = "crlf"
+	RuleBOM           = "bom"
+)
+
+// LintIssue is a problem found in a template.
+type LintIssue struct {
+	Path     string // slash path relative to the template root
+	Line     int    // 1-based; 0 when the issue is not about a line
+	Severity Severity
+	Rule     string
+	Message  string
+}
+
+// String formats the issue as "path:line: severity: message (rule)".
+func (i LintIssue) String() string {
+	loc := i.Path
+	if i.Line > 0 {
+		loc += ":" + strconv.Itoa(i.Line)
+	}
+	return fmt.Sprintf("%s: %s: %s (%s)", loc, i.Severity, i.Message, i.Rule)
+}
+
+// utf8BOM starts files saved with a byte order mark.
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// parseLineRe extracts the line number from a text/template parse error.
+var parseLineRe = regexp.MustCompile(`^template: [^:]*:(\d+):`)
+
+// LintTemplate checks the template at the root of fsys and returns its
+// issues ordered by path and line. It reports templates that fail to
+// parse, references to variables that are neither built in nor declared
+// in the manifest, declared variables that are never used, files with
+// template actions but no .tmpl suffix, suspicious permissions, and CRLF
+// line endings or byte order marks. The error is only for failures to
+// read fsys.
+//
+// Read-only files, as served by embed.FS, carry no meaningful permissions
+// and are not checked for them.
+func LintTemplate(fsys fs.FS) ([]LintIssue, error) {
+	l := &linter{used: make(map[string]bool)}
+
+	manifest, err := LoadManifest(fsys, ".")
+	if err != nil {
+		l.add(manifestName, 0, SeverityError, RuleManifest, err.Error())
+	} else {
+		l.declared = make(map[string]bool)
+		for _, v := range manifest.Variables {
+			l.declared[v.Name] = true
+		}
+		for _, rule := range manifest.Files {
+			l.text(manifestName, rule.condition(), false)
+		}
+		for _, hook := range append(manifest.Hooks.Pre, manifest.Hooks.Post...) {
+			l.command(hook.Run)
+		}
+		for _, check := range manifest.Verify {
+			l.command(check.Run)
+		}
+	}
+
+	err = fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		switch {
+		case name == "." || name == manifestName:
+			return nil
+		case d.IsDir() && d.Name() == vcsDir:
+			return fs.SkipDir
+		}
+		if strings.Contains(d.Name(), "{{") {
+			l.text(name, d.Name(), false)
+		}
+		if d.IsDir() {
+			return nil
+		}
+		return l.file(fsys, name, d)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if manifest != nil {
+		for _, v := range manifest.Variables {
+			if !l.used[v.Name] {
+				l.add(manifestName, 0, SeverityWarning, RuleUnusedVar, fmt.Sprintf("variable %q is declared but never used", v.Name))
+			}
+		}
+	}
+
+	sort.SliceStable(l.issues, func(i, j int) bool {
+		a, b := l.issues[i], l.issues[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Line < b.Line
+	})
+	return l.issues, nil
+}
+
+type linter struct {
+	declared map[string]bool // nil when the manifest could not be read
+	used     map[string]bool
+	issues   []LintIssue
+}
+
+func (l *linter) add(path string, line int, severity Severity, rule, message string) {
+	l.issues = append(l.issues, LintIssue{Path: path, Line: line, Severity: severity, Rule: rule, Message: message})
+}
+
+// file checks a single template file.
+func (l *linter) file(fsys fs.FS, name string, d fs.DirEntry) error {
+	info, err := d.Info()
+	if err != nil {
+		return err
+	}
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return err
+	}
+	l.mode(name, info.Mode(), data)
+
+	// Binary files are copied as they are.
+	if bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0 {
+		return nil
+	}
+	if bytes.HasPrefix(data, utf8BOM) {
+		l.add(name, 1, SeverityWarning, RuleBOM, "file starts with a UTF-8 byte order mark")
+	}
+	if i := bytes.Index(data, []byte("\r\n")); i >= 0 {
+		l.add(name, lineAt(data, i), SeverityWarning, RuleLineEndings, "file has CRLF line endings")
+	}
+
+	if isTemplateFile(name) {
+		l.text(name, string(data), true)
+	} else if i := bytes.Index(data, []byte("{{")); i >= 0 {
+		l.add(name, lineAt(data, i), SeverityWarning, RuleMissingSuffix,
+			"file contains {{ but has no .tmpl suffix, so it is copied without rendering")
+	}
+	return nil
+}
+
+// mode checks a file's permissions against its content.
+func (l *linter) mode(name string, mode fs.FileMode, data []byte) {
+	perm := mode.Perm()
+	if perm&0200 == 0 {
+		return
+	}
+	shebang := bytes.HasPrefix(data, []byte("#!"))
+	switch {
+	case perm&0002 != 0:
+		l.add(name, 0, SeverityWarning, RuleFileMode, fmt.Sprintf("file is world-writable (%04o)", perm))
+	case shebang && perm&0100 == 0:
+		l.add(name, 0, SeverityWarning, RuleFileMode, "script starts with #! but is not executable")
+	case !shebang && perm&0100 != 0 && bytes.IndexByte(data, 0) < 0:
+		l.add(name, 0, SeverityWarning, RuleFileMode, "text file is executable but has no #! line")
+	}
+}
+
+// text parses template text from the file at name and checks the
+// variables it references. lines is false when the text is not the file's
+// content, such as its name, so issues carry no line number.
+func (l *linter) text(name, text string, lines bool) {
+	tmpl, err := parseNamedTemplate(name, text, TemplateVars{})
+	if err != nil {
+		line := 0
+		if m := parseLineRe.FindStringSubmatch(err.Error()); m != nil && lines {
+			line, _ = strconv.Atoi(m[1])
+		}
+		l.add(name, line, SeverityError, RuleParse, err.Error())
+		return
+	}
+
+	for _, t := range tmpl.Templates() {
+		if t.Tree == nil {
+			continue
+		}
+		templateRefs(t, func(ref string, pos parse.Pos) {
+			l.used[ref] = true
+			if l.declared == nil || isBuiltinVar(ref) || l.declared[ref] {
+				return
+			}
+			line := 0
+			if lines {
+				line = lineAt([]byte(text), int(pos))
+			}
+			l.add(name, line, SeverityError, RuleUndefinedVar,
+				fmt.Sprintf("{{.%s}} is not a built-in variable or declared in %s", ref, manifestName))
+		})
+	}
+}
+
+// command marks the declared variables a hook or verify command reads
+// from its environment as used.
+func (l *linter) command(run string) {
+	for name := range l.declared {
+		if strings.Contains(run, hookEnvName(name)) {
+			l.used[name] = true
+		}
+	}
+}
+
+// lineAt returns the 1-based line of the byte offset in data.
+func lineAt(data []byte, offset int) int {
+	offset = min(max(offset, 0), len(data))
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
+
+// templateRefs calls fn for each top-level variable t references, such as
+// ProjectName in {{.ProjectName}} or {{$.ProjectName}}. Fields inside range
+// and with blocks are relative to the new dot and are not reported.
+func templateRefs(t *template.Template, fn func(name string, pos parse.Pos)) {
+	var walk func(node parse.Node, atRoot bool)
+	walk = func(node parse.Node, atRoot bool) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child, atRoot)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe, atRoot)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, cmd := range n.Cmds {
+				walk(cmd, atRoot)
+			}
+		case *parse.CommandNode:
+			for _, arg := range n.Args {
+				walk(arg, atRoot)
+			}
+		case *parse.ChainNode:
+			walk(n.Node, atRoot)
+		case *parse.FieldNode:
+			if atRoot {
+				fn(n.Ident[0], n.Pos)
+			}
+		case *parse.VariableNode:
+			if n.Ident[0] == "$" && len(n.Ident) > 1 {
+				fn(n.Ident[1], n.Pos)
+			}
+		case *parse.IfNode:
+			walk(n.Pipe, atRoot)
+			walk(n.List, atRoot)
+			walk(n.ElseList, atRoot)
+		case *parse.RangeNode:
+			walk(n.Pipe, atRoot)
+			walk(n.List, false)
+			walk(n.ElseList, atRoot)
+		case *parse.WithNode:
+			walk(n.Pipe, atRoot)
+			walk(n.List, false)
+			walk(n.ElseList, atRoot)
+		case *parse.TemplateNode:
+			walk(n.Pipe, atRoot)
+		}
+	}
+	walk(t.Tree.Root, true)
+}
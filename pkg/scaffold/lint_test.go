@@ -0,0 +1,124 @@
+package scaffold
+
+import (
+	"io/fs"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/JackDrogon/project/pkg/templates"
+)
+
+func TestLintTemplate(t *testing.T) {
+	fsys := fstest.MapFS{
+		"template.yaml": {Data: []byte("variables:\n" +
+			"  - name: license\n" +
+			"  - name: ci\n" +
+			"  - name: unused\n" +
+			"  - name: port\n" +
+			"    default: 80\n" +
+			"files:\n" +
+			"  - path: .github\n" +
+			"    when: .ci\n" +
+			"hooks:\n" +
+			"  post:\n" +
+			"    - run: echo $PROJECT_PORT\n"), Mode: 0644},
+		"main.go.tmpl": {Data: []byte("package main\n\n" +
+			"// {{.ProjectName}} {{.license}}\n" +
+			"{{range .Nope}}{{.Inner}}{{end}}\n" +
+			"{{with $.Missing}}{{end}}\n"), Mode: 0644},
+		"bad.tmpl":            {Data: []byte("ok\n{{.ProjectName\n"), Mode: 0644},
+		"notes.md":            {Data: []byte("plain\nsee {{.ProjectName}}\n"), Mode: 0644},
+		"dos.txt":             {Data: []byte("\xef\xbb\xbfa\r\nb\r\n"), Mode: 0644},
+		"run.sh":              {Data: []byte("#!/bin/sh\n"), Mode: 0644},
+		"data.txt":            {Data: []byte("text\n"), Mode: 0755},
+		"open.txt":            {Data: []byte("text\n"), Mode: 0666},
+		"logo.bin":            {Data: []byte("\x00{{\r\n"), Mode: 0644},
+		"{{.Bogus}}/keep.txt": {Data: []byte("x\n"), Mode: 0644},
+		".git/HEAD":           {Data: []byte("{{"), Mode: 0644},
+	}
+
+	issues, err := LintTemplate(fsys)
+	if err != nil {
+		t.Fatalf("LintTemplate() error = %v", err)
+	}
+	type key struct {
+		Path string
+		Line int
+		Rule string
+	}
+	var got []key
+	for _, issue := range issues {
+		got = append(got, key{issue.Path, issue.Line, issue.Rule})
+	}
+	want := []key{
+		{"bad.tmpl", 3, RuleParse},
+		{"data.txt", 0, RuleFileMode},
+		{"dos.txt", 1, RuleBOM},
+		{"dos.txt", 1, RuleLineEndings},
+		{"main.go.tmpl", 4, RuleUndefinedVar},
+		{"main.go.tmpl", 5, RuleUndefinedVar},
+		{"notes.md", 2, RuleMissingSuffix},
+		{"open.txt", 0, RuleFileMode},
+		{"run.sh", 0, RuleFileMode},
+		{"template.yaml", 0, RuleUnusedVar},
+		{"{{.Bogus}}", 0, RuleUndefinedVar},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LintTemplate() issues =\n%v\nwant\n%v", issues, want)
+	}
+	for _, issue := range issues {
+		if issue.Rule == RuleUnusedVar && issue.Message != `variable "unused" is declared but never used` {
+			t.Errorf("unused variable issue = %q", issue.Message)
+		}
+	}
+}
+
+func TestLintTemplate_ReadOnly(t *testing.T) {
+	// Read-only modes, as embed.FS reports them, are not checked.
+	fsys := fstest.MapFS{
+		"run.sh": {Data: []byte("#!/bin/sh\n"), Mode: 0444},
+	}
+	issues, err := LintTemplate(fsys)
+	if err != nil {
+		t.Fatalf("LintTemplate() error = %v", err)
+	}
+	if len(issues) != 0 {
+		t.Errorf("LintTemplate() issues = %v, want none", issues)
+	}
+}
+
+func TestLintTemplate_InvalidManifest(t *testing.T) {
+	fsys := fstest.MapFS{
+		"template.yaml": {Data: []byte("variables:\n  - name: ProjectName\n")},
+		"main.go.tmpl":  {Data: []byte("{{.Anything}}\n")},
+	}
+	issues, err := LintTemplate(fsys)
+	if err != nil {
+		t.Fatalf("LintTemplate() error = %v", err)
+	}
+	// Without a manifest, variable references cannot be checked.
+	if len(issues) != 1 || issues[0].Rule != RuleManifest || issues[0].Severity != SeverityError {
+		t.Errorf("LintTemplate() issues = %v, want a single manifest error", issues)
+	}
+}
+
+func TestLintTemplate_BuiltinTemplates(t *testing.T) {
+	for _, lang := range []string{"go", "cpp"} {
+		t.Run(lang, func(t *testing.T) {
+			fsys, err := fs.Sub(templates.FS, lang)
+			if err != nil {
+				t.Fatal(err)
+			}
+			issues, err := LintTemplate(fsys)
+			if err != nil {
+				t.Fatalf("LintTemplate() error = %v", err)
+			}
+			for _, issue := range issues {
+				if issue.Severity == SeverityError {
+					t.Errorf("built-in template: %v", issue)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,284 @@
+package scaffold
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// manifestName is the optional manifest file at the root of a template.
+// It is read by the Creator and never copied into the generated project.
+const manifestName = "template.yaml"
+
+// Variable types accepted in a manifest.
+const (
+	TypeString = "string"
+	TypeBool   = "bool"
+	TypeInt    = "int"
+	TypeList   = "list"
+)
+
+// validVarName matches names usable as {{.name}} in a template.
+var validVarName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Manifest describes a template and the custom variables it accepts.
+type Manifest struct {
+	Description string     `yaml:"description"`
+	Naming      string     `yaml:"naming"` // name rules, such as "go"; default the template name
+	Variables   []Variable `yaml:"variables"`
+	Files       []FileRule `yaml:"files"`
+	Hooks       Hooks      `yaml:"hooks"`
+	Verify      []Check    `yaml:"verify"`
+}
+
+// Variable declares a custom template variable. A variable without a
+// default is required.
+type Variable struct {
+	Name        string `yaml:"name"`
+	Type        string `yaml:"type"`
+	Description string `yaml:"description"`
+	Default     any    `yaml:"default"`
+	Pattern     string `yaml:"pattern"`
+}
+
+// FileRule includes the files matching Path only when the When expression
+// is true. Path is a path.Match pattern relative to the template root,
+// matched against names with the .tmpl suffix stripped; a trailing slash
+// restricts it to directories, whose whole subtree is then included or
+// skipped together. When is a text/template pipeline such as ".docker" or
+// "not .cpplint" or `eq .license "MIT"`.
+type FileRule struct {
+	Path string `yaml:"path"`
+	When string `yaml:"when"`
+}
+
+// Required reports whether the variable must be supplied by the user.
+func (v Variable) Required() bool { return v.Default == nil }
+
+// LoadManifest reads the manifest at the root of the template dir in fsys.
+// A template without a manifest yields an empty Manifest.
+func LoadManifest(fsys fs.FS, dir string) (*Manifest, error) {
+	name := path.Join(dir, manifestName)
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return &Manifest{}, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	return ParseManifest(data)
+}
+
+// ParseManifest decodes and validates manifest YAML.
+func ParseManifest(data []byte) (*Manifest, error) {
+	var m Manifest
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", manifestName, err)
+	}
+	if err := m.validate(); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", manifestName, err)
+	}
+	return &m, nil
+}
+
+func (m *Manifest) validate() error {
+	if _, ok := namingRules[m.Naming]; m.Naming != "" && !ok {
+		return fmt.Errorf("unknown naming %q: must be one of %s", m.Naming, namingRuleNames())
+	}
+	for _, rule := range m.Files {
+		if err := rule.validate(); err != nil {
+			return err
+		}
+	}
+	for _, hook := range append(m.Hooks.Pre, m.Hooks.Post...) {
+		if err := hook.validate(); err != nil {
+			return err
+		}
+	}
+	for _, check := range m.Verify {
+		if err := check.validate(); err != nil {
+			return err
+		}
+	}
+
+	seen := make(map[string]bool)
+	names := make([]string, 0, len(m.Variables))
+	for i := range m.Variables {
+		v := &m.Variables[i]
+		if !validVarName.MatchString(v.Name) {
+			return fmt.Errorf("variable name %q is invalid: must be a template identifier", v.Name)
+		}
+		if isBuiltinVar(v.Name) {
+			return fmt.Errorf("variable %q shadows a built-in variable", v.Name)
+		}
+		if seen[v.Name] {
+			return fmt.Errorf("variable %q declared more than once", v.Name)
+		}
+		seen[v.Name] = true
+		names = append(names, v.Name)
+
+		switch v.Type {
+		case "":
+			v.Type = TypeString
+		case TypeString, TypeBool, TypeInt, TypeList:
+		default:
+			return fmt.Errorf("variable %q has unknown type %q", v.Name, v.Type)
+		}
+		if v.Pattern != "" {
+			if _, err := regexp.Compile(v.Pattern); err != nil {
+				return fmt.Errorf("variable %q has invalid pattern: %w", v.Name, err)
+			}
+		}
+		if v.Default != nil {
+			def, err := v.check(v.Default)
+			if err != nil {
+				return fmt.Errorf("default for %w", err)
+			}
+			v.Default = def
+		}
+	}
+	return checkHookEnvNames(names)
+}
+
+// Resolve merges supplied values with the declared defaults and returns the
+// custom variables for rendering. Supplied values for undeclared names are
+// passed through unchanged, except that a RawValue gets the type
+// inferValue guesses.
+func (m *Manifest) Resolve(supplied map[string]any) (map[string]any, error) {
+	values := make(map[string]any, len(m.Variables)+len(supplied))
+	for name, value := range supplied {
+		if raw, ok := value.(RawValue); ok {
+			value = inferValue(string(raw))
+		}
+		values[name] = value
+	}
+
+	for _, v := range m.Variables {
+		value, ok := supplied[v.Name]
+		if !ok {
+			if v.Required() {
+				return nil, &MissingVarError{Name: v.Name, Description: v.Description}
+			}
+			value = v.Default
+		}
+		checked, err := v.check(value)
+		if err != nil {
+			return nil, err
+		}
+		values[v.Name] = checked
+	}
+	return values, nil
+}
+
+// check converts value to the declared type and applies the pattern.
+func (v Variable) check(value any) (any, error) {
+	converted, err := convertValue(value, v.Type)
+	if err != nil {
+		return nil, fmt.Errorf("variable %q: %w", v.Name, err)
+	}
+	if v.Pattern != "" {
+		re := regexp.MustCompile(v.Pattern)
+		if s := fmt.Sprint(converted); !re.MatchString(s) {
+			return nil, fmt.Errorf("variable %q: value %q does not match pattern %s", v.Name, s, v.Pattern)
+		}
+	}
+	return converted, nil
+}
+
+// convertValue normalizes a value to the Go type used for typ. Strings,
+// and RawValues given with --set, are parsed into the declared type.
+func convertValue(value any, typ string) (any, error) {
+	if raw, ok := value.(RawValue); ok {
+		value = string(raw)
+	}
+	if s, ok := value.(string); ok && typ != TypeString && typ != "" {
+		return parseTyped(s, typ)
+	}
+
+	switch typ {
+	case TypeBool:
+		if b, ok := value.(bool); ok {
+			return b, nil
+		}
+	case TypeInt:
+		switch n := value.(type) {
+		case int:
+			return n, nil
+		case int64:
+			return int(n), nil
+		case float64:
+			if n == float64(int(n)) {
+				return int(n), nil
+			}
+		}
+	case TypeList:
+		if list, ok := value.([]any); ok {
+			return list, nil
+		}
+	default:
+		if s, ok := value.(string); ok {
+			return s, nil
+		}
+		switch value.(type) {
+		case bool, int, int64, float64:
+			return fmt.Sprint(value), nil
+		}
+	}
+	return nil, fmt.Errorf("value %v is not a valid %s", value, typ)
+}
+
+// parseTyped parses s as a value of the given non-string type.
+func parseTyped(s, typ string) (any, error) {
+	switch typ {
+	case TypeBool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, fmt.Errorf("value %q is not a valid bool", s)
+		}
+		return b, nil
+	case TypeInt:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, fmt.Errorf("value %q is not a valid int", s)
+		}
+		return n, nil
+	case TypeList:
+		return parseList(s), nil
+	}
+	return nil, fmt.Errorf("unknown type %q", typ)
+}
+
+// parseList splits "a,b,c" or "[a, b, c]" into a list of trimmed strings.
+func parseList(s string) []any {
+	s = strings.TrimSpace(s)
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	if strings.TrimSpace(s) == "" {
+		return []any{}
+	}
+	parts := strings.Split(s, ",")
+	list := make([]any, len(parts))
+	for i, part := range parts {
+		list[i] = strings.TrimSpace(part)
+	}
+	return list
+}
+
+// MissingVarError reports a required template variable with no value.
+type MissingVarError struct {
+	Name        string
+	Description string
+}
+
+func (e *MissingVarError) Error() string {
+	msg := fmt.Sprintf("missing required variable %q", e.Name)
+	if e.Description != "" {
+		msg += " (" + e.Description + ")"
+	}
+	return msg + fmt.Sprintf("; supply it with --set %s=<value>", e.Name)
+}
@@ -0,0 +1,153 @@
+package scaffold
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParseManifest(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{"empty", "", false},
+		{"description only", "description: A Go service", false},
+		{
+			"all variable types",
+			`variables:
+  - name: license
+    default: MIT
+  - name: docker
+    type: bool
+    default: false
+  - name: port
+    type: int
+    default: 8080
+  - name: tags
+    type: list
+    default: [a, b]`,
+			false,
+		},
+		{"invalid yaml", "variables: [", true},
+		{"invalid name", "variables:\n  - name: my-var\n    default: x", true},
+		{"shadows builtin", "variables:\n  - name: ProjectName\n    default: x", true},
+		{"hook env shadows builtin", "variables:\n  - name: name\n    default: x", true},
+		{"hook env collision", "variables:\n  - name: appPort\n    default: x\n  - name: app_port\n    default: y", true},
+		{"duplicate", "variables:\n  - name: a\n    default: x\n  - name: a\n    default: y", true},
+		{"unknown type", "variables:\n  - name: a\n    type: float", true},
+		{"bad pattern", "variables:\n  - name: a\n    pattern: '('", true},
+		{"default wrong type", "variables:\n  - name: a\n    type: int\n    default: abc", true},
+		{"default fails pattern", "variables:\n  - name: a\n    default: abc\n    pattern: '^[0-9]+$'", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseManifest([]byte(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseManifest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestManifestResolve(t *testing.T) {
+	m, err := ParseManifest([]byte(`variables:
+  - name: license
+    description: SPDX license identifier
+    pattern: '^[A-Za-z0-9.-]+$'
+  - name: docker
+    type: bool
+    default: false
+`))
+	if err != nil {
+		t.Fatalf("ParseManifest() error = %v", err)
+	}
+
+	t.Run("missing required variable", func(t *testing.T) {
+		_, err := m.Resolve(nil)
+		var missing *MissingVarError
+		if !errors.As(err, &missing) {
+			t.Fatalf("Resolve() error = %v, want MissingVarError", err)
+		}
+		if missing.Name != "license" {
+			t.Errorf("MissingVarError.Name = %q, want %q", missing.Name, "license")
+		}
+	})
+
+	t.Run("defaults and supplied values", func(t *testing.T) {
+		got, err := m.Resolve(map[string]any{"license": "MIT", "extra": "kept"})
+		if err != nil {
+			t.Fatalf("Resolve() error = %v", err)
+		}
+		if got["license"] != "MIT" || got["docker"] != false || got["extra"] != "kept" {
+			t.Errorf("Resolve() = %v", got)
+		}
+	})
+
+	t.Run("string values are coerced to declared type", func(t *testing.T) {
+		got, err := m.Resolve(map[string]any{"license": "MIT", "docker": "true"})
+		if err != nil {
+			t.Fatalf("Resolve() error = %v", err)
+		}
+		if got["docker"] != true {
+			t.Errorf("docker = %#v, want true", got["docker"])
+		}
+		if _, err := m.Resolve(map[string]any{"license": "MIT", "docker": "maybe"}); err == nil {
+			t.Error("Resolve() expected bool coercion error, got nil")
+		}
+	})
+
+	t.Run("raw values", func(t *testing.T) {
+		m, err := ParseManifest([]byte("variables:\n  - name: code\n  - name: port\n    type: int\n    default: 80\n"))
+		if err != nil {
+			t.Fatalf("ParseManifest() error = %v", err)
+		}
+		got, err := m.Resolve(map[string]any{
+			"code":   RawValue("007"),
+			"port":   RawValue("8080"),
+			"docker": RawValue("true"),
+			"count":  RawValue("3"),
+			"tags":   RawValue("[a, b]"),
+			"goVer":  RawValue("1.22"),
+		})
+		if err != nil {
+			t.Fatalf("Resolve() error = %v", err)
+		}
+		want := map[string]any{"code": "007", "port": 8080, "docker": true, "count": 3, "tags": []any{"a", "b"}, "goVer": "1.22"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Resolve() = %#v, want %#v", got, want)
+		}
+	})
+
+	t.Run("supplied value must match pattern", func(t *testing.T) {
+		if _, err := m.Resolve(map[string]any{"license": "not valid!"}); err == nil {
+			t.Fatal("Resolve() expected pattern error, got nil")
+		}
+	})
+}
+
+func TestLoadManifest(t *testing.T) {
+	fsys := fstest.MapFS{
+		"go/template.yaml": {Data: []byte("description: Go template")},
+		"cpp/main.cc":      {Data: []byte("int main() {}")},
+	}
+
+	m, err := LoadManifest(fsys, "go")
+	if err != nil {
+		t.Fatalf("LoadManifest(go) error = %v", err)
+	}
+	if m.Description != "Go template" {
+		t.Errorf("Description = %q, want %q", m.Description, "Go template")
+	}
+
+	m, err = LoadManifest(fsys, "cpp")
+	if err != nil {
+		t.Fatalf("LoadManifest(cpp) error = %v", err)
+	}
+	if m.Description != "" || len(m.Variables) != 0 {
+		t.Errorf("LoadManifest(cpp) = %+v, want empty manifest", m)
+	}
+}
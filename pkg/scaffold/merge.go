@@ -0,0 +1,245 @@
+package scaffold
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ConflictPolicy decides what happens when merging a template into an
+// existing directory and a generated file already exists with different
+// content.
+type ConflictPolicy string
+
+const (
+	// ConflictSkip keeps the existing file.
+	ConflictSkip ConflictPolicy = "skip"
+	// ConflictOverwrite replaces the existing file with the template's.
+	ConflictOverwrite ConflictPolicy = "overwrite"
+	// ConflictKeepBoth keeps the existing file and writes both versions
+	// next to it: the existing one with an ".orig" suffix and the
+	// template's with a ".new" suffix.
+	ConflictKeepBoth ConflictPolicy = "keep-both"
+	// ConflictPrompt asks Options.Resolve for each conflicting file.
+	ConflictPrompt ConflictPolicy = "prompt"
+)
+
+// origSuffix and newSuffix are appended to the existing and the template's
+// version of a file under ConflictKeepBoth.
+const (
+	origSuffix = ".orig"
+	newSuffix  = ".new"
+)
+
+// ParseConflictPolicy parses a --conflict flag value.
+func ParseConflictPolicy(s string) (ConflictPolicy, error) {
+	switch p := ConflictPolicy(s); p {
+	case ConflictSkip, ConflictOverwrite, ConflictKeepBoth, ConflictPrompt:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown conflict policy %q: must be skip, overwrite, keep-both, or prompt", s)
+}
+
+// ConflictResolver chooses a policy for one conflicting file. It must not
+// return ConflictPrompt. path is relative to the project directory.
+type ConflictResolver func(path string, existing, incoming []byte) (ConflictPolicy, error)
+
+// MergeSummary lists the files a merge touched, by outcome. Paths are
+// relative to the project directory.
+type MergeSummary struct {
+	Created     []string
+	Identical   []string
+	Skipped     []string
+	Overwritten []string
+	KeptBoth    []string
+}
+
+// String returns a one-line count of each outcome.
+func (s MergeSummary) String() string {
+	return fmt.Sprintf("%d created, %d skipped, %d overwritten, %d kept both, %d identical",
+		len(s.Created), len(s.Skipped), len(s.Overwritten), len(s.KeptBoth), len(s.Identical))
+}
+
+// mergeDir copies every file under srcDir into destDir, resolving files
+// that already exist with different content according to policy. Progress
+// is reported under displayDir. Every change to destDir is recorded in undo
+// so the caller can revert the merge if it or a later step fails.
+func mergeDir(ctx context.Context, w io.Writer, srcDir, destDir, displayDir string, policy ConflictPolicy, resolve ConflictResolver, undo *undoLog) (MergeSummary, error) {
+	var summary MergeSummary
+	if policy == ConflictPrompt && resolve == nil {
+		return summary, errors.New("conflict policy prompt requires a resolver")
+	}
+
+	err := filepath.WalkDir(srcDir, func(src string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(srcDir, src)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return undo.mkdirAll(destDir)
+		}
+		dest := filepath.Join(destDir, rel)
+		display := filepath.Join(displayDir, rel)
+
+		existing, statErr := os.Lstat(dest)
+		exists := statErr == nil
+		if statErr != nil && !errors.Is(statErr, os.ErrNotExist) {
+			return statErr
+		}
+
+		if d.IsDir() {
+			if exists && !existing.IsDir() {
+				return fmt.Errorf("cannot merge directory %s: a file with that name exists", display)
+			}
+			return undo.mkdirAll(dest)
+		}
+		if exists && existing.IsDir() {
+			return fmt.Errorf("cannot merge file %s: a directory with that name exists", display)
+		}
+		// Writing through a symlink could change a file outside the project.
+		if exists && existing.Mode()&fs.ModeSymlink != 0 {
+			return fmt.Errorf("cannot merge file %s: a symlink with that name exists", display)
+		}
+
+		incoming, err := os.ReadFile(src)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		mode := info.Mode().Perm()
+
+		if !exists {
+			_, _ = fmt.Fprintf(w, "  create %s\n", display)
+			summary.Created = append(summary.Created, rel)
+			return undo.createFile(dest, incoming, mode)
+		}
+
+		current, err := os.ReadFile(dest)
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(current, incoming) {
+			_, _ = fmt.Fprintf(w, "  identical %s\n", display)
+			summary.Identical = append(summary.Identical, rel)
+			return nil
+		}
+
+		choice := policy
+		if choice == ConflictPrompt {
+			if choice, err = resolve(rel, current, incoming); err != nil {
+				return err
+			}
+		}
+
+		switch choice {
+		case ConflictSkip:
+			_, _ = fmt.Fprintf(w, "  skip %s\n", display)
+			summary.Skipped = append(summary.Skipped, rel)
+			return nil
+		case ConflictOverwrite:
+			_, _ = fmt.Fprintf(w, "  overwrite %s\n", display)
+			summary.Overwritten = append(summary.Overwritten, rel)
+			return undo.overwriteFile(dest, current, existing.Mode().Perm(), incoming, mode)
+		case ConflictKeepBoth:
+			_, _ = fmt.Fprintf(w, "  keep both %s (versions in %s%s and %s%s)\n", display, display, origSuffix, display, newSuffix)
+			summary.KeptBoth = append(summary.KeptBoth, rel)
+			if err := undo.keepBoth(dest+origSuffix, current, existing.Mode().Perm()); err != nil {
+				return err
+			}
+			return undo.keepBoth(dest+newSuffix, incoming, mode)
+		}
+		return fmt.Errorf("invalid conflict resolution %q for %s", choice, display)
+	})
+	return summary, err
+}
+
+// undoLog records the changes a merge makes so they can be reverted.
+type undoLog struct {
+	steps []func()
+}
+
+// mkdirAll creates dir and any missing parents, recording the ones it made.
+func (u *undoLog) mkdirAll(dir string) error {
+	var missing []string
+	for d := filepath.Clean(dir); ; d = filepath.Dir(d) {
+		if _, err := os.Lstat(d); err == nil {
+			break
+		}
+		missing = append(missing, d)
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	// Parents were appended last; record them first so they are removed last.
+	for i := len(missing) - 1; i >= 0; i-- {
+		d := missing[i]
+		u.steps = append(u.steps, func() { _ = os.Remove(d) })
+	}
+	return nil
+}
+
+func (u *undoLog) createFile(name string, data []byte, mode fs.FileMode) error {
+	u.steps = append(u.steps, func() { _ = os.Remove(name) })
+	return os.WriteFile(name, data, mode)
+}
+
+func (u *undoLog) overwriteFile(name string, old []byte, oldMode fs.FileMode, data []byte, mode fs.FileMode) error {
+	u.steps = append(u.steps, func() { _ = writeFileMode(name, old, oldMode) })
+	return writeFileMode(name, data, mode)
+}
+
+// keepBoth writes one version of a conflicting file beside it, replacing
+// an earlier .orig or .new file if there is one, but not a symlink.
+func (u *undoLog) keepBoth(name string, data []byte, mode fs.FileMode) error {
+	if info, err := os.Lstat(name); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+		return fmt.Errorf("cannot write %s: a symlink with that name exists", name)
+	}
+	if old, err := os.ReadFile(name); err == nil {
+		info, err := os.Stat(name)
+		if err != nil {
+			return err
+		}
+		return u.overwriteFile(name, old, info.Mode().Perm(), data, mode)
+	}
+	return u.createFile(name, data, mode)
+}
+
+// rollback reverts the recorded changes, most recent first.
+func (u *undoLog) rollback() {
+	for i := len(u.steps) - 1; i >= 0; i-- {
+		u.steps[i]()
+	}
+	u.steps = nil
+}
+
+// writeFileMode writes data to name and sets its permissions, which
+// os.WriteFile leaves unchanged for an existing file.
+func writeFileMode(name string, data []byte, mode fs.FileMode) error {
+	if err := os.WriteFile(name, data, mode); err != nil {
+		return err
+	}
+	return os.Chmod(name, mode)
+}
+
+// hasGitDir reports whether dir is the root of a git work tree.
+func hasGitDir(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, ".git"))
+	return err == nil
+}
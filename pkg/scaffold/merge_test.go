@@ -0,0 +1,320 @@
+package scaffold
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+// setupMerge writes a staged project and an existing destination that
+// share same.txt, differ on conflict.txt, and each have a file of their own.
+func setupMerge(t *testing.T) (src, dest string) {
+	t.Helper()
+	tmp := t.TempDir()
+	src = filepath.Join(tmp, "src")
+	dest = filepath.Join(tmp, "dest")
+
+	files := map[string]string{
+		filepath.Join(src, "same.txt"):       "same\n",
+		filepath.Join(src, "conflict.txt"):   "template\n",
+		filepath.Join(src, "sub", "new.txt"): "new\n",
+		filepath.Join(dest, "same.txt"):      "same\n",
+		filepath.Join(dest, "conflict.txt"):  "mine\n",
+		filepath.Join(dest, "unrelated.txt"): "untouched\n",
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return src, dest
+}
+
+func readString(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatalf("read %s: %v", name, err)
+	}
+	return string(data)
+}
+
+func TestMergeDir(t *testing.T) {
+	tests := []struct {
+		policy       ConflictPolicy
+		wantConflict string
+		wantNew      bool
+		wantSummary  MergeSummary
+	}{
+		{
+			ConflictSkip, "mine\n", false,
+			MergeSummary{Created: []string{filepath.Join("sub", "new.txt")}, Identical: []string{"same.txt"}, Skipped: []string{"conflict.txt"}},
+		},
+		{
+			ConflictOverwrite, "template\n", false,
+			MergeSummary{Created: []string{filepath.Join("sub", "new.txt")}, Identical: []string{"same.txt"}, Overwritten: []string{"conflict.txt"}},
+		},
+		{
+			ConflictKeepBoth, "mine\n", true,
+			MergeSummary{Created: []string{filepath.Join("sub", "new.txt")}, Identical: []string{"same.txt"}, KeptBoth: []string{"conflict.txt"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			src, dest := setupMerge(t)
+
+			summary, err := mergeDir(context.Background(), &bytes.Buffer{}, src, dest, "dest", tt.policy, nil, &undoLog{})
+			if err != nil {
+				t.Fatalf("mergeDir() error = %v", err)
+			}
+			if !reflect.DeepEqual(summary, tt.wantSummary) {
+				t.Errorf("summary = %+v, want %+v", summary, tt.wantSummary)
+			}
+			if got := readString(t, filepath.Join(dest, "conflict.txt")); got != tt.wantConflict {
+				t.Errorf("conflict.txt = %q, want %q", got, tt.wantConflict)
+			}
+			_, err = os.Stat(filepath.Join(dest, "conflict.txt.new"))
+			if gotNew := err == nil; gotNew != tt.wantNew {
+				t.Errorf("conflict.txt.new exists = %v, want %v", gotNew, tt.wantNew)
+			}
+			if tt.wantNew {
+				if got := readString(t, filepath.Join(dest, "conflict.txt.new")); got != "template\n" {
+					t.Errorf("conflict.txt.new = %q, want %q", got, "template\n")
+				}
+				if got := readString(t, filepath.Join(dest, "conflict.txt.orig")); got != "mine\n" {
+					t.Errorf("conflict.txt.orig = %q, want %q", got, "mine\n")
+				}
+			} else if _, err := os.Stat(filepath.Join(dest, "conflict.txt.orig")); err == nil {
+				t.Error("conflict.txt.orig should only be written with keep-both")
+			}
+			if got := readString(t, filepath.Join(dest, "unrelated.txt")); got != "untouched\n" {
+				t.Errorf("unrelated.txt = %q, want untouched", got)
+			}
+			if got := readString(t, filepath.Join(dest, "sub", "new.txt")); got != "new\n" {
+				t.Errorf("sub/new.txt = %q, want %q", got, "new\n")
+			}
+		})
+	}
+}
+
+func TestMergeDir_Prompt(t *testing.T) {
+	src, dest := setupMerge(t)
+
+	var asked []string
+	resolve := func(path string, existing, incoming []byte) (ConflictPolicy, error) {
+		asked = append(asked, path)
+		if string(existing) != "mine\n" || string(incoming) != "template\n" {
+			t.Errorf("resolver got existing=%q incoming=%q", existing, incoming)
+		}
+		return ConflictOverwrite, nil
+	}
+
+	summary, err := mergeDir(context.Background(), &bytes.Buffer{}, src, dest, "dest", ConflictPrompt, resolve, &undoLog{})
+	if err != nil {
+		t.Fatalf("mergeDir() error = %v", err)
+	}
+	if !reflect.DeepEqual(asked, []string{"conflict.txt"}) {
+		t.Errorf("resolver asked about %v, want only conflict.txt", asked)
+	}
+	if len(summary.Overwritten) != 1 {
+		t.Errorf("summary = %+v, want one overwritten file", summary)
+	}
+
+	t.Run("resolver error aborts", func(t *testing.T) {
+		src, dest := setupMerge(t)
+		stop := errors.New("stop")
+		_, err := mergeDir(context.Background(), &bytes.Buffer{}, src, dest, "dest", ConflictPrompt,
+			func(string, []byte, []byte) (ConflictPolicy, error) { return "", stop }, &undoLog{})
+		if !errors.Is(err, stop) {
+			t.Errorf("mergeDir() error = %v, want %v", err, stop)
+		}
+	})
+
+	t.Run("missing resolver", func(t *testing.T) {
+		src, dest := setupMerge(t)
+		if _, err := mergeDir(context.Background(), &bytes.Buffer{}, src, dest, "dest", ConflictPrompt, nil, &undoLog{}); err == nil {
+			t.Error("mergeDir() expected error, got nil")
+		}
+	})
+}
+
+func TestMergeDir_TypeMismatch(t *testing.T) {
+	src, dest := setupMerge(t)
+	if err := os.WriteFile(filepath.Join(dest, "sub"), []byte("file where a dir should be"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := mergeDir(context.Background(), &bytes.Buffer{}, src, dest, "dest", ConflictOverwrite, nil, &undoLog{}); err == nil {
+		t.Fatal("mergeDir() expected error, got nil")
+	}
+}
+
+func TestMergeDir_Symlink(t *testing.T) {
+	for _, name := range []string{"conflict.txt", "conflict.txt" + newSuffix} {
+		t.Run(name, func(t *testing.T) {
+			src, dest := setupMerge(t)
+			outside := filepath.Join(filepath.Dir(dest), "outside.txt")
+			if err := os.WriteFile(outside, []byte("outside\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Remove(filepath.Join(dest, name)); err != nil && !os.IsNotExist(err) {
+				t.Fatal(err)
+			}
+			if err := os.Symlink(outside, filepath.Join(dest, name)); err != nil {
+				t.Skipf("symlinks not supported: %v", err)
+			}
+
+			undo := &undoLog{}
+			if _, err := mergeDir(context.Background(), &bytes.Buffer{}, src, dest, "dest", ConflictKeepBoth, nil, undo); err == nil {
+				t.Fatal("mergeDir() should refuse to write through a symlink")
+			}
+			undo.rollback()
+			if got := readString(t, outside); got != "outside\n" {
+				t.Errorf("file outside the project = %q, want it untouched", got)
+			}
+		})
+	}
+}
+
+func TestParseConflictPolicy(t *testing.T) {
+	for _, s := range []string{"skip", "overwrite", "keep-both", "prompt"} {
+		if _, err := ParseConflictPolicy(s); err != nil {
+			t.Errorf("ParseConflictPolicy(%q) error = %v", s, err)
+		}
+	}
+	if _, err := ParseConflictPolicy("merge"); err == nil {
+		t.Error("ParseConflictPolicy(merge) expected error, got nil")
+	}
+}
+
+func TestCreate_Merge(t *testing.T) {
+	requireGit(t)
+	chdirTemp(t)
+
+	fsys := fstest.MapFS{
+		"cpp/justfile.tmpl":     {Data: []byte("build: # {{.ProjectName}}\n")},
+		"cpp/dev-tools/cpplint": {Data: []byte("lint\n"), Mode: 0755},
+		"cpp/src/main.cc":       {Data: []byte("int main() {}\n")},
+	}
+	if err := os.MkdirAll(filepath.Join("demo", "src"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join("demo", "src", "main.cc"), []byte("// existing\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join("demo", ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	err := NewCreator(fsys, &out).Create(context.Background(), Options{Lang: "cpp", ProjectName: "demo", Merge: true})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	if got := readString(t, filepath.Join("demo", "src", "main.cc")); got != "// existing\n" {
+		t.Errorf("src/main.cc = %q, want existing content kept", got)
+	}
+	if got := readString(t, filepath.Join("demo", "justfile")); got != "build: # demo\n" {
+		t.Errorf("justfile = %q", got)
+	}
+	info, err := os.Stat(filepath.Join("demo", "dev-tools", "cpplint"))
+	if err != nil || info.Mode().Perm() != 0755 {
+		t.Errorf("dev-tools/cpplint should be created executable, info = %v, err = %v", info, err)
+	}
+	if !bytes.Contains(out.Bytes(), []byte("Summary: 2 created, 1 skipped, 0 overwritten, 0 kept both, 0 identical")) {
+		t.Errorf("output missing summary:\n%s", out.String())
+	}
+	assertNoStaging(t, ".")
+}
+
+func TestMergeDir_Rollback(t *testing.T) {
+	for _, policy := range []ConflictPolicy{ConflictOverwrite, ConflictKeepBoth} {
+		t.Run(string(policy), func(t *testing.T) { testMergeRollback(t, policy) })
+	}
+}
+
+func testMergeRollback(t *testing.T, policy ConflictPolicy) {
+	src, dest := setupMerge(t)
+	if err := os.WriteFile(filepath.Join(src, "unrelated.txt.new"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var undo undoLog
+	if _, err := mergeDir(context.Background(), &bytes.Buffer{}, src, dest, "dest", policy, nil, &undo); err != nil {
+		t.Fatalf("mergeDir() error = %v", err)
+	}
+	undo.rollback()
+
+	want := map[string]string{
+		"same.txt":      "same\n",
+		"conflict.txt":  "mine\n",
+		"unrelated.txt": "untouched\n",
+	}
+	var got []string
+	err := filepath.WalkDir(dest, func(name string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(dest, name)
+		got = append(got, rel)
+		if content := readString(t, name); content != want[rel] {
+			t.Errorf("%s = %q after rollback, want %q", rel, content, want[rel])
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Errorf("files after rollback = %v, want only %v", got, want)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "sub")); !os.IsNotExist(err) {
+		t.Errorf("created directory sub should be removed, stat err = %v", err)
+	}
+}
+
+func TestCreate_MergeCanceledRollsBack(t *testing.T) {
+	chdirTemp(t)
+	fsys := fstest.MapFS{
+		"cpp/a.txt":         {Data: []byte("new\n")},
+		"cpp/conflict.txt":  {Data: []byte("template\n")},
+		"cpp/sub/later.txt": {Data: []byte("later\n")},
+	}
+	if err := os.MkdirAll("demo", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join("demo", "conflict.txt"), []byte("mine\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The user interrupts while being asked about the conflict.
+	ctx, cancel := context.WithCancel(context.Background())
+	resolve := func(string, []byte, []byte) (ConflictPolicy, error) {
+		cancel()
+		return ConflictOverwrite, nil
+	}
+	opts := Options{Lang: "cpp", ProjectName: "demo", Merge: true, Conflict: ConflictPrompt, Resolve: resolve}
+	if err := NewCreator(fsys, &bytes.Buffer{}).Create(ctx, opts); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Create() error = %v, want %v", err, context.Canceled)
+	}
+
+	if got := readString(t, filepath.Join("demo", "conflict.txt")); got != "mine\n" {
+		t.Errorf("conflict.txt = %q, want the original restored", got)
+	}
+	for _, name := range []string{"a.txt", "sub", ".git"} {
+		if _, err := os.Stat(filepath.Join("demo", name)); !os.IsNotExist(err) {
+			t.Errorf("%s should be removed, stat err = %v", name, err)
+		}
+	}
+	assertNoStaging(t, ".")
+}
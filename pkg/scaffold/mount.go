@@ -0,0 +1,98 @@
+package scaffold
+
+import (
+	"io/fs"
+	"strings"
+)
+
+// mountFS serves a single template tree as the only language directory of
+// a template filesystem.
+type mountFS struct {
+	name string
+	fsys fs.FS
+}
+
+// MountFS returns a template filesystem whose only language directory,
+// name, holds the contents of fsys. It turns a single template, such as one
+// fetched from git, into a layer for Creator.Overlay.
+func MountFS(name string, fsys fs.FS) fs.FS {
+	return &mountFS{name: name, fsys: fsys}
+}
+
+// Open implements fs.FS.
+func (m *mountFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return &overlayRoot{entries: []fs.DirEntry{mountEntry{m}}}, nil
+	}
+
+	rel, ok := m.rel(name)
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	f, err := m.fsys.Open(rel)
+	if err != nil {
+		return nil, err
+	}
+	if rel == "." {
+		return &mountRoot{File: f, name: m.name}, nil
+	}
+	return f, nil
+}
+
+// rel maps a path under the mount point to its path in fsys.
+func (m *mountFS) rel(name string) (string, bool) {
+	if name == m.name {
+		return ".", true
+	}
+	rest, ok := strings.CutPrefix(name, m.name+"/")
+	return rest, ok
+}
+
+// mountRoot is fsys's root directory, renamed to the mount point.
+type mountRoot struct {
+	fs.File
+	name string
+}
+
+func (r *mountRoot) Stat() (fs.FileInfo, error) {
+	info, err := r.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return renamedInfo{FileInfo: info, name: r.name}, nil
+}
+
+func (r *mountRoot) ReadDir(n int) ([]fs.DirEntry, error) {
+	dir, ok := r.File.(fs.ReadDirFile)
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: r.name, Err: fs.ErrInvalid}
+	}
+	return dir.ReadDir(n)
+}
+
+// mountEntry is the mount point as listed in the root directory.
+type mountEntry struct {
+	m *mountFS
+}
+
+func (e mountEntry) Name() string      { return e.m.name }
+func (e mountEntry) IsDir() bool       { return true }
+func (e mountEntry) Type() fs.FileMode { return fs.ModeDir }
+
+func (e mountEntry) Info() (fs.FileInfo, error) {
+	info, err := fs.Stat(e.m.fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	return renamedInfo{FileInfo: info, name: e.m.name}, nil
+}
+
+type renamedInfo struct {
+	fs.FileInfo
+	name string
+}
+
+func (i renamedInfo) Name() string { return i.name }
@@ -0,0 +1,51 @@
+package scaffold
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestMountFS(t *testing.T) {
+	fetched := fstest.MapFS{
+		"template.yaml":   {Data: []byte("description: fetched\n")},
+		"main.go.tmpl":    {Data: []byte("package main // {{.ProjectName}}")},
+		"internal/x.go":   {Data: []byte("package internal")},
+		"scripts/run.sh":  {Data: []byte("#!/bin/sh\n"), Mode: 0755},
+		"docs/.gitkeep":   {},
+		"internal/y.tmpl": {Data: []byte("y")},
+	}
+	fsys := MountFS("svc", fetched)
+
+	if err := fstest.TestFS(fsys, "svc/main.go.tmpl", "svc/internal/x.go", "svc/scripts/run.sh"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Stat(fsys, "main.go.tmpl"); err == nil {
+		t.Error("files should only be reachable under the mount point")
+	}
+
+	t.Run("layers over built-in templates", func(t *testing.T) {
+		builtin := fstest.MapFS{"go/main.go": {Data: []byte("package main")}}
+		c := NewCreator(builtin, &bytes.Buffer{})
+		c.Overlay(fsys)
+
+		infos, err := c.Templates()
+		if err != nil {
+			t.Fatalf("Templates() error = %v", err)
+		}
+		if len(infos) != 2 || infos[1].Name != "svc" || infos[1].Description != "fetched" {
+			t.Errorf("Templates() = %+v, want go and svc", infos)
+		}
+
+		out, err := c.Render(context.Background(), Options{Lang: "svc", ProjectName: "demo"})
+		if err != nil {
+			t.Fatalf("Render() error = %v", err)
+		}
+		data, _ := fs.ReadFile(out, "main.go")
+		if string(data) != "package main // demo" {
+			t.Errorf("main.go = %q", data)
+		}
+	})
+}
@@ -0,0 +1,341 @@
+package scaffold
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/module"
+)
+
+// NameError is a project name or module path that breaks the naming rules
+// of the language being generated. It matches ErrInvalidName.
+type NameError struct {
+	Kind       string // "project name", "module path", or "module path prefix"
+	Value      string
+	Naming     string // the rule set, such as "go" or "cpp"
+	Reason     string
+	Suggestion string // a corrected value, or how to set one, when one can be derived
+}
+
+func (e *NameError) Error() string {
+	msg := fmt.Sprintf("%s %q is invalid for %s: %s", e.Kind, e.Value, e.Naming, e.Reason)
+	if e.Suggestion != "" {
+		msg += fmt.Sprintf("; try %q", e.Suggestion)
+	}
+	return msg
+}
+
+func (e *NameError) Is(target error) bool { return target == ErrInvalidName }
+
+// namingRule checks names against one ecosystem's conventions, on top of
+// ValidateProjectName. Each check returns why a value is invalid, or "" if
+// it is fine. module is nil for languages without module paths.
+type namingRule struct {
+	project func(name string) string
+	module  func(path string) string
+	// suggest and suggestModule derive a valid value from an invalid one.
+	suggest       func(name string) string
+	suggestModule func(path string) string
+}
+
+// namingRules are the rule sets a manifest can name with "naming". A
+// template without one uses the rules named after it, if any.
+var namingRules = map[string]namingRule{
+	"go":     {module: checkGoModule, suggestModule: suggestGoModule},
+	"cpp":    {project: checkCppName, suggest: suggestCppName},
+	"rust":   {project: checkCrateName, suggest: suggestCrateName},
+	"python": {project: checkPyPIName, suggest: suggestPyPIName},
+}
+
+// namingRuleNames returns the known rule sets, sorted, for error messages.
+func namingRuleNames() string {
+	names := make([]string, 0, len(namingRules))
+	for name := range namingRules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// naming returns the name and rules of the naming rule set lang uses; both
+// are empty when there is none.
+func (c *Creator) naming(lang string) (string, namingRule, error) {
+	manifest, err := LoadManifest(c.fsys, lang)
+	if err != nil {
+		return "", namingRule{}, err
+	}
+	name := manifest.Naming
+	if name == "" {
+		name = lang
+	}
+	rule, ok := namingRules[name]
+	if !ok {
+		return "", namingRule{}, nil
+	}
+	return name, rule, nil
+}
+
+// ValidateName checks a project name with ValidateProjectName and the
+// naming rules of the lang template, such as C++ identifiers for cpp.
+func (c *Creator) ValidateName(lang, name string) error {
+	if err := ValidateProjectName(name); err != nil {
+		return err
+	}
+	naming, rule, err := c.naming(lang)
+	if err != nil || rule.project == nil {
+		return err
+	}
+	reason := rule.project(name)
+	if reason == "" {
+		return nil
+	}
+	e := &NameError{Kind: "project name", Value: name, Naming: naming, Reason: reason}
+	if rule.suggest != nil {
+		if s := rule.suggest(name); s != name && ValidateProjectName(s) == nil && rule.project(s) == "" {
+			e.Suggestion = s
+		}
+	}
+	return e
+}
+
+// ValidateModulePath checks a module path against the naming rules of the
+// lang template. Languages without module paths accept any.
+func (c *Creator) ValidateModulePath(lang, path string) error {
+	naming, rule, err := c.naming(lang)
+	if err != nil || rule.module == nil {
+		return err
+	}
+	reason := rule.module(path)
+	if reason == "" {
+		return nil
+	}
+	e := &NameError{Kind: "module path", Value: path, Naming: naming, Reason: reason}
+	if rule.suggestModule != nil {
+		if s := rule.suggestModule(path); s != path && rule.module(s) == "" {
+			e.Suggestion = s
+		}
+	}
+	return e
+}
+
+// checkNames validates the project name and the module path the project
+// will get, given or derived, before anything is written. The problems of
+// a derived module path are reported against what it was derived from:
+// the module path prefix git provided if that is invalid, else the project
+// name.
+func (c *Creator) checkNames(ctx context.Context, opts Options) error {
+	if err := c.ValidateName(opts.Lang, opts.ProjectName); err != nil {
+		return err
+	}
+	modulePath := c.DefaultVars(ctx, opts).ModulePath
+	err := c.ValidateModulePath(opts.Lang, modulePath)
+	var nerr *NameError
+	if opts.ModulePath != "" || !errors.As(err, &nerr) {
+		return err
+	}
+	rule := namingRules[nerr.Naming]
+	d := c.detect(ctx, filepath.Dir(filepath.Clean(opts.ProjectName)))
+	if reason := rule.module(d.prefix); d.prefix != "" && reason != "" {
+		e := &NameError{
+			Kind:   "module path prefix",
+			Value:  d.prefix,
+			Naming: nerr.Naming,
+			Reason: fmt.Sprintf("%s (from %s)", reason, d.prefixSource()),
+		}
+		if rule.suggestModule == nil {
+			return e
+		}
+		if s := rule.suggestModule(d.prefix); s != d.prefix && rule.module(s) == "" {
+			if d.origin != "" {
+				e.Suggestion = "--module " + path.Join(s, opts.ProjectName)
+			} else {
+				e.Suggestion = "git config " + ModulePrefixKey + " " + s
+			}
+		}
+		return e
+	}
+	e := &NameError{
+		Kind:   "project name",
+		Value:  opts.ProjectName,
+		Naming: nerr.Naming,
+		Reason: fmt.Sprintf("the module path %q derived from it is invalid: %s", modulePath, nerr.Reason),
+	}
+	if rule.suggestModule != nil {
+		s := rule.suggestModule(opts.ProjectName)
+		fixed := opts
+		fixed.ProjectName = s
+		if s != opts.ProjectName && c.ValidateName(opts.Lang, s) == nil &&
+			c.ValidateModulePath(opts.Lang, c.DefaultVars(ctx, fixed).ModulePath) == nil {
+			e.Suggestion = s
+		}
+	}
+	return e
+}
+
+// checkGoModule applies the go command's rules for module paths. A path
+// whose first element has no dot, such as "myapp", is a local module and
+// only needs to be a valid import path.
+func checkGoModule(path string) string {
+	first, _, _ := strings.Cut(path, "/")
+	check := module.CheckImportPath
+	if strings.Contains(first, ".") {
+		check = module.CheckPath
+	}
+	if err := check(path); err != nil {
+		reason := strings.TrimPrefix(err.Error(), fmt.Sprintf("malformed module path %q: ", path))
+		return strings.TrimPrefix(reason, fmt.Sprintf("malformed import path %q: ", path))
+	}
+	return ""
+}
+
+// suggestGoModule cleans up common mistakes in module paths: a URL scheme
+// or .git suffix, an uppercase host, and characters import paths don't
+// allow.
+func suggestGoModule(path string) string {
+	path = strings.TrimSpace(path)
+	if _, rest, ok := strings.Cut(path, "://"); ok {
+		path = rest
+	}
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	var elems []string
+	for i, elem := range strings.Split(path, "/") {
+		elem = strings.Map(func(r rune) rune {
+			if r < 0x80 && (r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-._~", r)) {
+				return r
+			}
+			return '-'
+		}, elem)
+		elem = strings.Trim(elem, ".-")
+		if i == 0 && strings.Contains(elem, ".") {
+			elem = strings.ToLower(elem)
+		}
+		if elem != "" {
+			elems = append(elems, elem)
+		}
+	}
+	return strings.Join(elems, "/")
+}
+
+// underscored returns the identifier templates derive from a project name,
+// with '-' and '.' replaced by '_'.
+func underscored(name string) string {
+	return strings.NewReplacer("-", "_", ".", "_").Replace(name)
+}
+
+// cmakeReservedTargets are target names CMake keeps for itself (CMP0037).
+var cmakeReservedTargets = setOf(
+	"all", "ALL_BUILD", "clean", "depend", "edit_cache", "help", "install", "INSTALL",
+	"list_install_components", "package", "PACKAGE", "package_source", "rebuild_cache",
+	"RUN_TESTS", "test", "ZERO_CHECK",
+)
+
+// checkCppName requires a name that works as a CMake project and target
+// name and, with separators replaced, as a C++ identifier.
+func checkCppName(name string) string {
+	id := underscored(name)
+	switch {
+	case cmakeReservedTargets[name]:
+		return fmt.Sprintf("%q is a target name reserved by CMake", name)
+	case isKeyword("cpp", id):
+		return fmt.Sprintf("%q is a C++ keyword", id)
+	case strings.Contains(id, "__"):
+		return fmt.Sprintf("C++ reserves identifiers containing \"__\", such as %q", id)
+	}
+	return ""
+}
+
+func suggestCppName(name string) string {
+	name = collapse(name, "-_.")
+	if checkCppName(name) != "" {
+		name += "-project"
+	}
+	return name
+}
+
+// cargoReservedNames are package names cargo refuses: built-in crates, its
+// artifact directories, and names Windows reserves.
+var cargoReservedNames = setOf(
+	"alloc", "core", "proc_macro", "proc-macro", "std", "test",
+	"build", "deps", "examples", "incremental",
+	"aux", "com1", "com2", "com3", "com4", "com5", "com6", "com7", "com8", "com9",
+	"con", "lpt1", "lpt2", "lpt3", "lpt4", "lpt5", "lpt6", "lpt7", "lpt8", "lpt9", "nul", "prn",
+)
+
+// maxCrateNameLen is the longest name crates.io accepts.
+const maxCrateNameLen = 64
+
+// checkCrateName applies cargo's rules for package names.
+func checkCrateName(name string) string {
+	switch {
+	case strings.Contains(name, "."):
+		return "crate names may contain only letters, digits, '-', and '_'"
+	case len(name) > maxCrateNameLen:
+		return fmt.Sprintf("crate names must be at most %d characters", maxCrateNameLen)
+	case isKeyword("rust", underscored(name)):
+		return fmt.Sprintf("%q is a Rust keyword", underscored(name))
+	case cargoReservedNames[strings.ToLower(name)]:
+		return fmt.Sprintf("%q is reserved by cargo", name)
+	}
+	return ""
+}
+
+func suggestCrateName(name string) string {
+	name = collapse(strings.ReplaceAll(name, ".", "-"), "-_")
+	if len(name) > maxCrateNameLen {
+		name = strings.TrimRight(name[:maxCrateNameLen], "-_")
+	}
+	if checkCrateName(name) != "" {
+		name += "-rs"
+	}
+	return name
+}
+
+// checkPyPIName applies the packaging rules for distribution names (PEP
+// 508) and requires the normalized import name to be usable.
+func checkPyPIName(name string) string {
+	last := name[len(name)-1]
+	if strings.IndexByte("-_.", last) >= 0 {
+		return "package names must end with a letter or digit"
+	}
+	if pkg := strings.ToLower(collapse(underscored(name), "_")); isKeyword("python", pkg) {
+		return fmt.Sprintf("its import name %q is a Python keyword", pkg)
+	}
+	return ""
+}
+
+func suggestPyPIName(name string) string {
+	name = strings.TrimRight(name, "-_.")
+	if checkPyPIName(name) != "" {
+		name = "py" + name
+	}
+	return name
+}
+
+// collapse replaces each run of the separators seps with its first
+// character, so "my__app" becomes "my_app".
+func collapse(s, seps string) string {
+	var b strings.Builder
+	prevSep := false
+	for _, r := range s {
+		isSep := strings.ContainsRune(seps, r)
+		if isSep && prevSep {
+			continue
+		}
+		prevSep = isSep
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func setOf(words ...string) map[string]bool {
+	m := make(map[string]bool, len(words))
+	for _, w := range words {
+		m[w] = true
+	}
+	return m
+}
@@ -0,0 +1,181 @@
+package scaffold
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/JackDrogon/project/pkg/git"
+)
+
+func TestValidateName(t *testing.T) {
+	fsys := fstest.MapFS{
+		"go/main.go":           {Data: []byte("package main\n")},
+		"cpp/main.cc":          {Data: []byte("int main() {}\n")},
+		"rust/Cargo.toml":      {Data: []byte("")},
+		"python/setup.py":      {Data: []byte("")},
+		"lib/template.yaml":    {Data: []byte("naming: cpp\n")},
+		"other/README.md":      {Data: []byte("")},
+		"broken/template.yaml": {Data: []byte("naming: cobol\n")},
+	}
+	c := NewCreator(fsys, &bytes.Buffer{})
+
+	tests := []struct {
+		lang, name string
+		wantErr    bool
+		suggestion string
+	}{
+		{"go", "class", false, ""},
+		{"cpp", "my-lib", false, ""},
+		{"cpp", "class", true, "class-project"},
+		{"cpp", "test", true, "test-project"},
+		{"cpp", "a__b", true, "a_b"},
+		{"cpp", "a-_b", true, "a-b"},
+		{"lib", "class", true, "class-project"},
+		{"other", "class", false, ""},
+		{"rust", "my-crate", false, ""},
+		{"rust", "my.crate", true, "my-crate"},
+		{"rust", "fn", true, "fn-rs"},
+		{"rust", "std", true, "std-rs"},
+		{"python", "my-pkg", false, ""},
+		{"python", "pkg-", true, "pkg"},
+		{"python", "class", true, "pyclass"},
+		{"cpp", "my app", true, "my-app"},
+	}
+	for _, tt := range tests {
+		err := c.ValidateName(tt.lang, tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateName(%q, %q) error = %v, wantErr %v", tt.lang, tt.name, err, tt.wantErr)
+			continue
+		}
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, ErrInvalidName) {
+			t.Errorf("ValidateName(%q, %q) error = %v, want ErrInvalidName", tt.lang, tt.name, err)
+		}
+		var nerr *NameError
+		got := ""
+		if errors.As(err, &nerr) {
+			got = nerr.Suggestion
+		} else if s := suggestProjectName(tt.name); s != "" {
+			got = s
+		}
+		if got != tt.suggestion {
+			t.Errorf("ValidateName(%q, %q) suggestion = %q, want %q (%v)", tt.lang, tt.name, got, tt.suggestion, err)
+		}
+	}
+
+	if err := c.ValidateName("broken", "demo"); err == nil {
+		t.Error("ValidateName() with an unknown naming should fail")
+	}
+}
+
+func TestValidateModulePath(t *testing.T) {
+	c := NewCreator(fstest.MapFS{"go/main.go": {Data: []byte("package main\n")}}, &bytes.Buffer{})
+
+	tests := []struct {
+		path       string
+		wantErr    bool
+		suggestion string
+	}{
+		{"myapp", false, ""},
+		{"github.com/x/y", false, ""},
+		{"example.com/a/b/v2", false, ""},
+		{"GitHub.com/x/y", true, "github.com/x/y"},
+		{"https://github.com/x/y.git", true, "github.com/x/y"},
+		{"github.com/x/my app", true, "github.com/x/my-app"},
+		{"github.com/x/y/", true, "github.com/x/y"},
+	}
+	for _, tt := range tests {
+		err := c.ValidateModulePath("go", tt.path)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateModulePath(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			continue
+		}
+		if err == nil {
+			continue
+		}
+		var nerr *NameError
+		if !errors.As(err, &nerr) || !errors.Is(err, ErrInvalidName) || nerr.Suggestion != tt.suggestion {
+			t.Errorf("ValidateModulePath(%q) error = %#v, want suggestion %q", tt.path, err, tt.suggestion)
+		} else if strings.Count(err.Error(), tt.path) != 1 {
+			t.Errorf("ValidateModulePath(%q) error = %q, want the path quoted once", tt.path, err)
+		}
+	}
+}
+
+func TestCheckNames_DerivedModulePath(t *testing.T) {
+	c := NewCreator(fstest.MapFS{"go/main.go": {Data: []byte("package main\n")}}, &bytes.Buffer{})
+	c.SetGit(&git.Fake{})
+	ctx := context.Background()
+
+	err := c.checkNames(ctx, Options{Lang: "go", ProjectName: "app."})
+	var nerr *NameError
+	if !errors.As(err, &nerr) || nerr.Kind != "project name" || nerr.Value != "app." || nerr.Suggestion != "app" {
+		t.Errorf("checkNames() error = %#v, want the project name blamed with suggestion %q", err, "app")
+	}
+	err = c.checkNames(ctx, Options{Lang: "go", ProjectName: "demo", ModulePath: "app."})
+	if !errors.As(err, &nerr) || nerr.Kind != "module path" {
+		t.Errorf("checkNames() error = %#v, want the given module path blamed", err)
+	}
+}
+
+func TestCheckNames_BadModulePrefix(t *testing.T) {
+	fsys := fstest.MapFS{"go/main.go": {Data: []byte("package main\n")}}
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		config     map[string]string
+		value      string
+		source     string
+		suggestion string
+	}{
+		{
+			"configured", map[string]string{ModulePrefixKey: "GitHub.com/acme"},
+			"GitHub.com/acme", "git config " + ModulePrefixKey, "git config " + ModulePrefixKey + " github.com/acme",
+		},
+		{
+			"origin remote", map[string]string{"remote.origin.url": "https://github.com/my org/mono.git"},
+			"github.com/my org", "https://github.com/my org/mono.git", "--module github.com/my-org/app",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCreator(fsys, &bytes.Buffer{})
+			c.SetGit(&git.Fake{Config: tt.config})
+			err := c.checkNames(ctx, Options{Lang: "go", ProjectName: "app"})
+			var nerr *NameError
+			if !errors.As(err, &nerr) || nerr.Kind != "module path prefix" || nerr.Value != tt.value || nerr.Suggestion != tt.suggestion {
+				t.Fatalf("checkNames() error = %#v, want the prefix blamed with suggestion %q", err, tt.suggestion)
+			}
+			if !strings.Contains(err.Error(), tt.source) {
+				t.Errorf("checkNames() error = %q, want it to name %s", err, tt.source)
+			}
+		})
+	}
+}
+
+func TestCreate_InvalidName(t *testing.T) {
+	fsys := fstest.MapFS{"cpp/CMakeLists.txt.tmpl": {Data: []byte("project({{.ProjectName}})\n")}}
+	c := NewCreator(fsys, &bytes.Buffer{})
+	c.SetGit(&git.Fake{})
+	ctx := context.Background()
+
+	chdirTemp(t)
+	err := c.Create(ctx, Options{Lang: "cpp", ProjectName: "class", NoGit: true})
+	if !errors.Is(err, ErrInvalidName) {
+		t.Fatalf("Create() error = %v, want ErrInvalidName", err)
+	}
+	if _, err := os.Stat("class"); !os.IsNotExist(err) {
+		t.Errorf("Create() left %q behind: %v", "class", err)
+	}
+	if _, err := c.Render(ctx, Options{Lang: "cpp", ProjectName: "class"}); !errors.Is(err, ErrInvalidName) {
+		t.Errorf("Render() error = %v, want ErrInvalidName", err)
+	}
+}
@@ -0,0 +1,126 @@
+package scaffold
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+	"time"
+)
+
+// overlayFS merges several template trees into one. Each top-level language
+// directory is served entirely by the first layer that contains it, so a
+// local template shadows a built-in one of the same name instead of being
+// mixed with it file by file.
+type overlayFS struct {
+	layers []fs.FS
+}
+
+// NewOverlayFS returns a filesystem that layers the given template trees,
+// highest precedence first.
+func NewOverlayFS(layers ...fs.FS) fs.FS {
+	return &overlayFS{layers: layers}
+}
+
+// Open implements fs.FS.
+func (o *overlayFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		entries, err := o.ReadDir(".")
+		if err != nil {
+			return nil, err
+		}
+		return &overlayRoot{entries: entries}, nil
+	}
+
+	layer := o.layerFor(name)
+	if layer == nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return layer.Open(name)
+}
+
+// ReadDir implements fs.ReadDirFS. The root lists the union of language
+// directories across all layers, sorted by name.
+func (o *overlayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	if name != "." {
+		layer := o.layerFor(name)
+		if layer == nil {
+			return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+		}
+		return fs.ReadDir(layer, name)
+	}
+
+	seen := make(map[string]bool)
+	var merged []fs.DirEntry
+	for _, layer := range o.layers {
+		entries, err := fs.ReadDir(layer, ".")
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() || seen[entry.Name()] {
+				continue
+			}
+			seen[entry.Name()] = true
+			merged = append(merged, entry)
+		}
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Name() < merged[j].Name() })
+	return merged, nil
+}
+
+// layerFor returns the first layer that has the top-level directory of name.
+func (o *overlayFS) layerFor(name string) fs.FS {
+	lang, _, _ := strings.Cut(name, "/")
+	for _, layer := range o.layers {
+		if info, err := fs.Stat(layer, lang); err == nil && info.IsDir() {
+			return layer
+		}
+	}
+	return nil
+}
+
+// overlayRoot is the synthetic directory returned by opening ".".
+type overlayRoot struct {
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *overlayRoot) Stat() (fs.FileInfo, error) { return overlayRootInfo{}, nil }
+func (d *overlayRoot) Close() error               { return nil }
+
+func (d *overlayRoot) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: ".", Err: errors.New("is a directory")}
+}
+
+func (d *overlayRoot) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(rest) {
+		n = len(rest)
+	}
+	d.offset += n
+	return rest[:n], nil
+}
+
+type overlayRootInfo struct{}
+
+func (overlayRootInfo) Name() string       { return "." }
+func (overlayRootInfo) Size() int64        { return 0 }
+func (overlayRootInfo) Mode() fs.FileMode  { return fs.ModeDir | 0555 }
+func (overlayRootInfo) ModTime() time.Time { return time.Time{} }
+func (overlayRootInfo) IsDir() bool        { return true }
+func (overlayRootInfo) Sys() any           { return nil }
@@ -0,0 +1,103 @@
+package scaffold
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestOverlayFS(t *testing.T) {
+	local := fstest.MapFS{
+		"go/main.go.tmpl":   {Data: []byte("local go")},
+		"rust/Cargo.toml":   {Data: []byte("local rust")},
+		"README.md":         {Data: []byte("not a language")},
+		"rust/src/main.rs":  {Data: []byte("fn main() {}")},
+		"go/local-only.txt": {Data: []byte("only in local")},
+	}
+	builtin := fstest.MapFS{
+		"go/main.go.tmpl":      {Data: []byte("builtin go")},
+		"go/builtin.txt":       {Data: []byte("only in builtin")},
+		"cpp/CMakeLists":       {Data: []byte("builtin cpp")},
+		"cpp/src/main.cc":      {Data: []byte("int main() {}")},
+		"cpp/include/.gitkeep": {},
+	}
+	fsys := NewOverlayFS(local, builtin)
+
+	t.Run("root lists union of languages", func(t *testing.T) {
+		c := NewCreator(fsys, &bytes.Buffer{})
+		langs, err := c.ListLangs()
+		if err != nil {
+			t.Fatalf("ListLangs() error = %v", err)
+		}
+		want := []string{"cpp", "go", "rust"}
+		if len(langs) != len(want) {
+			t.Fatalf("ListLangs() = %v, want %v", langs, want)
+		}
+		for i := range want {
+			if langs[i] != want[i] {
+				t.Errorf("ListLangs()[%d] = %q, want %q", i, langs[i], want[i])
+			}
+		}
+	})
+
+	t.Run("higher layer shadows whole language", func(t *testing.T) {
+		got, err := fs.ReadFile(fsys, "go/main.go.tmpl")
+		if err != nil {
+			t.Fatalf("ReadFile() error = %v", err)
+		}
+		if string(got) != "local go" {
+			t.Errorf("go/main.go.tmpl = %q, want %q", got, "local go")
+		}
+		if _, err := fs.Stat(fsys, "go/builtin.txt"); err == nil {
+			t.Error("go/builtin.txt should be shadowed by the local go template")
+		}
+	})
+
+	t.Run("lower layer fills missing languages", func(t *testing.T) {
+		if _, err := fs.ReadFile(fsys, "cpp/src/main.cc"); err != nil {
+			t.Errorf("ReadFile(cpp/src/main.cc) error = %v", err)
+		}
+	})
+
+	t.Run("unknown language does not exist", func(t *testing.T) {
+		if _, err := fsys.Open("java/Main.java"); err == nil {
+			t.Error("Open(java/Main.java) expected error, got nil")
+		}
+	})
+
+	t.Run("passes fstest", func(t *testing.T) {
+		if err := fstest.TestFS(fsys, "go/main.go.tmpl", "cpp/src/main.cc", "rust/Cargo.toml"); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestOverlayFS_CopyFromDiskLayer(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "go"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "go", "hello.txt.tmpl"), []byte("hi {{.ProjectName}}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	builtin := fstest.MapFS{"go/hello.txt.tmpl": {Data: []byte("builtin")}}
+	fsys := NewOverlayFS(os.DirFS(dir), builtin)
+
+	dest := filepath.Join(t.TempDir(), "output")
+	if err := CopyEmbedDir(context.Background(), &bytes.Buffer{}, fsys, "go", dest, TemplateVars{ProjectName: "demo"}); err != nil {
+		t.Fatalf("CopyEmbedDir() error = %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(dest, "hello.txt"))
+	if err != nil {
+		t.Fatalf("read hello.txt: %v", err)
+	}
+	if string(got) != "hi demo" {
+		t.Errorf("hello.txt = %q, want %q", got, "hi demo")
+	}
+}
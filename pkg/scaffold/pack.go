@@ -0,0 +1,100 @@
+package scaffold
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+)
+
+// vcsDir is left out when checking and packing a template directory that
+// is also a repository checkout.
+const vcsDir = ".git"
+
+// CheckTemplate validates the template at the root of fsys without
+// rendering it: its manifest must be valid, and every .tmpl file and
+// templated name must parse with the settings projects are rendered with.
+// All parse errors are reported together.
+func CheckTemplate(fsys fs.FS) error {
+	if _, err := LoadManifest(fsys, "."); err != nil {
+		return err
+	}
+
+	var errs []error
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name == "." {
+			return nil
+		}
+		if d.IsDir() && d.Name() == vcsDir {
+			return fs.SkipDir
+		}
+		if _, err := parseTemplate(d.Name(), TemplateVars{}); err != nil {
+			errs = append(errs, fmt.Errorf("%s: invalid name: %w", name, err))
+		}
+		if d.IsDir() || !isTemplateFile(d.Name()) {
+			return nil
+		}
+
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		// Errors name the file, e.g. "template: main.go.tmpl:3: ...".
+		if _, err := parseNamedTemplate(name, string(data), TemplateVars{}); err != nil {
+			errs = append(errs, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return errors.Join(errs...)
+}
+
+// PackTemplate checks the template at the root of fsys and writes it to w
+// as an archive with its entries under name. Only names, contents, and
+// whether each file is executable are recorded, so packing the same
+// template always produces the same bytes wherever it is checked out.
+func PackTemplate(w io.Writer, fsys fs.FS, format ArchiveFormat, name string) error {
+	if err := CheckTemplate(fsys); err != nil {
+		return err
+	}
+
+	files := NewMemFS()
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		switch {
+		case p == ".":
+			return nil
+		case d.IsDir() && d.Name() == vcsDir:
+			return fs.SkipDir
+		case d.IsDir():
+			return files.MkdirAll(p, 0755)
+		case !d.Type().IsRegular():
+			return fmt.Errorf("cannot pack %s: not a regular file", p)
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		perm := fs.FileMode(0644)
+		if info.Mode()&0111 != 0 {
+			perm = 0755
+		}
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		return files.WriteFile(p, data, perm)
+	})
+	if err != nil {
+		return err
+	}
+	return WriteArchive(w, files, format, name)
+}
@@ -0,0 +1,131 @@
+package scaffold
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCheckTemplate(t *testing.T) {
+	good := fstest.MapFS{
+		"template.yaml":               {Data: []byte("variables:\n  - name: license\n    default: MIT\n")},
+		"main.go.tmpl":                {Data: []byte("package main // {{.ProjectName | snake}} {{.license}}\n")},
+		"{{.ProjectName}}.h":          {Data: []byte("#pragma once\n")},
+		"notes.txt":                   {Data: []byte("{{ not a template, not checked\n")},
+		".git/objects/x.tmpl":         {Data: []byte("{{")},
+		"docs/{{.ProjectName}}/x.txt": {Data: []byte("x")},
+	}
+	if err := CheckTemplate(good); err != nil {
+		t.Errorf("CheckTemplate(good) error = %v", err)
+	}
+
+	bad := fstest.MapFS{
+		"a.txt.tmpl":    {Data: []byte("{{.ProjectName")},
+		"b.txt.tmpl":    {Data: []byte("{{ nosuchfunc .ProjectName }}")},
+		"{{.Broken.txt": {Data: []byte("x")},
+	}
+	err := CheckTemplate(bad)
+	if err == nil {
+		t.Fatal("CheckTemplate(bad) expected error, got nil")
+	}
+	for _, name := range []string{"a.txt.tmpl", "b.txt.tmpl", "{{.Broken.txt: invalid name"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("CheckTemplate(bad) error should mention %s, got:\n%v", name, err)
+		}
+	}
+
+	manifest := fstest.MapFS{"template.yaml": {Data: []byte("variables:\n  - name: ProjectName\n")}}
+	if err := CheckTemplate(manifest); err == nil {
+		t.Error("CheckTemplate() with an invalid manifest expected error, got nil")
+	}
+}
+
+func TestPackTemplate(t *testing.T) {
+	fsys := fstest.MapFS{
+		"template.yaml":   {Data: []byte("description: svc\n")},
+		"main.go.tmpl":    {Data: []byte("package main\n")},
+		"bin/run":         {Data: []byte("#!/bin/sh\n"), Mode: 0775},
+		"docs/readme.md":  {Data: []byte("docs\n"), Mode: 0600},
+		".git/HEAD":       {Data: []byte("ref: refs/heads/main\n")},
+		".gitignore":      {Data: []byte("build/\n")},
+		"empty/.gitkeep":  {},
+		"nested/a/b/c.md": {Data: []byte("c\n")},
+	}
+
+	var first bytes.Buffer
+	if err := PackTemplate(&first, fsys, ArchiveTgz, "svc"); err != nil {
+		t.Fatalf("PackTemplate() error = %v", err)
+	}
+
+	// Permissions differ between checkouts only in ways packing ignores.
+	other := fstest.MapFS{}
+	for name, f := range fsys {
+		g := *f
+		if name == "bin/run" {
+			g.Mode = 0755
+		}
+		if name == "docs/readme.md" {
+			g.Mode = 0664
+		}
+		other[name] = &g
+	}
+	var second bytes.Buffer
+	if err := PackTemplate(&second, other, ArchiveTgz, "svc"); err != nil {
+		t.Fatalf("PackTemplate() error = %v", err)
+	}
+	if !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Error("packing the same template twice should produce identical bytes")
+	}
+
+	gz, err := gzip.NewReader(&first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modes := make(map[string]int64)
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		modes[hdr.Name] = hdr.Mode
+	}
+	want := map[string]int64{
+		"svc/":                0755,
+		"svc/.gitignore":      0644,
+		"svc/bin/":            0755,
+		"svc/bin/run":         0755,
+		"svc/docs/":           0755,
+		"svc/docs/readme.md":  0644,
+		"svc/empty/":          0755,
+		"svc/empty/.gitkeep":  0644,
+		"svc/main.go.tmpl":    0644,
+		"svc/nested/":         0755,
+		"svc/nested/a/":       0755,
+		"svc/nested/a/b/":     0755,
+		"svc/nested/a/b/c.md": 0644,
+		"svc/template.yaml":   0644,
+	}
+	if len(modes) != len(want) {
+		t.Errorf("archive entries = %v, want %v", modes, want)
+	}
+	for name, mode := range want {
+		if got, ok := modes[name]; !ok || got != mode {
+			t.Errorf("%s mode = %o (present %v), want %o", name, got, ok, mode)
+		}
+	}
+
+	t.Run("invalid template", func(t *testing.T) {
+		bad := fstest.MapFS{"a.tmpl": {Data: []byte("{{")}}
+		if err := PackTemplate(io.Discard, bad, ArchiveZip, "bad"); err == nil {
+			t.Error("PackTemplate() expected error, got nil")
+		}
+	})
+}
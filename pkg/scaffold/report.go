@@ -0,0 +1,42 @@
+package scaffold
+
+import "io/fs"
+
+// Report describes what Create did or, in a dry run, would do. Pass one in
+// Options.Report to have it filled in.
+type Report struct {
+	Files  []FileReport
+	Merge  *MergeSummary // per-file outcomes when merging
+	Commit string        // the initial commit, when one was made
+
+	// Git is how git was used, once resolved from GitAuto, and Repository
+	// the enclosing work tree the project was added to with GitParent.
+	// Staged is set when the project was staged there but not committed.
+	Git        GitMode
+	Repository string
+	Staged     bool
+}
+
+// FileReport describes a file or directory generated from a template.
+type FileReport struct {
+	Source   string      // slash-separated path in the template filesystem
+	Dest     string      // path in the project, including the project directory
+	Mode     fs.FileMode // permissions, with fs.ModeDir set for directories
+	Rendered bool        // whether the content was rendered as a template
+}
+
+// addFile records the template entry at src generated as dest. It is a
+// no-op on a nil Report.
+func (r *Report) addFile(src, dest string, entry fs.DirEntry) {
+	if r == nil {
+		return
+	}
+	f := FileReport{Source: src, Dest: dest}
+	if entry.IsDir() {
+		f.Mode = fs.ModeDir | 0755
+	} else {
+		f.Mode = fileMode(entry)
+		f.Rendered = isTemplateFile(entry.Name())
+	}
+	r.Files = append(r.Files, f)
+}
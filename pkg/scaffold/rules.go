@@ -0,0 +1,61 @@
+package scaffold
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+func (r FileRule) validate() error {
+	pattern := strings.TrimSuffix(r.Path, "/")
+	if pattern == "" {
+		return fmt.Errorf("file rule has an empty path")
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("file rule %q has an invalid path pattern: %w", r.Path, err)
+	}
+	if strings.TrimSpace(r.When) == "" {
+		return fmt.Errorf("file rule %q has an empty when expression", r.Path)
+	}
+	if _, err := parseTemplate(r.condition(), TemplateVars{}); err != nil {
+		return fmt.Errorf("file rule %q has an invalid when expression: %w", r.Path, err)
+	}
+	return nil
+}
+
+// condition wraps the When pipeline so it renders "true" when it holds.
+func (r FileRule) condition() string {
+	return "{{if " + r.When + "}}true{{end}}"
+}
+
+// matches reports whether the rule applies to the entry at rel, a slash
+// path relative to the template root with any .tmpl suffix stripped.
+func (r FileRule) matches(rel string, isDir bool) bool {
+	pattern := r.Path
+	if strings.HasSuffix(pattern, "/") {
+		if !isDir {
+			return false
+		}
+		pattern = strings.TrimSuffix(pattern, "/")
+	}
+	ok, _ := path.Match(pattern, rel)
+	return ok
+}
+
+// includeEntry evaluates every rule matching rel and reports whether the
+// entry should be generated. Entries no rule matches are always included.
+func includeEntry(rules []FileRule, rel string, isDir bool, vars TemplateVars) (bool, error) {
+	for _, rule := range rules {
+		if !rule.matches(rel, isDir) {
+			continue
+		}
+		out, err := RenderTemplate([]byte(rule.condition()), vars)
+		if err != nil {
+			return false, fmt.Errorf("file rule %q: %w", rule.Path, err)
+		}
+		if string(out) != "true" {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,148 @@
+package scaffold
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFileRuleMatches(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  string
+		rel   string
+		isDir bool
+		want  bool
+	}{
+		{"exact file", "Dockerfile", "Dockerfile", false, true},
+		{"glob", "*.md", "README.md", false, true},
+		{"glob does not cross directories", "*.md", "docs/guide.md", false, false},
+		{"nested glob", "docs/*.md", "docs/guide.md", false, true},
+		{"directory rule matches directory", "dev-tools/", "dev-tools", true, true},
+		{"directory rule skips files", "dev-tools/", "dev-tools", false, false},
+		{"plain rule matches directory", "dev-tools", "dev-tools", true, true},
+		{"no match", "Dockerfile", "Makefile", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FileRule{Path: tt.rule, When: ".x"}.matches(tt.rel, tt.isDir)
+			if got != tt.want {
+				t.Errorf("matches(%q, %v) = %v, want %v", tt.rel, tt.isDir, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseManifest_FileRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{"valid", "files:\n  - path: Dockerfile\n    when: .docker", false},
+		{"expression", "files:\n  - path: LICENSE\n    when: 'eq .license \"MIT\"'", false},
+		{"empty path", "files:\n  - when: .docker", true},
+		{"bad pattern", "files:\n  - path: '[x'\n    when: .docker", true},
+		{"empty when", "files:\n  - path: Dockerfile", true},
+		{"bad expression", "files:\n  - path: Dockerfile\n    when: 'eq .docker ('", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseManifest([]byte(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseManifest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCopyEmbedDir_FileRules(t *testing.T) {
+	fsys := fstest.MapFS{
+		"lang/template.yaml": {Data: []byte(`files:
+  - path: Dockerfile
+    when: .docker
+  - path: dev-tools/
+    when: not .nolint
+  - path: LICENSE
+    when: eq .license "MIT"
+`)},
+		"lang/Dockerfile.tmpl":     {Data: []byte("FROM {{.ProjectName}}")},
+		"lang/LICENSE":             {Data: []byte("MIT")},
+		"lang/dev-tools/cpplint":   {Data: []byte("lint")},
+		"lang/src/main.cc":         {Data: []byte("int main() {}")},
+		"lang/src/Dockerfile.tmpl": {Data: []byte("nested is not matched")},
+	}
+
+	tests := []struct {
+		name    string
+		extra   map[string]any
+		present []string
+		absent  []string
+	}{
+		{
+			"all enabled",
+			map[string]any{"docker": true, "nolint": false, "license": "MIT"},
+			[]string{"Dockerfile", "LICENSE", "dev-tools/cpplint", "src/main.cc", "src/Dockerfile"},
+			nil,
+		},
+		{
+			"all disabled",
+			map[string]any{"docker": false, "nolint": true, "license": "Apache-2.0"},
+			[]string{"src/main.cc", "src/Dockerfile"},
+			[]string{"Dockerfile", "LICENSE", "dev-tools"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vars := TemplateVars{ProjectName: "demo", Extra: tt.extra}
+			dest := filepath.Join(t.TempDir(), "output")
+
+			var copyOut bytes.Buffer
+			if err := CopyEmbedDir(context.Background(), &copyOut, fsys, "lang", dest, vars); err != nil {
+				t.Fatalf("CopyEmbedDir() error = %v", err)
+			}
+			for _, name := range tt.present {
+				if _, err := os.Stat(filepath.Join(dest, filepath.FromSlash(name))); err != nil {
+					t.Errorf("%s should exist: %v", name, err)
+				}
+			}
+			for _, name := range tt.absent {
+				if _, err := os.Stat(filepath.Join(dest, filepath.FromSlash(name))); err == nil {
+					t.Errorf("%s should not exist", name)
+				}
+			}
+
+			// Dry-run must list exactly what was created.
+			var previewOut bytes.Buffer
+			if err := PreviewEmbedDir(&previewOut, fsys, "lang", dest, vars); err != nil {
+				t.Fatalf("PreviewEmbedDir() error = %v", err)
+			}
+			preview := strings.ReplaceAll(previewOut.String(), "/\n", "\n")
+			if preview != copyOut.String() {
+				t.Errorf("PreviewEmbedDir() = %q, want %q", preview, copyOut.String())
+			}
+		})
+	}
+}
+
+func TestCopyEmbedDir_FileRuleUndefinedVariable(t *testing.T) {
+	fsys := fstest.MapFS{
+		"lang/template.yaml": {Data: []byte("files:\n  - path: Dockerfile\n    when: .docker\n")},
+		"lang/Dockerfile":    {Data: []byte("FROM scratch")},
+	}
+
+	err := CopyEmbedDir(context.Background(), &bytes.Buffer{}, fsys, "lang", filepath.Join(t.TempDir(), "out"), TemplateVars{ProjectName: "demo"})
+	if err == nil {
+		t.Fatal("CopyEmbedDir() expected error, got nil")
+	}
+	if !strings.Contains(err.Error(), `"docker"`) {
+		t.Errorf("CopyEmbedDir() error = %v, want mention of docker", err)
+	}
+}
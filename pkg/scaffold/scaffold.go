@@ -1,11 +1,14 @@
 package scaffold
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"os"
+	"path/filepath"
+	"sort"
 
 	"github.com/JackDrogon/project/pkg/git"
 )
@@ -14,12 +17,47 @@ import (
 type Creator struct {
 	fsys fs.FS
 	w    io.Writer
+	git  git.Backend
+
+	// detected caches the defaults read from git, by directory.
+	detected map[string]detected
 }
 
 // NewCreator returns a Creator that reads templates from fsys and writes
 // progress output to w.
 func NewCreator(fsys fs.FS, w io.Writer) *Creator {
-	return &Creator{fsys: fsys, w: w}
+	return &Creator{fsys: fsys, w: w, git: git.Exec{}}
+}
+
+// SetOutput redirects progress output to w, e.g. to keep stdout free for
+// an archive.
+func (c *Creator) SetOutput(w io.Writer) {
+	c.w = w
+}
+
+// SetGit makes the Creator use b instead of the git executable, e.g. a
+// git.Fake in tests, to initialize repositories and read defaults.
+func (c *Creator) SetGit(b git.Backend) {
+	c.git = b
+	c.detected = nil
+}
+
+// Overlay layers additional template trees over the Creator's current ones.
+// Layers are given highest precedence first; see NewOverlayFS.
+func (c *Creator) Overlay(layers ...fs.FS) {
+	if len(layers) == 0 {
+		return
+	}
+	c.fsys = NewOverlayFS(append(layers, c.fsys)...)
+}
+
+// TemplateFS returns the template tree for lang, with its manifest at the
+// root, as the Creator sees it after overlays.
+func (c *Creator) TemplateFS(lang string) (fs.FS, error) {
+	if err := c.checkLang(context.Background(), Options{Lang: lang}); err != nil {
+		return nil, err
+	}
+	return fs.Sub(c.fsys, lang)
 }
 
 // Options holds all parameters for project creation.
@@ -27,33 +65,105 @@ type Options struct {
 	Lang        string
 	ProjectName string
 	ModulePath  string
+	Author      string // overrides the default author when non-empty
+	AuthorEmail string // overrides the default author email when non-empty
+	Year        int    // overrides the current year when non-zero
 	Force       bool
-	Signoff     bool
 	DryRun      bool
+	AllowEnv    bool // enables the env template function
+	NoHooks     bool // skips the template's pre and post hooks
+
+	// Git chooses between initializing a repository in the project and
+	// adding the project to the enclosing one; NoGit is the same as
+	// GitNone. A new repository gets an initial commit unless NoCommit is
+	// set. In an enclosing repository the project is only staged, and
+	// Commit commits it too. InitialBranch names the first branch instead
+	// of git's default. CommitMessage is rendered as a template with the
+	// project variables; it defaults to "Initial commit", or "Add <project>" in an
+	// enclosing repository. CommitAuthor, as "Name <email>", overrides the
+	// configured author. Sign signs the commit with git's configured GPG or
+	// SSH key, or with SigningKey when set.
+	Git           GitMode
+	NoGit         bool
+	NoCommit      bool
+	Commit        bool
+	InitialBranch string
+	CommitMessage string
+	CommitAuthor  string
+	Signoff       bool
+	Sign          bool
+	SigningKey    string
+	Remotes       []Remote
+
+	// Merge applies the template into an existing directory instead of
+	// replacing it. Files that exist with different content are handled
+	// by Conflict; ConflictPrompt defers each one to Resolve.
+	Merge    bool
+	Conflict ConflictPolicy
+	Resolve  ConflictResolver
+
+	// Archive, when set, writes the project to ArchiveOut in that format
+	// instead of creating a directory. ArchiveGit initializes the git
+	// repository first and includes it in the archive.
+	Archive    ArchiveFormat
+	ArchiveOut io.Writer
+	ArchiveGit bool
+
+	// Report, when non-nil, is filled in with the files Create generated,
+	// or would generate in a dry run, and the initial commit.
+	Report *Report
+
+	// Vars holds user-supplied custom template variables, e.g. from
+	// --set and --values. Declared manifest types are applied on resolve.
+	Vars map[string]any
+
+	// workDir is where files are written and git runs; Create points it at
+	// a staging directory. Empty means ProjectName.
+	workDir string
+	// gitRoot is the top of the enclosing work tree with GitParent.
+	gitRoot string
 }
 
-// pipeline chains fallible steps, short-circuiting on the first error.
+// dir returns the directory the project is currently being built in.
+func (o Options) dir() string {
+	if o.workDir != "" {
+		return o.workDir
+	}
+	return o.ProjectName
+}
+
+// pipeline chains fallible steps, short-circuiting on the first error or
+// once ctx is done.
 type pipeline struct {
+	ctx  context.Context
 	err  error
 	opts Options
 }
 
-func newPipeline(opts Options) *pipeline {
-	return &pipeline{opts: opts}
+func newPipeline(ctx context.Context, opts Options) *pipeline {
+	return &pipeline{ctx: ctx, opts: opts}
 }
 
-func (p *pipeline) step(fn func(Options) error) *pipeline {
+func (p *pipeline) step(fn func(context.Context, Options) error) *pipeline {
 	if p.err == nil {
-		p.err = fn(p.opts)
+		p.err = p.ctx.Err()
+	}
+	if p.err == nil {
+		p.err = fn(p.ctx, p.opts)
 	}
 	return p
 }
 
 func (p *pipeline) Err() error { return p.err }
 
-// Create scaffolds a new project based on the given options.
-func (c *Creator) Create(opts Options) error {
-	p := newPipeline(opts).step(c.validate).step(c.checkLang)
+// Create scaffolds a new project based on the given options. The project
+// is built in a staging directory beside the destination and moved into
+// place only once every step has succeeded; on failure the staging
+// directory is removed and any existing destination is left as it was.
+// Canceling ctx stops the current step, killing any child process, and
+// removes what the run created.
+func (c *Creator) Create(ctx context.Context, opts Options) error {
+	p := newPipeline(ctx, opts).step(c.validate).step(c.checkLang).step(c.checkNames).step(c.checkVars).step(c.checkGit)
 	if p.Err() != nil {
 		return p.Err()
 	}
@@ -62,10 +172,73 @@ func (c *Creator) Create(opts Options) error {
 
 	if opts.DryRun {
 		_, _ = fmt.Fprintln(c.w, "Dry-run mode: no files will be created")
-		return PreviewEmbedDir(c.w, c.fsys, opts.Lang, opts.ProjectName)
+		vars, err := c.templateVars(ctx, opts)
+		if err != nil {
+			return err
+		}
+		if err := previewTree(c.w, c.fsys, opts.Lang, opts.ProjectName, vars, opts.Report); err != nil {
+			return err
+		}
+		return c.previewHooks(opts)
+	}
+
+	if opts.Archive != "" {
+		if err := c.createArchive(ctx, opts); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintln(c.w, "Project archive written successfully")
+		return nil
+	}
+
+	opts, err := c.resolveGit(ctx, opts)
+	if err != nil {
+		return err
+	}
+	p.opts = opts
+	if opts.Report != nil {
+		opts.Report.Git = opts.Git
+		opts.Report.Repository = opts.gitRoot
+	}
+	if err := p.step(c.checkDestDir).Err(); err != nil {
+		return err
+	}
+
+	stage, err := newStaging(opts.ProjectName)
+	if err != nil {
+		return err
+	}
+	defer stage.cleanup()
+
+	p.opts.workDir = stage.dir
+	if opts.Merge {
+		// The merge writes into the destination itself, so a failure in it,
+		// in the post hooks, or in git init afterwards is undone through the
+		// log. Files the post hooks create themselves are not tracked.
+		var undo undoLog
+		p.step(c.preHooks).step(c.copyTemplates).
+			step(func(ctx context.Context, opts Options) error { return c.mergeTemplates(ctx, opts, &undo) }).
+			step(func(ctx context.Context, opts Options) error {
+				opts.workDir = ""
+				return c.postHooks(ctx, opts)
+			}).
+			step(func(ctx context.Context, opts Options) error { return c.initMergedGitRepo(ctx, opts, &undo) })
+		if err := p.Err(); err != nil {
+			undo.rollback()
+			return err
+		}
+		_, _ = fmt.Fprintln(c.w, "Project merged successfully")
+		return nil
 	}
 
-	if err := p.step(c.checkDestDir).step(c.copyTemplates).step(c.initGitRepo).Err(); err != nil {
+	// Post hooks and git run once the project is in place, so paths they
+	// record, such as a CMake build tree's, point at the destination. If
+	// they fail, the project is moved back out and removed.
+	if err := p.step(c.preHooks).step(c.copyTemplates).step(func(ctx context.Context, opts Options) error {
+		return stage.commit(func() error {
+			opts.workDir = ""
+			return newPipeline(ctx, opts).step(c.postHooks).step(c.initGitRepo).step(c.addToParentRepo).Err()
+		})
+	}).Err(); err != nil {
 		return err
 	}
 
@@ -73,18 +246,118 @@ func (c *Creator) Create(opts Options) error {
 	return nil
 }
 
-func (c *Creator) validate(opts Options) error {
+// Render renders the project described by opts into memory and returns
+// it as a filesystem rooted at the project directory, without touching the
+// disk, printing progress, or initializing git. Force, DryRun, Merge, and
+// the git options are ignored; opts.Report, if set, lists the files. The
+// result can be inspected, modified, and written out with CopyFS.
+func (c *Creator) Render(ctx context.Context, opts Options) (*MemFS, error) {
+	p := newPipeline(ctx, opts).step(c.validate).step(c.checkLang).step(c.checkNames)
+	if p.Err() != nil {
+		return nil, p.Err()
+	}
+	vars, err := c.templateVars(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	out := NewMemFS()
+	if err := renderTree(ctx, io.Discard, c.fsys, opts.Lang, out, opts.ProjectName, vars, opts.Report); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// createArchive writes the project to opts.ArchiveOut. It is rendered in
+// memory unless git or hooks need a real directory, in which case it is
+// built in a staging directory that is always removed afterwards.
+func (c *Creator) createArchive(ctx context.Context, opts Options) error {
+	if opts.ArchiveOut == nil {
+		return errors.New("no archive output given")
+	}
+	hooks, err := c.hooks(opts)
+	if err != nil {
+		return err
+	}
+	if !opts.ArchiveGit && len(hooks.Pre)+len(hooks.Post) == 0 {
+		vars, err := c.templateVars(ctx, opts)
+		if err != nil {
+			return err
+		}
+		out := NewMemFS()
+		if err := renderTree(ctx, c.w, c.fsys, opts.Lang, out, opts.ProjectName, vars, opts.Report); err != nil {
+			return err
+		}
+		return WriteArchive(opts.ArchiveOut, out, opts.Archive, opts.ProjectName)
+	}
+
+	stage, err := newStaging(opts.ProjectName)
+	if err != nil {
+		return err
+	}
+	defer stage.cleanup()
+
+	opts.workDir = stage.dir
+	p := newPipeline(ctx, opts).step(c.preHooks).step(c.copyTemplates).step(c.postHooks)
+	if opts.ArchiveGit {
+		p.step(c.initGitRepo)
+	}
+	return p.step(func(_ context.Context, opts Options) error {
+		return WriteArchive(opts.ArchiveOut, os.DirFS(opts.dir()), opts.Archive, opts.ProjectName)
+	}).Err()
+}
+
+func (c *Creator) validate(_ context.Context, opts Options) error {
 	return ValidateProjectName(opts.ProjectName)
 }
 
-func (c *Creator) checkLang(opts Options) error {
+func (c *Creator) checkLang(_ context.Context, opts Options) error {
 	if _, err := fs.ReadDir(c.fsys, opts.Lang); err != nil {
-		return fmt.Errorf("unsupported language: %s", opts.Lang)
+		return fmt.Errorf("%w: %s", ErrUnsupportedLang, opts.Lang)
 	}
 	return nil
 }
 
-func (c *Creator) checkDestDir(opts Options) error {
+// checkVars resolves the template variables up front so a missing or
+// invalid value fails before anything is written.
+func (c *Creator) checkVars(ctx context.Context, opts Options) error {
+	_, err := c.templateVars(ctx, opts)
+	return err
+}
+
+// templateVars builds the rendering variables for opts, including the
+// custom variables declared in the template manifest.
+func (c *Creator) templateVars(ctx context.Context, opts Options) (TemplateVars, error) {
+	vars := c.DefaultVars(ctx, opts)
+	vars.AllowEnv = opts.AllowEnv
+
+	for name := range opts.Vars {
+		if isBuiltinVar(name) {
+			return vars, markErr(fmt.Errorf("variable %q is built in and cannot be set", name), ErrInvalidVariable)
+		}
+	}
+
+	manifest, err := LoadManifest(c.fsys, opts.Lang)
+	if err != nil {
+		return vars, err
+	}
+	extra, err := manifest.Resolve(opts.Vars)
+	if err != nil {
+		return vars, markErr(fmt.Errorf("template %s: %w", opts.Lang, err), ErrInvalidVariable)
+	}
+	names := make([]string, 0, len(extra))
+	for name := range extra {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if err := checkHookEnvNames(names); err != nil {
+		return vars, markErr(err, ErrInvalidVariable)
+	}
+	vars.Extra = extra
+	return vars, nil
+}
+
+func (c *Creator) checkDestDir(_ context.Context, opts Options) error {
 	info, err := os.Stat(opts.ProjectName)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
@@ -94,41 +367,254 @@ func (c *Creator) checkDestDir(opts Options) error {
 	}
 
 	if !info.IsDir() {
-		return fmt.Errorf("destination %q already exists and is not a directory", opts.ProjectName)
+		return markErr(fmt.Errorf("destination %q already exists and is not a directory", opts.ProjectName), ErrDestinationExists)
 	}
 
+	if opts.Merge {
+		return nil
+	}
 	if !opts.Force {
-		return fmt.Errorf("directory %q already exists; use --force to overwrite", opts.ProjectName)
+		return markErr(fmt.Errorf("directory %q already exists; use --force to overwrite or --merge to add to it", opts.ProjectName), ErrDestinationExists)
+	}
+
+	_, _ = fmt.Fprintf(c.w, "Warning: directory %q already exists, replacing it due to --force\n", opts.ProjectName)
+	return nil
+}
+
+func (c *Creator) copyTemplates(ctx context.Context, opts Options) error {
+	vars, err := c.templateVars(ctx, opts)
+	if err != nil {
+		return err
+	}
+	// When merging, per-file outcomes are reported by mergeTemplates.
+	w := c.w
+	if opts.Merge {
+		w = io.Discard
+	}
+	return copyTree(ctx, w, c.fsys, opts.Lang, opts.dir(), opts.ProjectName, vars, opts.Report)
+}
+
+// hooks returns the template's hooks, or none when opts.NoHooks is set.
+func (c *Creator) hooks(opts Options) (Hooks, error) {
+	if opts.NoHooks {
+		return Hooks{}, nil
 	}
+	manifest, err := LoadManifest(c.fsys, opts.Lang)
+	if err != nil {
+		return Hooks{}, err
+	}
+	return manifest.Hooks, nil
+}
 
-	_, _ = fmt.Fprintf(c.w, "Warning: directory %q already exists, removing due to --force\n", opts.ProjectName)
-	if err := os.RemoveAll(opts.ProjectName); err != nil {
-		return fmt.Errorf("failed to remove existing directory %q: %w", opts.ProjectName, err)
+// preHooks runs the template's pre hooks in the still empty work directory.
+func (c *Creator) preHooks(ctx context.Context, opts Options) error {
+	hooks, err := c.hooks(opts)
+	if err != nil {
+		return err
 	}
+	return c.runHooks(ctx, opts, "pre", hooks.Pre)
+}
+
+// postHooks runs the template's post hooks once the files are written.
+func (c *Creator) postHooks(ctx context.Context, opts Options) error {
+	hooks, err := c.hooks(opts)
+	if err != nil {
+		return err
+	}
+	return c.runHooks(ctx, opts, "post", hooks.Post)
+}
+
+func (c *Creator) runHooks(ctx context.Context, opts Options, stage string, hooks []Hook) error {
+	if len(hooks) == 0 {
+		return nil
+	}
+	vars, err := c.templateVars(ctx, opts)
+	if err != nil {
+		return err
+	}
+	return runHooks(ctx, c.w, stage, hooks, opts.dir(), vars)
+}
 
+// previewHooks lists the hook commands a dry run would have executed.
+func (c *Creator) previewHooks(opts Options) error {
+	hooks, err := c.hooks(opts)
+	if err != nil {
+		return err
+	}
+	for _, stage := range []struct {
+		name  string
+		hooks []Hook
+	}{{"pre", hooks.Pre}, {"post", hooks.Post}} {
+		for _, h := range stage.hooks {
+			_, _ = fmt.Fprintf(c.w, "  run %s (%s hook)\n", h.Run, stage.name)
+		}
+	}
+	return nil
+}
+
+// mergeTemplates applies the staged project onto the destination directory.
+func (c *Creator) mergeTemplates(ctx context.Context, opts Options, undo *undoLog) error {
+	policy := opts.Conflict
+	if policy == "" {
+		policy = ConflictSkip
+	}
+	summary, err := mergeDir(ctx, c.w, opts.dir(), opts.ProjectName, opts.ProjectName, policy, opts.Resolve, undo)
+	if err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(c.w, "Summary: %s\n", summary)
+	if opts.Report != nil {
+		opts.Report.Merge = &summary
+	}
 	return nil
 }
 
-func (c *Creator) copyTemplates(opts Options) error {
-	vars := NewTemplateVars(opts.ProjectName, opts.ModulePath)
-	return CopyEmbedDir(c.w, c.fsys, opts.Lang, opts.ProjectName, vars)
+// initMergedGitRepo initializes git in a merged project unless the
+// destination already is a repository or is inside one, which is left for
+// the user to commit.
+func (c *Creator) initMergedGitRepo(ctx context.Context, opts Options, undo *undoLog) error {
+	opts.workDir = ""
+	switch {
+	case opts.NoGit || opts.Git == GitNone:
+		return nil
+	case hasGitDir(opts.dir()):
+		_, _ = fmt.Fprintln(c.w, "Existing git repository left untouched; review and commit the changes")
+		return nil
+	case opts.Git == GitParent:
+		_, _ = fmt.Fprintf(c.w, "Enclosing git repository at %s left untouched; review and commit the changes\n", opts.gitRoot)
+		return nil
+	}
+	gitDir := filepath.Join(opts.dir(), ".git")
+	undo.steps = append(undo.steps, func() { _ = os.RemoveAll(gitDir) })
+	return c.initGitRepo(ctx, opts)
 }
 
-func (c *Creator) initGitRepo(opts Options) error {
-	commitArgs := []string{"commit", "-m", "Initial commit"}
-	if opts.Signoff {
-		commitArgs = []string{"commit", "-s", "-m", "Initial commit"}
+// checkGit validates the git options up front, so a bad author or commit
+// message fails before anything is written.
+func (c *Creator) checkGit(ctx context.Context, opts Options) error {
+	if opts.NoGit {
+		return nil
+	}
+	if opts.CommitAuthor != "" {
+		if _, err := ParseIdentity(opts.CommitAuthor); err != nil {
+			return err
+		}
 	}
+	vars, err := c.templateVars(ctx, opts)
+	if err != nil {
+		return err
+	}
+	_, err = commitMessage(opts, vars)
+	return err
+}
 
-	for _, args := range [][]string{{"init"}, {"add", "."}, commitArgs} {
-		if err := git.Run(opts.ProjectName, args...); err != nil {
+func (c *Creator) initGitRepo(ctx context.Context, opts Options) error {
+	if !opts.nestedGit() {
+		return nil
+	}
+	repo := c.git.Open(opts.dir())
+	if err := repo.Init(ctx, git.InitOptions{InitialBranch: opts.InitialBranch}); err != nil {
+		return err
+	}
+	for _, remote := range opts.Remotes {
+		if err := repo.SetRemote(ctx, remote.Name, remote.URL); err != nil {
 			return err
 		}
 	}
+	if opts.NoCommit {
+		return nil
+	}
 
+	if err := repo.Add(ctx, "."); err != nil {
+		return err
+	}
+	id, err := c.commit(ctx, repo, opts)
+	if err != nil {
+		return err
+	}
+	if opts.Report != nil {
+		opts.Report.Commit = id
+	}
 	return nil
 }
 
+// addToParentRepo stages the project, now in place, in the enclosing
+// repository with GitParent, and commits it there if opts.Commit is set.
+// Only the project's files are committed; anything else the user had staged
+// stays staged. On failure the project is unstaged again.
+func (c *Creator) addToParentRepo(ctx context.Context, opts Options) (err error) {
+	if opts.Git != GitParent {
+		return nil
+	}
+	rel, err := relPath(opts.gitRoot, opts.ProjectName)
+	if err != nil {
+		return err
+	}
+	repo := c.git.Open(opts.gitRoot)
+	defer func() {
+		if err != nil {
+			_ = repo.Unstage(context.WithoutCancel(ctx), rel)
+		}
+	}()
+	if err := repo.Add(ctx, rel); err != nil {
+		return err
+	}
+	if !opts.Commit || opts.NoCommit {
+		_, _ = fmt.Fprintf(c.w, "Staged %s in the git repository at %s; review and commit it\n", rel, opts.gitRoot)
+		if opts.Report != nil {
+			opts.Report.Staged = true
+		}
+		return nil
+	}
+	id, err := c.commit(ctx, repo, opts, rel)
+	if err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(c.w, "Committed %s to the git repository at %s\n", rel, opts.gitRoot)
+	if opts.Report != nil {
+		opts.Report.Commit = id
+	}
+	return nil
+}
+
+// commit commits what is staged in repo, or only paths when given, as opts
+// describes, and returns the commit id. If git has no identity to commit
+// with, it commits as the author, or as whatever identity can be pieced
+// together, rather than fail on a machine without one.
+func (c *Creator) commit(ctx context.Context, repo git.Repository, opts Options, paths ...string) (string, error) {
+	vars, err := c.templateVars(ctx, opts)
+	if err != nil {
+		return "", err
+	}
+	commit := git.CommitOptions{Signoff: opts.Signoff, Sign: opts.Sign, SigningKey: opts.SigningKey, Paths: paths}
+	if commit.Message, err = commitMessage(opts, vars); err != nil {
+		return "", err
+	}
+	if opts.CommitAuthor != "" {
+		author, err := ParseIdentity(opts.CommitAuthor)
+		if err != nil {
+			return "", err
+		}
+		commit.Author = &author
+	}
+
+	id, err := repo.Commit(ctx, commit)
+	if !errors.Is(err, git.ErrNoIdentity) {
+		return id, err
+	}
+	fallback := commit.Author
+	if fallback == nil {
+		sig, err := fallbackIdentity(ctx, repo, vars.Author, vars.AuthorEmail)
+		if err != nil {
+			return "", err
+		}
+		fallback = &sig
+		commit.Author = fallback
+	}
+	commit.Committer = fallback
+	return repo.Commit(ctx, commit)
+}
+
 // ListLangs returns the available template language names.
 func (c *Creator) ListLangs() ([]string, error) {
 	entries, err := fs.ReadDir(c.fsys, ".")
@@ -144,3 +630,28 @@ func (c *Creator) ListLangs() ([]string, error) {
 	}
 	return langs, nil
 }
+
+// TemplateInfo describes an available template.
+type TemplateInfo struct {
+	Name        string
+	Description string
+	Variables   []Variable
+}
+
+// Templates returns the available templates with their manifest metadata.
+func (c *Creator) Templates() ([]TemplateInfo, error) {
+	langs, err := c.ListLangs()
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]TemplateInfo, 0, len(langs))
+	for _, lang := range langs {
+		manifest, err := LoadManifest(c.fsys, lang)
+		if err != nil {
+			return nil, fmt.Errorf("template %s: %w", lang, err)
+		}
+		infos = append(infos, TemplateInfo{Name: lang, Description: manifest.Description, Variables: manifest.Variables})
+	}
+	return infos, nil
+}
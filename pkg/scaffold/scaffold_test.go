@@ -2,13 +2,50 @@ package scaffold
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"io/fs"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 	"testing/fstest"
+	"time"
 )
 
+// chdirTemp switches the working directory to a fresh temp dir for the
+// duration of the test, since Create resolves the project name relative to it.
+func chdirTemp(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+	return dir
+}
+
+// mkdirMode returns the permissions a directory made by mkdir gets under
+// the current umask.
+func mkdirMode(t *testing.T) fs.FileMode {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "mkdir")
+	if err := os.Mkdir(dir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info.Mode().Perm()
+}
+
 func TestListLangs(t *testing.T) {
 	fsys := fstest.MapFS{
 		"go/Makefile":  {Data: []byte("build:")},
@@ -34,12 +71,50 @@ func TestListLangs(t *testing.T) {
 	}
 }
 
+func TestTemplates(t *testing.T) {
+	fsys := fstest.MapFS{
+		"go/template.yaml": {Data: []byte("description: Go module\nvariables:\n  - name: license\n    default: MIT\n")},
+		"cpp/Makefile":     {Data: []byte("build:")},
+	}
+
+	infos, err := NewCreator(fsys, &bytes.Buffer{}).Templates()
+	if err != nil {
+		t.Fatalf("Templates() error = %v", err)
+	}
+	want := []TemplateInfo{
+		{Name: "cpp"},
+		{Name: "go", Description: "Go module", Variables: []Variable{{Name: "license", Type: TypeString, Default: "MIT"}}},
+	}
+	if !reflect.DeepEqual(infos, want) {
+		t.Errorf("Templates() = %+v, want %+v", infos, want)
+	}
+}
+
+func TestCreate_MissingRequiredVariable(t *testing.T) {
+	fsys := fstest.MapFS{
+		"go/template.yaml": {Data: []byte("variables:\n  - name: license\n    description: SPDX id\n")},
+		"go/LICENSE.tmpl":  {Data: []byte("{{.license}}")},
+	}
+	chdirTemp(t)
+
+	err := NewCreator(fsys, &bytes.Buffer{}).Create(context.Background(), Options{Lang: "go", ProjectName: "demo"})
+	if err == nil {
+		t.Fatal("Create() expected error, got nil")
+	}
+	if !strings.Contains(err.Error(), `missing required variable "license"`) {
+		t.Errorf("Create() error = %v, want missing variable message", err)
+	}
+	if _, err := os.Stat("demo"); !os.IsNotExist(err) {
+		t.Errorf("destination should not be created, stat err = %v", err)
+	}
+}
+
 func TestCheckDestDir(t *testing.T) {
 	c := NewCreator(fstest.MapFS{}, &bytes.Buffer{})
 
 	t.Run("missing destination is allowed", func(t *testing.T) {
 		dest := filepath.Join(t.TempDir(), "newproj")
-		if err := c.checkDestDir(Options{ProjectName: dest}); err != nil {
+		if err := c.checkDestDir(context.Background(), Options{ProjectName: dest}); err != nil {
 			t.Fatalf("checkDestDir() error = %v", err)
 		}
 	})
@@ -51,7 +126,7 @@ func TestCheckDestDir(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		err := c.checkDestDir(Options{ProjectName: dest})
+		err := c.checkDestDir(context.Background(), Options{ProjectName: dest})
 		if err == nil {
 			t.Fatal("checkDestDir() expected error, got nil")
 		}
@@ -66,7 +141,7 @@ func TestCheckDestDir(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		err := c.checkDestDir(Options{ProjectName: dest})
+		err := c.checkDestDir(context.Background(), Options{ProjectName: dest})
 		if err == nil {
 			t.Fatal("checkDestDir() expected error, got nil")
 		}
@@ -75,7 +150,7 @@ func TestCheckDestDir(t *testing.T) {
 		}
 	})
 
-	t.Run("existing directory with force is removed", func(t *testing.T) {
+	t.Run("existing directory with force is kept until commit", func(t *testing.T) {
 		dest := filepath.Join(t.TempDir(), "existing")
 		if err := os.MkdirAll(filepath.Join(dest, "nested"), 0755); err != nil {
 			t.Fatal(err)
@@ -84,12 +159,309 @@ func TestCheckDestDir(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		if err := c.checkDestDir(Options{ProjectName: dest, Force: true}); err != nil {
+		if err := c.checkDestDir(context.Background(), Options{ProjectName: dest, Force: true}); err != nil {
 			t.Fatalf("checkDestDir() error = %v", err)
 		}
 
-		if _, err := os.Stat(dest); !os.IsNotExist(err) {
-			t.Fatalf("destination should be removed, stat err = %v", err)
+		if _, err := os.Stat(filepath.Join(dest, "nested", "old.txt")); err != nil {
+			t.Fatalf("destination should be left in place, stat err = %v", err)
 		}
 	})
 }
+
+// requireGit skips the test when git is unavailable and gives commits an
+// identity, since CI machines often have no global user.name/user.email.
+func requireGit(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Setenv("GIT_AUTHOR_NAME", "Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+}
+
+// assertNoStaging fails if a staging directory was left behind in dir.
+func assertNoStaging(t *testing.T, dir string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), ".staging-") {
+			t.Errorf("staging directory %s left behind", entry.Name())
+		}
+	}
+}
+
+func TestCreate_Atomic(t *testing.T) {
+	good := fstest.MapFS{
+		"go/main.go.tmpl": {Data: []byte("package main // {{.ProjectName}}")},
+		"go/sub/a.txt":    {Data: []byte("a")},
+	}
+	bad := fstest.MapFS{
+		"go/a.txt":      {Data: []byte("written before the failure")},
+		"go/z.txt.tmpl": {Data: []byte("{{.missing}}")},
+	}
+
+	t.Run("success moves project into place", func(t *testing.T) {
+		requireGit(t)
+		dir := chdirTemp(t)
+
+		var out bytes.Buffer
+		if err := NewCreator(good, &out).Create(context.Background(), Options{Lang: "go", ProjectName: "demo"}); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		if _, err := os.Stat(filepath.Join("demo", "main.go")); err != nil {
+			t.Errorf("main.go missing: %v", err)
+		}
+		if info, err := os.Stat("demo"); err != nil {
+			t.Error(err)
+		} else if want := mkdirMode(t); info.Mode().Perm() != want {
+			t.Errorf("project directory mode = %v, want %v as from mkdir", info.Mode().Perm(), want)
+		}
+		if _, err := os.Stat(filepath.Join("demo", ".git")); err != nil {
+			t.Errorf(".git missing: %v", err)
+		}
+		if !strings.Contains(out.String(), "  create "+filepath.Join("demo", "main.go")) {
+			t.Errorf("output should report final paths, got:\n%s", out.String())
+		}
+		assertNoStaging(t, dir)
+	})
+
+	t.Run("render failure leaves nothing behind", func(t *testing.T) {
+		dir := chdirTemp(t)
+
+		if err := NewCreator(bad, &bytes.Buffer{}).Create(context.Background(), Options{Lang: "go", ProjectName: "demo"}); err == nil {
+			t.Fatal("Create() expected error, got nil")
+		}
+		if _, err := os.Stat("demo"); !os.IsNotExist(err) {
+			t.Errorf("destination should not exist, stat err = %v", err)
+		}
+		assertNoStaging(t, dir)
+	})
+
+	t.Run("force failure restores original directory", func(t *testing.T) {
+		dir := chdirTemp(t)
+		if err := os.MkdirAll(filepath.Join("demo", "nested"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join("demo", "nested", "old.txt"), []byte("old"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := NewCreator(bad, &bytes.Buffer{}).Create(context.Background(), Options{Lang: "go", ProjectName: "demo", Force: true}); err == nil {
+			t.Fatal("Create() expected error, got nil")
+		}
+		got, err := os.ReadFile(filepath.Join("demo", "nested", "old.txt"))
+		if err != nil || string(got) != "old" {
+			t.Errorf("original file should be intact, got %q, err = %v", got, err)
+		}
+		if _, err := os.Stat(filepath.Join("demo", "a.txt")); err == nil {
+			t.Error("partial output should not be mixed into the original directory")
+		}
+		assertNoStaging(t, dir)
+	})
+
+	t.Run("force success replaces original directory", func(t *testing.T) {
+		requireGit(t)
+		dir := chdirTemp(t)
+		if err := os.MkdirAll("demo", 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join("demo", "old.txt"), []byte("old"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := NewCreator(good, &bytes.Buffer{}).Create(context.Background(), Options{Lang: "go", ProjectName: "demo", Force: true}); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		if _, err := os.Stat(filepath.Join("demo", "old.txt")); err == nil {
+			t.Error("old.txt should be gone after --force")
+		}
+		if _, err := os.Stat(filepath.Join("demo", "main.go")); err != nil {
+			t.Errorf("main.go missing: %v", err)
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 {
+			t.Errorf("expected only the project directory, found %d entries", len(entries))
+		}
+	})
+}
+
+func TestRender(t *testing.T) {
+	fsys := fstest.MapFS{
+		"go/template.yaml":              {Data: []byte("variables:\n  - name: license\n    default: MIT\n")},
+		"go/main.go.tmpl":               {Data: []byte("package main // {{.ProjectName}} {{.license}}")},
+		"go/cmd/{{.ProjectName}}/x.txt": {Data: []byte("x")},
+	}
+	dir := chdirTemp(t)
+
+	var out bytes.Buffer
+	got, err := NewCreator(fsys, &out).Render(context.Background(), Options{Lang: "go", ProjectName: "demo"})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if err := fstest.TestFS(got, "main.go", "cmd/demo/x.txt"); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := fs.ReadFile(got, "main.go")
+	if want := "package main // demo MIT"; string(data) != want {
+		t.Errorf("main.go = %q, want %q", data, want)
+	}
+	if _, err := fs.Stat(got, manifestName); err == nil {
+		t.Error("manifest should not be rendered")
+	}
+	if out.Len() != 0 {
+		t.Errorf("Render() should not print, got %q", out.String())
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Render() should not touch the disk, found %v", entries)
+	}
+
+	t.Run("unknown language", func(t *testing.T) {
+		if _, err := NewCreator(fsys, &out).Render(context.Background(), Options{Lang: "java", ProjectName: "demo"}); err == nil {
+			t.Error("Render() expected error, got nil")
+		}
+	})
+}
+
+func TestCreate_Canceled(t *testing.T) {
+	requireSh(t)
+	fsys := fstest.MapFS{
+		"go/template.yaml": {Data: []byte("hooks:\n  post:\n    - run: sleep 5\n")},
+		"go/main.go":       {Data: []byte("package main")},
+	}
+
+	t.Run("before start", func(t *testing.T) {
+		dir := chdirTemp(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := NewCreator(fsys, &bytes.Buffer{}).Create(ctx, Options{Lang: "go", ProjectName: "demo"})
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("Create() error = %v, want %v", err, context.Canceled)
+		}
+		if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+			t.Errorf("Create() should not write anything, found %v", entries)
+		}
+	})
+
+	t.Run("during a hook", func(t *testing.T) {
+		dir := chdirTemp(t)
+		if err := os.MkdirAll("demo", 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join("demo", "old.txt"), []byte("old"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		err := NewCreator(fsys, &bytes.Buffer{}).Create(ctx, Options{Lang: "go", ProjectName: "demo", Force: true})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Create() error = %v, want %v", err, context.DeadlineExceeded)
+		}
+		if elapsed := time.Since(start); elapsed > 3*time.Second {
+			t.Errorf("Create() took %s; the hook should be killed on cancel", elapsed)
+		}
+		if got, err := os.ReadFile(filepath.Join("demo", "old.txt")); err != nil || string(got) != "old" {
+			t.Errorf("original directory should be intact, got %q, err = %v", got, err)
+		}
+		assertNoStaging(t, dir)
+	})
+}
+
+func TestCreate_Report(t *testing.T) {
+	fsys := fstest.MapFS{
+		"go/main.go.tmpl":   {Data: []byte("package main // {{.ProjectName}}\n")},
+		"go/bin/run":        {Data: []byte("#!/bin/sh\n"), Mode: 0755},
+		"go/template.yaml":  {Data: []byte("description: Go\n")},
+		"go/sub/plain.txt":  {Data: []byte("plain\n")},
+		"other/ignored.txt": {Data: []byte("x")},
+	}
+	want := []FileReport{
+		{Source: "go/bin", Dest: filepath.Join("demo", "bin"), Mode: fs.ModeDir | 0755},
+		{Source: "go/bin/run", Dest: filepath.Join("demo", "bin", "run"), Mode: 0755},
+		{Source: "go/main.go.tmpl", Dest: filepath.Join("demo", "main.go"), Mode: 0644, Rendered: true},
+		{Source: "go/sub", Dest: filepath.Join("demo", "sub"), Mode: fs.ModeDir | 0755},
+		{Source: "go/sub/plain.txt", Dest: filepath.Join("demo", "sub", "plain.txt"), Mode: 0644},
+	}
+
+	t.Run("dry run", func(t *testing.T) {
+		dir := chdirTemp(t)
+		var report Report
+		opts := Options{Lang: "go", ProjectName: "demo", DryRun: true, Report: &report}
+		if err := NewCreator(fsys, &bytes.Buffer{}).Create(context.Background(), opts); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		if !reflect.DeepEqual(report.Files, want) {
+			t.Errorf("report.Files = %+v, want %+v", report.Files, want)
+		}
+		if report.Commit != "" {
+			t.Errorf("report.Commit = %q, want none in a dry run", report.Commit)
+		}
+		assertNoStaging(t, dir)
+	})
+
+	t.Run("create", func(t *testing.T) {
+		requireGit(t)
+		chdirTemp(t)
+		var report Report
+		opts := Options{Lang: "go", ProjectName: "demo", Report: &report}
+		if err := NewCreator(fsys, &bytes.Buffer{}).Create(context.Background(), opts); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		if !reflect.DeepEqual(report.Files, want) {
+			t.Errorf("report.Files = %+v, want %+v", report.Files, want)
+		}
+		out, err := exec.Command("git", "-C", "demo", "rev-parse", "HEAD").Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if head := strings.TrimSpace(string(out)); report.Commit != head {
+			t.Errorf("report.Commit = %q, want HEAD %q", report.Commit, head)
+		}
+	})
+}
+
+func TestCreate_ErrorKinds(t *testing.T) {
+	fsys := fstest.MapFS{
+		"go/main.go":       {Data: []byte("package main\n")},
+		"go/template.yaml": {Data: []byte("variables:\n  - name: port\n    type: int\n")},
+	}
+	tests := []struct {
+		name  string
+		opts  Options
+		setup func(t *testing.T)
+		want  error
+	}{
+		{"invalid name", Options{Lang: "go", ProjectName: "1demo"}, nil, ErrInvalidName},
+		{"unsupported language", Options{Lang: "cobol", ProjectName: "demo"}, nil, ErrUnsupportedLang},
+		{"missing variable", Options{Lang: "go", ProjectName: "demo"}, nil, ErrInvalidVariable},
+		{"bad variable", Options{Lang: "go", ProjectName: "demo", Vars: map[string]any{"port": "x"}}, nil, ErrInvalidVariable},
+		{"existing destination", Options{Lang: "go", ProjectName: "demo", Vars: map[string]any{"port": 1}}, func(t *testing.T) {
+			if err := os.Mkdir("demo", 0755); err != nil {
+				t.Fatal(err)
+			}
+		}, ErrDestinationExists},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chdirTemp(t)
+			if tt.setup != nil {
+				tt.setup(t)
+			}
+			err := NewCreator(fsys, &bytes.Buffer{}).Create(context.Background(), tt.opts)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Create() error = %v, want errors.Is %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+// Package scaffoldtest runs the golden directory cases of package golden
+// from Go tests:
+//
+//	func TestTemplates(t *testing.T) {
+//		scaffoldtest.Run(t, creator, "go", "testdata/go")
+//	}
+//
+// Running the tests with -update rewrites the golden output instead.
+package scaffoldtest
+
+import (
+	"context"
+	"flag"
+	"testing"
+
+	"github.com/JackDrogon/project/pkg/scaffold"
+	"github.com/JackDrogon/project/pkg/scaffold/golden"
+)
+
+var update = flag.Bool("update", false, "rewrite scaffoldtest golden directories with the rendered output")
+
+// Run checks every case listed in the golden directory dir against
+// template lang, each as a subtest of t. With the -update test flag, it
+// rewrites the golden output instead.
+func Run(t *testing.T, creator *scaffold.Creator, lang, dir string) {
+	t.Helper()
+	cases, err := golden.LoadCases(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			mismatches, err := golden.Check(context.Background(), creator, lang, dir, c, *update)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, m := range mismatches {
+				t.Error(m)
+			}
+			if len(mismatches) > 0 {
+				t.Log("if the changes are intended, run the test with -update to refresh the golden output")
+			}
+		})
+	}
+}
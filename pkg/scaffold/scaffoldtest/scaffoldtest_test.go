@@ -0,0 +1,30 @@
+package scaffoldtest
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/JackDrogon/project/pkg/scaffold"
+	"github.com/JackDrogon/project/pkg/scaffold/golden"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	cases := "cases:\n  - name: default\n  - name: custom\n    project: api\n    year: 2024\n"
+	if err := os.WriteFile(filepath.Join(dir, golden.CasesFile), []byte(cases), 0644); err != nil {
+		t.Fatal(err)
+	}
+	creator := scaffold.NewCreator(fstest.MapFS{
+		"svc/main.go.tmpl": {Data: []byte("package main // {{.ProjectName}} {{.Year}}\n")},
+	}, io.Discard)
+	for _, c := range []golden.Case{{Name: "default"}, {Name: "custom", ProjectName: "api", Year: 2024}} {
+		if _, err := golden.Check(context.Background(), creator, "svc", dir, c, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	Run(t, creator, "svc", dir)
+}
@@ -0,0 +1,98 @@
+package scaffold
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// staging is a temporary directory next to a project's destination. The
+// project is rendered and initialized there, then moved into place by
+// commit, so a failure at any step leaves the destination untouched.
+type staging struct {
+	dest string
+	dir  string
+	done bool
+}
+
+// newStaging creates the staging directory beside dest, on the same
+// filesystem so commit can rename it into place. Unlike os.MkdirTemp, which
+// always uses 0700, it creates the directory 0777 less the umask, like
+// mkdir: the directory becomes the project root.
+func newStaging(dest string) (*staging, error) {
+	parent, base := filepath.Split(filepath.Clean(dest))
+	if parent == "" {
+		parent = "."
+	}
+	for try := 0; ; try++ {
+		dir := filepath.Join(parent, "."+base+".staging-"+strconv.FormatUint(uint64(rand.Uint32()), 10))
+		err := os.Mkdir(dir, 0777)
+		if err == nil {
+			return &staging{dest: dest, dir: dir}, nil
+		}
+		if !errors.Is(err, fs.ErrExist) || try == 10000 {
+			return nil, fmt.Errorf("failed to create staging directory: %w", err)
+		}
+	}
+}
+
+// commit moves the staged project to its destination. An existing
+// destination (only possible with --force) is set aside first and deleted
+// once the new project is in place; if the move fails it is restored.
+// settle, when non-nil, runs once the project is in place, before the old
+// destination is deleted; if it fails, the move is undone.
+func (s *staging) commit(settle func() error) error {
+	backup := ""
+	if _, err := os.Lstat(s.dest); err == nil {
+		backup = s.dir + ".orig"
+		if err := os.Rename(s.dest, backup); err != nil {
+			return fmt.Errorf("failed to move existing directory %q aside: %w", s.dest, err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to inspect destination %q: %w", s.dest, err)
+	}
+
+	if err := os.Rename(s.dir, s.dest); err != nil {
+		return s.restore(backup, fmt.Errorf("failed to move project into place: %w", err))
+	}
+	if settle != nil {
+		if err := settle(); err != nil {
+			if moveErr := os.Rename(s.dest, s.dir); moveErr != nil {
+				return fmt.Errorf("%w (project left at %q: %v)", err, s.dest, moveErr)
+			}
+			return s.restore(backup, err)
+		}
+	}
+	s.done = true
+
+	if backup != "" {
+		if err := os.RemoveAll(backup); err != nil {
+			return fmt.Errorf("failed to remove previous directory %q: %w", backup, err)
+		}
+	}
+	return nil
+}
+
+// restore moves the directory set aside as backup, if any, back to the
+// destination and returns err, noting where the directory was left if
+// that fails too.
+func (s *staging) restore(backup string, err error) error {
+	if backup == "" {
+		return err
+	}
+	if restoreErr := os.Rename(backup, s.dest); restoreErr != nil {
+		return fmt.Errorf("%w (original directory left at %q: %v)", err, backup, restoreErr)
+	}
+	return err
+}
+
+// cleanup removes the staging directory unless it was committed.
+func (s *staging) cleanup() {
+	if !s.done {
+		_ = os.RemoveAll(s.dir)
+	}
+}
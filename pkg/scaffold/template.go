@@ -2,57 +2,97 @@ package scaffold
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"io/fs"
 	"os"
 	"path"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"text/template"
 )
 
 const tmplSuffix = ".tmpl"
 
-// RenderTemplate applies TemplateVars to content using text/template.
+// RenderTemplate applies TemplateVars, including any Extra variables, to
+// content using text/template and the function library from templateFuncs.
 // It returns an error when template syntax is invalid or references unknown keys.
 func RenderTemplate(content []byte, vars TemplateVars) ([]byte, error) {
-	tmpl, err := template.New("").Option("missingkey=error").Parse(string(content))
+	tmpl, err := parseTemplate(string(content), vars)
 	if err != nil {
 		return nil, err
 	}
 	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, vars); err != nil {
+	if err := tmpl.Execute(&buf, vars.data()); err != nil {
+		if m := missingKeyRe.FindStringSubmatch(err.Error()); m != nil {
+			return nil, &UndefinedVarError{Name: m[1], Err: err}
+		}
 		return nil, err
 	}
 	return buf.Bytes(), nil
 }
 
+// parseTemplate parses text with the settings every template is rendered with.
+func parseTemplate(text string, vars TemplateVars) (*template.Template, error) {
+	return parseNamedTemplate("", text, vars)
+}
+
+// parseNamedTemplate is parseTemplate with a name for error messages, such
+// as the file the text came from.
+func parseNamedTemplate(name, text string, vars TemplateVars) (*template.Template, error) {
+	return template.New(name).Option("missingkey=error").Funcs(templateFuncs(vars)).Parse(text)
+}
+
+// missingKeyRe extracts the key from text/template's missingkey=error message.
+var missingKeyRe = regexp.MustCompile(`map has no entry for key "([^"]*)"`)
+
+// UndefinedVarError reports a template reference to a variable that is
+// neither built in, declared in the manifest, nor supplied by the user.
+type UndefinedVarError struct {
+	Name string
+	Err  error
+}
+
+func (e *UndefinedVarError) Error() string {
+	return fmt.Sprintf("template references undefined variable %q (supply it with --set %s=<value>): %v", e.Name, e.Name, e.Err)
+}
+
+func (e *UndefinedVarError) Unwrap() error { return e.Err }
+
 // CopyEmbedDir recursively copies a directory from an embedded filesystem
-// to the local filesystem, rendering template variables in file contents.
-func CopyEmbedDir(w io.Writer, fsys fs.FS, srcDir, destDir string, vars TemplateVars) error {
-	if err := os.MkdirAll(destDir, 0755); err != nil {
-		return err
-	}
+// to the local filesystem, rendering template variables in file contents
+// and in file and directory names. The template manifest at the root of
+// srcDir is not copied. Copying stops between entries once ctx is done.
+func CopyEmbedDir(ctx context.Context, w io.Writer, fsys fs.FS, srcDir, destDir string, vars TemplateVars) error {
+	return copyTree(ctx, w, fsys, srcDir, destDir, destDir, vars, nil)
+}
 
-	entries, err := fs.ReadDir(fsys, srcDir)
-	if err != nil {
+// copyTree is CopyEmbedDir writing into destDir while reporting paths under
+// displayDir, so a project built in a staging directory is reported under
+// its final name. Each entry is recorded in report, which may be nil.
+func copyTree(ctx context.Context, w io.Writer, fsys fs.FS, srcDir, destDir, displayDir string, vars TemplateVars, report *Report) error {
+	if err := os.MkdirAll(destDir, 0755); err != nil {
 		return err
 	}
+	return renderTree(ctx, w, fsys, srcDir, NewDiskFS(destDir), displayDir, vars, report)
+}
 
-	for _, entry := range entries {
-		// embed.FS always uses forward slashes
-		srcPath := path.Join(srcDir, entry.Name())
-		// Strip .tmpl suffix so "go.mod.tmpl" becomes "go.mod"
-		destName := strings.TrimSuffix(entry.Name(), tmplSuffix)
-		destPath := filepath.Join(destDir, destName)
-		_, _ = fmt.Fprintf(w, "  create %s\n", destPath)
+// renderTree renders the template at srcDir into dst, reporting each entry
+// under displayDir and recording it in report, which may be nil. It stops
+// between entries once ctx is done.
+func renderTree(ctx context.Context, w io.Writer, fsys fs.FS, srcDir string, dst WritableFS, displayDir string, vars TemplateVars, report *Report) error {
+	return walkTemplate(fsys, srcDir, "", vars, func(srcPath, rel string, entry fs.DirEntry) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(w, "  create %s\n", filepath.Join(displayDir, rel))
+		report.addFile(srcPath, filepath.Join(displayDir, rel), entry)
+		name := filepath.ToSlash(rel)
 
 		if entry.IsDir() {
-			if err := CopyEmbedDir(w, fsys, srcPath, destPath, vars); err != nil {
-				return err
-			}
-			continue
+			return dst.MkdirAll(name, 0755)
 		}
 
 		content, err := fs.ReadFile(fsys, srcPath)
@@ -61,48 +101,143 @@ func CopyEmbedDir(w io.Writer, fsys fs.FS, srcDir, destDir string, vars Template
 		}
 
 		rendered := content
-		if strings.HasSuffix(entry.Name(), tmplSuffix) {
+		if isTemplateFile(entry.Name()) {
 			rendered, err = RenderTemplate(content, vars)
 			if err != nil {
 				return fmt.Errorf("failed to render template %s: %w", srcPath, err)
 			}
 		}
 
-		mode := fs.FileMode(0644)
-		if info, err := entry.Info(); err == nil {
-			if perm := info.Mode().Perm(); perm != 0 {
-				mode = perm
-			}
-		}
+		return dst.WriteFile(name, rendered, fileMode(entry))
+	})
+}
 
-		if err := os.WriteFile(destPath, rendered, mode); err != nil {
-			return err
+// PreviewEmbedDir prints what files would be created without writing
+// anything. Names are rendered exactly as CopyEmbedDir would render them.
+func PreviewEmbedDir(w io.Writer, fsys fs.FS, srcDir, destDir string, vars TemplateVars) error {
+	return previewTree(w, fsys, srcDir, destDir, vars, nil)
+}
+
+// previewTree is PreviewEmbedDir also recording each entry in report, which
+// may be nil.
+func previewTree(w io.Writer, fsys fs.FS, srcDir, destDir string, vars TemplateVars, report *Report) error {
+	return walkTemplate(fsys, srcDir, destDir, vars, func(srcPath, destPath string, entry fs.DirEntry) error {
+		report.addFile(srcPath, destPath, entry)
+		if entry.IsDir() {
+			_, _ = fmt.Fprintf(w, "  create %s/\n", destPath)
+		} else {
+			_, _ = fmt.Fprintf(w, "  create %s\n", destPath)
 		}
+		return nil
+	})
+}
+
+// walkFunc is called for each template entry with its source path in fsys
+// and its rendered destination path. Directories are visited before their
+// contents.
+type walkFunc func(srcPath, destPath string, entry fs.DirEntry) error
+
+// walkTemplate visits every entry under srcDir in lexical order, skipping
+// the root manifest and anything excluded by its file rules, and mapping
+// each name to its destination. Two entries mapping to the same destination
+// are an error rather than one silently replacing the other.
+func walkTemplate(fsys fs.FS, srcDir, destDir string, vars TemplateVars, fn walkFunc) error {
+	manifest, err := LoadManifest(fsys, srcDir)
+	if err != nil {
+		return err
 	}
-	return nil
+	w := &walker{fsys: fsys, rules: manifest.Files, vars: vars, fn: fn, sources: map[string]string{}}
+	return w.walkDir(srcDir, "", destDir)
 }
 
-// PreviewEmbedDir prints what files would be created without writing anything.
-func PreviewEmbedDir(w io.Writer, fsys fs.FS, srcDir, destDir string) error {
-	entries, err := fs.ReadDir(fsys, srcDir)
+type walker struct {
+	fsys  fs.FS
+	rules []FileRule
+	vars  TemplateVars
+	fn    walkFunc
+	// sources maps each destination visited so far to its template entry.
+	sources map[string]string
+}
+
+// walkDir visits srcDir, whose path relative to the template root is rel.
+func (w *walker) walkDir(srcDir, rel, destDir string) error {
+	entries, err := fs.ReadDir(w.fsys, srcDir)
 	if err != nil {
 		return err
 	}
 
 	for _, entry := range entries {
+		if rel == "" && entry.Name() == manifestName {
+			continue
+		}
+		// embed.FS always uses forward slashes
 		srcPath := path.Join(srcDir, entry.Name())
-		destName := strings.TrimSuffix(entry.Name(), tmplSuffix)
+
+		entryRel := path.Join(rel, entry.Name())
+		match := entryRel
+		if !entry.IsDir() {
+			match = strings.TrimSuffix(match, tmplSuffix)
+		}
+		include, err := includeEntry(w.rules, match, entry.IsDir(), w.vars)
+		if err != nil {
+			return err
+		}
+		if !include {
+			continue
+		}
+
+		destName, err := renderName(entry.Name(), entry.IsDir(), w.vars)
+		if err != nil {
+			return fmt.Errorf("failed to render name %s: %w", srcPath, err)
+		}
 		destPath := filepath.Join(destDir, destName)
+		if prev, ok := w.sources[destPath]; ok {
+			return fmt.Errorf("%s and %s both render to %s", prev, srcPath, destPath)
+		}
+		w.sources[destPath] = srcPath
 
+		if err := w.fn(srcPath, destPath, entry); err != nil {
+			return err
+		}
 		if entry.IsDir() {
-			_, _ = fmt.Fprintf(w, "  create %s/\n", destPath)
-			if err := PreviewEmbedDir(w, fsys, srcPath, destPath); err != nil {
+			if err := w.walkDir(srcPath, entryRel, destPath); err != nil {
 				return err
 			}
-			continue
 		}
-
-		_, _ = fmt.Fprintf(w, "  create %s\n", destPath)
 	}
 	return nil
 }
+
+// isTemplateFile reports whether the file name marks its content as a
+// template to render.
+func isTemplateFile(name string) bool {
+	return strings.HasSuffix(name, tmplSuffix)
+}
+
+// renderName maps a template entry name to its destination name. The .tmpl
+// suffix is stripped from files so "go.mod.tmpl" becomes "go.mod", and
+// names containing template actions, like "{{.ProjectName}}.h", are
+// rendered. A rendered name must be a single, non-empty path element.
+func renderName(name string, isDir bool, vars TemplateVars) (string, error) {
+	if !isDir {
+		name = strings.TrimSuffix(name, tmplSuffix)
+	}
+	if !strings.Contains(name, "{{") {
+		return name, nil
+	}
+
+	rendered, err := RenderTemplate([]byte(name), vars)
+	if err != nil {
+		return "", err
+	}
+	result := string(rendered)
+	switch {
+	case strings.TrimSpace(result) == "":
+		return "", fmt.Errorf("%q renders to an empty name", name)
+	case strings.ContainsAny(result, `/\`):
+		return "", fmt.Errorf("%q renders to %q, which contains a path separator", name, result)
+	case result == "." || result == "..":
+		return "", fmt.Errorf("%q renders to %q, which escapes the destination", name, result)
+	}
+	return result, nil
+}
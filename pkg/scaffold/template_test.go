@@ -2,8 +2,11 @@ package scaffold
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"testing/fstest"
 )
@@ -98,7 +101,7 @@ func TestCopyEmbedDir(t *testing.T) {
 	dest := filepath.Join(destDir, "output")
 
 	var buf bytes.Buffer
-	if err := CopyEmbedDir(&buf, fsys, "lang", dest, vars); err != nil {
+	if err := CopyEmbedDir(context.Background(), &buf, fsys, "lang", dest, vars); err != nil {
 		t.Fatalf("CopyEmbedDir() error = %v", err)
 	}
 
@@ -144,6 +147,51 @@ func TestCopyEmbedDir(t *testing.T) {
 	}
 }
 
+func TestRenderTemplate_ExtraVars(t *testing.T) {
+	vars := TemplateVars{
+		ProjectName: "demo",
+		Extra:       map[string]any{"license": "MIT", "docker": true},
+	}
+
+	got, err := RenderTemplate([]byte("{{.ProjectName}} {{.license}}{{if .docker}} docker{{end}}"), vars)
+	if err != nil {
+		t.Fatalf("RenderTemplate() error = %v", err)
+	}
+	if string(got) != "demo MIT docker" {
+		t.Errorf("RenderTemplate() = %q, want %q", got, "demo MIT docker")
+	}
+}
+
+func TestRenderTemplate_UndefinedVarError(t *testing.T) {
+	_, err := RenderTemplate([]byte("{{.company}}"), TemplateVars{ProjectName: "demo"})
+	var undefined *UndefinedVarError
+	if !errors.As(err, &undefined) {
+		t.Fatalf("RenderTemplate() error = %v, want UndefinedVarError", err)
+	}
+	if undefined.Name != "company" {
+		t.Errorf("UndefinedVarError.Name = %q, want %q", undefined.Name, "company")
+	}
+}
+
+func TestCopyEmbedDir_SkipsRootManifest(t *testing.T) {
+	fsys := fstest.MapFS{
+		"lang/template.yaml":     {Data: []byte("description: test")},
+		"lang/sub/template.yaml": {Data: []byte("kept")},
+	}
+
+	dest := filepath.Join(t.TempDir(), "output")
+	if err := CopyEmbedDir(context.Background(), &bytes.Buffer{}, fsys, "lang", dest, TemplateVars{ProjectName: "demo"}); err != nil {
+		t.Fatalf("CopyEmbedDir() error = %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dest, "template.yaml")); err == nil {
+		t.Error("root template.yaml should not be copied")
+	}
+	if _, err := os.Stat(filepath.Join(dest, "sub", "template.yaml")); err != nil {
+		t.Errorf("nested template.yaml should be copied: %v", err)
+	}
+}
+
 func TestCopyEmbedDir_TargetDirConflict(t *testing.T) {
 	fsys := fstest.MapFS{
 		"lang/file.txt": {Data: []byte("content")},
@@ -159,7 +207,7 @@ func TestCopyEmbedDir_TargetDirConflict(t *testing.T) {
 	// CopyEmbedDir itself doesn't check for conflicts (Creator.Create does),
 	// but it should still work when the directory exists
 	var buf bytes.Buffer
-	if err := CopyEmbedDir(&buf, fsys, "lang", dest, vars); err != nil {
+	if err := CopyEmbedDir(context.Background(), &buf, fsys, "lang", dest, vars); err != nil {
 		t.Fatalf("CopyEmbedDir() error = %v", err)
 	}
 }
@@ -172,7 +220,7 @@ func TestCopyEmbedDir_InvalidTemplateFails(t *testing.T) {
 
 	dest := filepath.Join(t.TempDir(), "output")
 	var buf bytes.Buffer
-	err := CopyEmbedDir(&buf, fsys, "lang", dest, vars)
+	err := CopyEmbedDir(context.Background(), &buf, fsys, "lang", dest, vars)
 	if err == nil {
 		t.Fatal("CopyEmbedDir() expected error for invalid .tmpl file, got nil")
 	}
@@ -186,7 +234,7 @@ func TestCopyEmbedDir_NonTemplateFileBypassesRendering(t *testing.T) {
 
 	dest := filepath.Join(t.TempDir(), "output")
 	var buf bytes.Buffer
-	if err := CopyEmbedDir(&buf, fsys, "lang", dest, vars); err != nil {
+	if err := CopyEmbedDir(context.Background(), &buf, fsys, "lang", dest, vars); err != nil {
 		t.Fatalf("CopyEmbedDir() error = %v", err)
 	}
 
@@ -210,7 +258,7 @@ func TestCopyEmbedDir_PreservesExecutableBit(t *testing.T) {
 
 	dest := filepath.Join(t.TempDir(), "output")
 	var buf bytes.Buffer
-	if err := CopyEmbedDir(&buf, fsys, "lang", dest, vars); err != nil {
+	if err := CopyEmbedDir(context.Background(), &buf, fsys, "lang", dest, vars); err != nil {
 		t.Fatalf("CopyEmbedDir() error = %v", err)
 	}
 
@@ -222,3 +270,108 @@ func TestCopyEmbedDir_PreservesExecutableBit(t *testing.T) {
 		t.Errorf("script.sh mode = %o, want %o", info.Mode().Perm(), 0755)
 	}
 }
+
+func TestCopyEmbedDir_TemplatedNames(t *testing.T) {
+	fsys := fstest.MapFS{
+		"lang/cmd/{{.ProjectName}}/main.go.tmpl":                {Data: []byte("package main // {{.ProjectName}}")},
+		"lang/include/{{.ProjectName}}/{{.ProjectName}}.h.tmpl": {Data: []byte("#pragma once")},
+		"lang/{{.ProjectName | upper}}.md":                      {Data: []byte("raw {{.ProjectName}}")},
+	}
+	vars := TemplateVars{ProjectName: "demo"}
+
+	dest := filepath.Join(t.TempDir(), "output")
+	if err := CopyEmbedDir(context.Background(), &bytes.Buffer{}, fsys, "lang", dest, vars); err != nil {
+		t.Fatalf("CopyEmbedDir() error = %v", err)
+	}
+
+	want := map[string]string{
+		"cmd/demo/main.go":    "package main // demo",
+		"include/demo/demo.h": "#pragma once",
+		"DEMO.md":             "raw {{.ProjectName}}",
+	}
+	for name, content := range want {
+		got, err := os.ReadFile(filepath.Join(dest, filepath.FromSlash(name)))
+		if err != nil {
+			t.Errorf("read %s: %v", name, err)
+			continue
+		}
+		if string(got) != content {
+			t.Errorf("%s = %q, want %q", name, got, content)
+		}
+	}
+}
+
+func TestCopyEmbedDir_InvalidTemplatedNames(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		vars TemplateVars
+	}{
+		{"empty", "lang/{{.Author}}.tmpl", TemplateVars{ProjectName: "demo"}},
+		{"empty directory", "lang/{{.Author}}/file.txt", TemplateVars{ProjectName: "demo"}},
+		{"separator", "lang/{{.ModulePath}}.txt", TemplateVars{ProjectName: "demo", ModulePath: "a/b"}},
+		{"backslash", "lang/{{.ModulePath}}.txt", TemplateVars{ProjectName: "demo", ModulePath: `a\b`}},
+		{"parent", "lang/{{.ModulePath}}/file.txt", TemplateVars{ProjectName: "demo", ModulePath: ".."}},
+		{"undefined variable", "lang/{{.missing}}.txt", TemplateVars{ProjectName: "demo"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fstest.MapFS{tt.file: {Data: []byte("x")}}
+			tmp := t.TempDir()
+			dest := filepath.Join(tmp, "output")
+
+			if err := CopyEmbedDir(context.Background(), &bytes.Buffer{}, fsys, "lang", dest, tt.vars); err == nil {
+				t.Fatal("CopyEmbedDir() expected error, got nil")
+			}
+			if _, err := os.Stat(filepath.Join(tmp, "b.txt")); err == nil {
+				t.Error("rendered name escaped the destination")
+			}
+		})
+	}
+}
+
+func TestCopyEmbedDir_DuplicateNames(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+	}{
+		{"rendered names", fstest.MapFS{
+			"lang/{{.a}}.go": {Data: []byte("a")},
+			"lang/{{.b}}.go": {Data: []byte("b")},
+		}},
+		{"tmpl suffix", fstest.MapFS{
+			"lang/x":      {Data: []byte("raw")},
+			"lang/x.tmpl": {Data: []byte("rendered")},
+		}},
+	}
+	vars := TemplateVars{ProjectName: "demo", Extra: map[string]any{"a": "same", "b": "same"}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := filepath.Join(t.TempDir(), "output")
+			err := CopyEmbedDir(context.Background(), &bytes.Buffer{}, tt.fsys, "lang", dest, vars)
+			if err == nil || !strings.Contains(err.Error(), "both render to") {
+				t.Fatalf("CopyEmbedDir() error = %v, want a duplicate destination", err)
+			}
+		})
+	}
+}
+
+func TestPreviewEmbedDir_RendersNames(t *testing.T) {
+	fsys := fstest.MapFS{
+		"lang/template.yaml":                {Data: []byte("description: x")},
+		"lang/src/{{.ProjectName}}.cc.tmpl": {Data: []byte("")},
+	}
+
+	var buf bytes.Buffer
+	if err := PreviewEmbedDir(&buf, fsys, "lang", "out", TemplateVars{ProjectName: "demo"}); err != nil {
+		t.Fatalf("PreviewEmbedDir() error = %v", err)
+	}
+
+	want := "  create " + filepath.Join("out", "src") + "/\n" +
+		"  create " + filepath.Join("out", "src", "demo.cc") + "\n"
+	if buf.String() != want {
+		t.Errorf("PreviewEmbedDir() output = %q, want %q", buf.String(), want)
+	}
+}
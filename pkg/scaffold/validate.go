@@ -1,8 +1,11 @@
 package scaffold
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
+	"strings"
+	"unicode"
 )
 
 // validProjectName matches names starting with a letter, containing only
@@ -14,13 +17,39 @@ const maxProjectNameLen = 255
 // ValidateProjectName checks that name is a safe, valid project/directory name.
 func ValidateProjectName(name string) error {
 	if name == "" {
-		return fmt.Errorf("project name must not be empty")
+		return markErr(errors.New("project name must not be empty"), ErrInvalidName)
 	}
 	if len(name) > maxProjectNameLen {
-		return fmt.Errorf("project name must be at most %d characters, got %d", maxProjectNameLen, len(name))
+		return markErr(fmt.Errorf("project name must be at most %d characters, got %d", maxProjectNameLen, len(name)), ErrInvalidName)
 	}
 	if !validProjectName.MatchString(name) {
-		return fmt.Errorf("project name %q is invalid: must start with a letter and contain only [a-zA-Z0-9._-]", name)
+		msg := fmt.Sprintf("project name %q is invalid: must start with a letter and contain only [a-zA-Z0-9._-]", name)
+		if s := suggestProjectName(name); s != "" {
+			msg += fmt.Sprintf("; try %q", s)
+		}
+		return markErr(errors.New(msg), ErrInvalidName)
 	}
 	return nil
 }
+
+// suggestProjectName derives a valid name from an invalid one by turning
+// other characters into '-' and dropping what precedes the first letter,
+// so "my app" becomes "my-app" and "2fa tool" "fa-tool". It returns "" if
+// nothing is left.
+func suggestProjectName(name string) string {
+	s := strings.Map(func(r rune) rune {
+		if r < 0x80 && (unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("._-", r)) {
+			return r
+		}
+		return '-'
+	}, name)
+	s = strings.TrimLeftFunc(s, func(r rune) bool { return !(r < 0x80 && unicode.IsLetter(r)) })
+	s = strings.TrimRight(collapse(s, "-"), "-")
+	if len(s) > maxProjectNameLen {
+		s = s[:maxProjectNameLen]
+	}
+	if !validProjectName.MatchString(s) {
+		return ""
+	}
+	return s
+}
@@ -48,3 +48,17 @@ func TestValidateProjectName(t *testing.T) {
 		})
 	}
 }
+
+func TestSuggestProjectName(t *testing.T) {
+	tests := map[string]string{
+		"my project": "my-project",
+		"2fa tool":   "fa-tool",
+		"my@@app!":   "my-app",
+		"123":        "",
+	}
+	for name, want := range tests {
+		if got := suggestProjectName(name); got != want {
+			t.Errorf("suggestProjectName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
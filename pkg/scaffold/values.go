@@ -0,0 +1,108 @@
+package scaffold
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RawValue is a variable value given on the command line. It is converted
+// to the type the template manifest declares for it, or, for variables the
+// manifest does not declare, to the type inferValue guesses.
+type RawValue string
+
+// ParseSetValues parses repeated key=value pairs into a variables map of
+// RawValues, so "--set code=007" stays "007" for a string variable.
+func ParseSetValues(pairs []string) (map[string]any, error) {
+	values := make(map[string]any, len(pairs))
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid value %q: expected key=value", pair)
+		}
+		if !validVarName.MatchString(key) {
+			return nil, fmt.Errorf("invalid variable name %q: must be a template identifier", key)
+		}
+		values[key] = RawValue(value)
+	}
+	return values, nil
+}
+
+// inferValue coerces an untyped command-line value: "true"/"false" become
+// bools, integers become ints, "[a, b]" becomes a list, and anything else
+// stays a string.
+func inferValue(s string) any {
+	switch s {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		return n
+	}
+	if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+		return parseList(s)
+	}
+	return s
+}
+
+// LoadValuesFile reads template variables from a YAML or JSON file. The
+// format is chosen by extension; anything other than .json is read as YAML.
+func LoadValuesFile(name string) (map[string]any, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read values file: %w", err)
+	}
+
+	values := make(map[string]any)
+	if strings.EqualFold(filepath.Ext(name), ".json") {
+		dec := json.NewDecoder(strings.NewReader(string(data)))
+		dec.UseNumber()
+		if err := dec.Decode(&values); err != nil {
+			return nil, fmt.Errorf("invalid values file %s: %w", name, err)
+		}
+		for key, value := range values {
+			values[key] = normalizeJSON(value)
+		}
+	} else if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("invalid values file %s: %w", name, err)
+	}
+
+	for key := range values {
+		if !validVarName.MatchString(key) {
+			return nil, fmt.Errorf("invalid variable name %q in %s: must be a template identifier", key, name)
+		}
+	}
+	return values, nil
+}
+
+// normalizeJSON converts json.Number values to int where they are whole
+// numbers so JSON and YAML files yield the same types.
+func normalizeJSON(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		if n, err := strconv.Atoi(v.String()); err == nil {
+			return n
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	case []any:
+		for i := range v {
+			v[i] = normalizeJSON(v[i])
+		}
+	case map[string]any:
+		for key := range v {
+			v[key] = normalizeJSON(v[key])
+		}
+	}
+	return value
+}
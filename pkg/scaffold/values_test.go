@@ -0,0 +1,90 @@
+package scaffold
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseSetValues(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   []string
+		want    map[string]any
+		wantErr bool
+	}{
+		{"string", []string{"company=Acme Inc"}, map[string]any{"company": RawValue("Acme Inc")}, false},
+		{"bool stays raw", []string{"docker=true"}, map[string]any{"docker": RawValue("true")}, false},
+		{"int stays raw", []string{"code=007"}, map[string]any{"code": RawValue("007")}, false},
+		{"value with equals", []string{"expr=a=b"}, map[string]any{"expr": RawValue("a=b")}, false},
+		{"empty value", []string{"license="}, map[string]any{"license": RawValue("")}, false},
+		{"later wins", []string{"a=1", "a=2"}, map[string]any{"a": RawValue("2")}, false},
+		{"missing equals", []string{"license"}, nil, true},
+		{"empty key", []string{"=x"}, nil, true},
+		{"invalid key", []string{"my-key=x"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSetValues(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("ParseSetValues() expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseSetValues() unexpected error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSetValues() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadValuesFile(t *testing.T) {
+	want := map[string]any{
+		"company": "Acme",
+		"docker":  true,
+		"port":    8080,
+		"tags":    []any{"a", "b"},
+	}
+
+	files := map[string]string{
+		"vars.yaml": "company: Acme\ndocker: true\nport: 8080\ntags: [a, b]\n",
+		"vars.json": `{"company": "Acme", "docker": true, "port": 8080, "tags": ["a", "b"]}`,
+	}
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := LoadValuesFile(path)
+			if err != nil {
+				t.Fatalf("LoadValuesFile() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("LoadValuesFile() = %#v, want %#v", got, want)
+			}
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		if _, err := LoadValuesFile(filepath.Join(t.TempDir(), "nope.yaml")); err == nil {
+			t.Fatal("LoadValuesFile() expected error, got nil")
+		}
+	})
+
+	t.Run("invalid key", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "vars.yaml")
+		if err := os.WriteFile(path, []byte("my-key: x\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadValuesFile(path); err == nil {
+			t.Fatal("LoadValuesFile() expected error, got nil")
+		}
+	})
+}
@@ -10,7 +10,16 @@ type TemplateVars struct {
 	ProjectName string
 	ModulePath  string
 	Author      string
+	AuthorEmail string
 	Year        int
+
+	// Extra holds custom variables declared by the template manifest or
+	// supplied by the user. They are rendered alongside the built-ins.
+	Extra map[string]any
+
+	// AllowEnv enables the env template function. It is not itself
+	// visible to templates.
+	AllowEnv bool
 }
 
 // NewTemplateVars creates a TemplateVars with sensible defaults.
@@ -31,3 +40,30 @@ func NewTemplateVars(projectName, modulePath string) TemplateVars {
 		Year:        time.Now().Year(),
 	}
 }
+
+// builtinVars lists the variable names TemplateVars always provides.
+var builtinVars = []string{"ProjectName", "ModulePath", "Author", "AuthorEmail", "Year"}
+
+func isBuiltinVar(name string) bool {
+	for _, b := range builtinVars {
+		if b == name {
+			return true
+		}
+	}
+	return false
+}
+
+// data returns the flat map templates are executed against: the built-in
+// fields plus every Extra entry.
+func (v TemplateVars) data() map[string]any {
+	m := make(map[string]any, len(builtinVars)+len(v.Extra))
+	for name, value := range v.Extra {
+		m[name] = value
+	}
+	m["ProjectName"] = v.ProjectName
+	m["ModulePath"] = v.ModulePath
+	m["Author"] = v.Author
+	m["AuthorEmail"] = v.AuthorEmail
+	m["Year"] = v.Year
+	return m
+}
@@ -0,0 +1,149 @@
+package scaffold
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// defaultCheckTimeout bounds a check that does not set its own timeout.
+const defaultCheckTimeout = 10 * time.Minute
+
+// Check is a command that verifies a generated project builds, declared
+// under verify in the template manifest. Like a hook, Run is not rendered;
+// it runs in the project directory with the template variables in its
+// environment. Requires lists the programs Run needs; when any of
+// them is not on PATH the check is skipped instead of failed, so templates
+// can be verified on machines with only some toolchains installed.
+type Check struct {
+	Name     string        `yaml:"name"`
+	Run      string        `yaml:"run"`
+	Requires []string      `yaml:"requires"`
+	Timeout  time.Duration `yaml:"timeout"`
+}
+
+func (c Check) validate() error {
+	if strings.TrimSpace(c.Run) == "" {
+		return errors.New("verify check has an empty run command")
+	}
+	if c.Timeout < 0 {
+		return fmt.Errorf("verify check %q has a negative timeout", c.Run)
+	}
+	for _, program := range c.Requires {
+		if strings.TrimSpace(program) == "" {
+			return fmt.Errorf("verify check %q requires an empty program name", c.Run)
+		}
+	}
+	return checkNoTemplate("verify check", c.Run)
+}
+
+func (c Check) timeout() time.Duration {
+	if c.Timeout == 0 {
+		return defaultCheckTimeout
+	}
+	return c.Timeout
+}
+
+// missing returns the programs in Requires that are not on PATH.
+func (c Check) missing() []string {
+	var missing []string
+	for _, program := range c.Requires {
+		if _, err := exec.LookPath(program); err != nil {
+			missing = append(missing, program)
+		}
+	}
+	return missing
+}
+
+// CheckStatus is the outcome of a verify check.
+type CheckStatus string
+
+const (
+	CheckPassed  CheckStatus = "passed"
+	CheckFailed  CheckStatus = "failed"
+	CheckSkipped CheckStatus = "skipped"
+)
+
+// CheckResult reports how a verify check went.
+type CheckResult struct {
+	Name    string // the check's name, or its command when it has none
+	Command string // the command run
+	Status  CheckStatus
+	Missing []string // required programs not on PATH, for skipped checks
+	Err     error    // why the check failed
+}
+
+// Verify generates the project described by opts in a temporary
+// directory, running the template's hooks unless opts.NoHooks is set, and
+// then runs each check the template declares, streaming their output to
+// the Creator's writer. git is not initialized and the directory is
+// removed afterwards. If every check would be skipped, nothing is
+// generated. The error reports a failure to generate the project; checks
+// that fail are reported in the results.
+func (c *Creator) Verify(ctx context.Context, opts Options) ([]CheckResult, error) {
+	p := newPipeline(ctx, opts).step(c.validate).step(c.checkLang).step(c.checkNames).step(c.checkVars)
+	if p.Err() != nil {
+		return nil, p.Err()
+	}
+	manifest, err := LoadManifest(c.fsys, opts.Lang)
+	if err != nil {
+		return nil, err
+	}
+	vars, err := c.templateVars(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]CheckResult, len(manifest.Verify))
+	runnable := false
+	for i, check := range manifest.Verify {
+		results[i] = CheckResult{Name: check.Name, Command: check.Run, Missing: check.missing()}
+		if results[i].Name == "" {
+			results[i].Name = results[i].Command
+		}
+		if len(results[i].Missing) > 0 {
+			results[i].Status = CheckSkipped
+		} else {
+			runnable = true
+		}
+	}
+	if !runnable {
+		return results, nil
+	}
+
+	tmp, err := os.MkdirTemp("", "project-verify-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	p.opts.workDir = filepath.Join(tmp, opts.ProjectName)
+	p.step(func(_ context.Context, opts Options) error { return os.Mkdir(opts.dir(), 0755) }).
+		step(c.preHooks).step(c.copyTemplates).step(c.postHooks)
+	if err := p.Err(); err != nil {
+		return nil, err
+	}
+
+	env := append(os.Environ(), hookEnv(vars)...)
+	for i, check := range manifest.Verify {
+		r := &results[i]
+		if r.Status == CheckSkipped {
+			_, _ = fmt.Fprintf(c.w, "  skip %s (%s not found)\n", r.Command, strings.Join(r.Missing, ", "))
+			continue
+		}
+		_, _ = fmt.Fprintf(c.w, "  run %s\n", r.Command)
+		r.Status = CheckPassed
+		if err := runHook(ctx, c.w, r.Command, check.timeout(), p.opts.workDir, env); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			r.Status, r.Err = CheckFailed, err
+		}
+	}
+	return results, nil
+}
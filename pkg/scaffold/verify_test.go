@@ -0,0 +1,121 @@
+package scaffold
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestParseManifest_Verify(t *testing.T) {
+	m, err := ParseManifest([]byte(`
+verify:
+  - run: go build ./...
+    requires: [go]
+  - name: configure
+    run: cmake -S . -B build
+    requires: [cmake, c++]
+    timeout: 2m
+`))
+	if err != nil {
+		t.Fatalf("ParseManifest() error = %v", err)
+	}
+	want := []Check{
+		{Run: "go build ./...", Requires: []string{"go"}},
+		{Name: "configure", Run: "cmake -S . -B build", Requires: []string{"cmake", "c++"}, Timeout: 2 * time.Minute},
+	}
+	if !reflect.DeepEqual(m.Verify, want) {
+		t.Errorf("verify = %+v, want %+v", m.Verify, want)
+	}
+	if m.Verify[0].timeout() != defaultCheckTimeout {
+		t.Errorf("default check timeout = %v, want %v", m.Verify[0].timeout(), defaultCheckTimeout)
+	}
+
+	for name, yaml := range map[string]string{
+		"empty run":        "verify:\n  - run: ' '\n",
+		"negative timeout": "verify:\n  - run: ls\n    timeout: -1s\n",
+		"template syntax":  "verify:\n  - run: 'echo {{.ProjectName}}'\n",
+		"empty program":    "verify:\n  - run: ls\n    requires: ['']\n",
+	} {
+		if _, err := ParseManifest([]byte(yaml)); err == nil {
+			t.Errorf("%s: ParseManifest() expected error, got nil", name)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	requireSh(t)
+	fsys := fstest.MapFS{
+		"sh/template.yaml": {Data: []byte(`
+hooks:
+  post:
+    - run: echo generated > hook.txt
+verify:
+  - name: files
+    run: test -f "$PROJECT_NAME.txt" && test -f hook.txt
+    requires: [sh]
+  - run: test "$PROJECT_NAME" = nope
+  - run: echo never
+    requires: [no-such-program-xyz]
+`)},
+		"sh/{{.ProjectName}}.txt": {Data: []byte("hi\n")},
+	}
+	var out bytes.Buffer
+	c := NewCreator(fsys, &out)
+	results, err := c.Verify(context.Background(), Options{Lang: "sh", ProjectName: "demo"})
+	if err != nil {
+		t.Fatalf("Verify() error = %v\n%s", err, out.String())
+	}
+
+	var got []string
+	for _, r := range results {
+		got = append(got, r.Name+" "+string(r.Status))
+	}
+	want := []string{"files passed", `test "$PROJECT_NAME" = nope failed`, "echo never skipped"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Verify() results = %v, want %v\n%s", got, want, out.String())
+	}
+	if results[1].Err == nil {
+		t.Error("failed check should carry its error")
+	}
+	if !reflect.DeepEqual(results[2].Missing, []string{"no-such-program-xyz"}) {
+		t.Errorf("skipped check missing = %v", results[2].Missing)
+	}
+	if !strings.Contains(out.String(), "skip echo never (no-such-program-xyz not found)") {
+		t.Errorf("output should mention the skipped check, got:\n%s", out.String())
+	}
+}
+
+func TestVerify_AllSkipped(t *testing.T) {
+	fsys := fstest.MapFS{
+		"x/template.yaml": {Data: []byte(`
+hooks:
+  pre:
+    - run: exit 1
+verify:
+  - run: build
+    requires: [no-such-program-xyz]
+`)},
+	}
+	results, err := NewCreator(fsys, &bytes.Buffer{}).Verify(context.Background(), Options{Lang: "x", ProjectName: "demo"})
+	if err != nil {
+		t.Fatalf("Verify() error = %v; nothing should be generated when every check is skipped", err)
+	}
+	if len(results) != 1 || results[0].Status != CheckSkipped {
+		t.Errorf("Verify() results = %+v, want one skipped check", results)
+	}
+}
+
+func TestVerify_GenerationFails(t *testing.T) {
+	requireSh(t)
+	fsys := fstest.MapFS{
+		"x/template.yaml": {Data: []byte("hooks:\n  post:\n    - run: exit 3\nverify:\n  - run: 'true'\n")},
+	}
+	_, err := NewCreator(fsys, &bytes.Buffer{}).Verify(context.Background(), Options{Lang: "x", ProjectName: "demo"})
+	if err == nil || !strings.Contains(err.Error(), "post hook") {
+		t.Errorf("Verify() error = %v, want the failed post hook", err)
+	}
+}
@@ -0,0 +1,280 @@
+package source
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/JackDrogon/project/pkg/scaffold"
+)
+
+// Limits on what an archive template may expand to, so a corrupt or
+// hostile archive cannot exhaust memory.
+const (
+	DefaultMaxArchiveSize  = 64 << 20 // total uncompressed bytes
+	DefaultMaxArchiveFiles = 10000    // files and directories
+)
+
+// Archive is a template packaged as a local .tar, .tar.gz, .tgz, or .zip
+// file. It is extracted into memory when opened; entries that would land
+// outside the template, links, and special files are rejected. When every
+// entry is under a single top-level directory, that directory is the
+// template.
+type Archive struct {
+	Path     string
+	MaxSize  int64 // zero means DefaultMaxArchiveSize
+	MaxFiles int   // zero means DefaultMaxArchiveFiles
+}
+
+// archiveExts maps file extensions to the archive format they hold.
+var archiveExts = []struct {
+	ext    string
+	format scaffold.ArchiveFormat
+}{
+	{".tar.gz", scaffold.ArchiveTgz},
+	{".tgz", scaffold.ArchiveTgz},
+	{".tar", scaffold.ArchiveTar},
+	{".zip", scaffold.ArchiveZip},
+}
+
+// archiveFormat returns the format of the archive file name by its
+// extension.
+func archiveFormat(name string) (scaffold.ArchiveFormat, bool) {
+	_, format, ok := splitArchiveExt(name)
+	return format, ok
+}
+
+// splitArchiveExt splits a known archive extension off name.
+func splitArchiveExt(name string) (string, scaffold.ArchiveFormat, bool) {
+	lower := strings.ToLower(name)
+	for _, e := range archiveExts {
+		if strings.HasSuffix(lower, e.ext) {
+			return name[:len(name)-len(e.ext)], e.format, true
+		}
+	}
+	return name, "", false
+}
+
+// String returns the archive's path.
+func (a *Archive) String() string { return a.Path }
+
+// Name returns the archive's file name without its extension.
+func (a *Archive) Name() string {
+	base, _, _ := splitArchiveExt(filepath.Base(a.Path))
+	return base
+}
+
+// Open implements Source by extracting the archive into memory. Archives
+// are read in place, so opts is unused.
+func (a *Archive) Open(ctx context.Context, _ Options) (fs.FS, error) {
+	format, ok := archiveFormat(a.Path)
+	if !ok {
+		return nil, fmt.Errorf("template archive %s: unknown format; use .tar, .tar.gz, .tgz, or .zip", a.Path)
+	}
+	f, err := os.Open(a.Path)
+	if err != nil {
+		return nil, fmt.Errorf("template archive: %w", err)
+	}
+	defer f.Close()
+
+	x := &extractor{ctx: ctx, out: scaffold.NewMemFS(), maxSize: a.MaxSize, maxFiles: a.MaxFiles}
+	if x.maxSize <= 0 {
+		x.maxSize = DefaultMaxArchiveSize
+	}
+	if x.maxFiles <= 0 {
+		x.maxFiles = DefaultMaxArchiveFiles
+	}
+
+	switch format {
+	case scaffold.ArchiveTar:
+		err = x.tar(f)
+	case scaffold.ArchiveTgz:
+		err = x.tgz(f)
+	case scaffold.ArchiveZip:
+		err = x.zip(f)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("template archive %s: %w", a.Path, err)
+	}
+
+	fsys, err := templateRoot(x.out)
+	if err != nil {
+		return nil, fmt.Errorf("template archive %s: %w", a.Path, err)
+	}
+	return fsys, nil
+}
+
+// templateRoot returns the single top-level directory of fsys if it holds
+// nothing else, or fsys itself.
+func templateRoot(fsys fs.FS) (fs.FS, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case len(entries) == 0:
+		return nil, errors.New("archive is empty")
+	case len(entries) == 1 && entries[0].IsDir():
+		return fs.Sub(fsys, entries[0].Name())
+	}
+	return fsys, nil
+}
+
+// extractor copies archive entries into memory within its limits.
+type extractor struct {
+	ctx      context.Context
+	out      *scaffold.MemFS
+	size     int64
+	maxSize  int64
+	files    int
+	maxFiles int
+}
+
+// entryName checks an archive entry name and returns it as an fs.FS path,
+// or "." for the archive root. Names that are absolute, contain "..", or
+// use backslashes are rejected rather than cleaned, since no legitimate
+// template needs them.
+func entryName(name string) (string, error) {
+	clean := strings.TrimSuffix(strings.TrimPrefix(name, "./"), "/")
+	if clean == "" || clean == "." {
+		return ".", nil
+	}
+	if strings.Contains(clean, `\`) || !fs.ValidPath(clean) {
+		return "", fmt.Errorf("%q is not a safe path inside the template", name)
+	}
+	return clean, nil
+}
+
+// count records one more entry, failing once there are too many.
+func (x *extractor) count() error {
+	if err := x.ctx.Err(); err != nil {
+		return err
+	}
+	x.files++
+	if x.files > x.maxFiles {
+		return fmt.Errorf("more than %d entries", x.maxFiles)
+	}
+	return nil
+}
+
+func (x *extractor) dir(name string) error {
+	if name == "." {
+		return nil
+	}
+	if err := x.count(); err != nil {
+		return err
+	}
+	return x.out.MkdirAll(name, 0755)
+}
+
+func (x *extractor) file(name string, perm fs.FileMode, r io.Reader) error {
+	if name == "." {
+		return errors.New("file entry has no name")
+	}
+	if err := x.count(); err != nil {
+		return err
+	}
+	// Read one byte past the budget to detect archives that exceed it,
+	// whatever sizes their headers claim.
+	data, err := io.ReadAll(io.LimitReader(r, x.maxSize-x.size+1))
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	x.size += int64(len(data))
+	if x.size > x.maxSize {
+		return fmt.Errorf("expands to more than %d bytes", x.maxSize)
+	}
+	return x.out.WriteFile(name, data, perm)
+}
+
+func (x *extractor) tgz(r io.Reader) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	return x.tar(gz)
+}
+
+func (x *extractor) tar(r io.Reader) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name, err := entryName(hdr.Name)
+		if err != nil {
+			return err
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = x.dir(name)
+		case tar.TypeReg:
+			err = x.file(name, fs.FileMode(hdr.Mode).Perm(), tr)
+		case tar.TypeSymlink, tar.TypeLink:
+			err = fmt.Errorf("%s is a link; templates cannot contain links", hdr.Name)
+		default:
+			err = fmt.Errorf("%s is not a regular file or directory", hdr.Name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (x *extractor) zip(f *os.File) error {
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	// Unsafe names are reported by entryName with the offending entry.
+	zr, err := zip.NewReader(f, info.Size())
+	if err != nil && !errors.Is(err, zip.ErrInsecurePath) {
+		return err
+	}
+
+	for _, zf := range zr.File {
+		name, err := entryName(zf.Name)
+		if err != nil {
+			return err
+		}
+
+		mode := zf.Mode()
+		switch {
+		case mode.IsDir():
+			err = x.dir(name)
+		case mode.IsRegular():
+			err = x.zipFile(name, mode.Perm(), zf)
+		case mode&fs.ModeSymlink != 0:
+			err = fmt.Errorf("%s is a link; templates cannot contain links", zf.Name)
+		default:
+			err = fmt.Errorf("%s is not a regular file or directory", zf.Name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (x *extractor) zipFile(name string, perm fs.FileMode, zf *zip.File) error {
+	rc, err := zf.Open()
+	if err != nil {
+		return fmt.Errorf("%s: %w", zf.Name, err)
+	}
+	defer rc.Close()
+	return x.file(name, perm, rc)
+}
@@ -0,0 +1,239 @@
+package source
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/JackDrogon/project/pkg/scaffold"
+)
+
+// tarEntry is a raw tar header and content, for building archives that
+// scaffold.WriteArchive would never produce.
+type tarEntry struct {
+	hdr  tar.Header
+	body string
+}
+
+func writeTgz(t *testing.T, name string, entries []tarEntry) string {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		hdr := e.hdr
+		if hdr.Typeflag == tar.TypeReg {
+			hdr.Size = int64(len(e.body))
+		}
+		if err := tw.WriteHeader(&hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func writeZip(t *testing.T, name string, files map[string]string, modes map[string]fs.FileMode) string {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, body := range files {
+		hdr := &zip.FileHeader{Name: name, Method: zip.Deflate}
+		if mode, ok := modes[name]; ok {
+			hdr.SetMode(mode)
+		}
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func openArchive(t *testing.T, a *Archive) (fs.FS, error) {
+	t.Helper()
+	return a.Open(context.Background(), Options{})
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string // Name()
+	}{
+		{"git+https://example.com/org/templates.git#main:go", "go"},
+		{"./go-service-template.tgz", "go-service-template"},
+		{"/share/templates/svc.tar.gz", "svc"},
+		{"svc.TAR", "svc"},
+	}
+	for _, tt := range tests {
+		src, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q) error = %v", tt.in, err)
+			continue
+		}
+		if src.Name() != tt.want {
+			t.Errorf("Parse(%q).Name() = %q, want %q", tt.in, src.Name(), tt.want)
+		}
+		if src.String() != tt.in {
+			t.Errorf("Parse(%q).String() = %q", tt.in, src.String())
+		}
+	}
+
+	for _, in := range []string{"go", "templates/go", "https://example.com/t.tgz", "t.rar"} {
+		if _, err := Parse(in); err == nil {
+			t.Errorf("Parse(%q) expected error, got nil", in)
+		}
+	}
+}
+
+func TestArchive(t *testing.T) {
+	project := fstest.MapFS{
+		"template.yaml":   {Data: []byte("description: svc\n")},
+		"main.go.tmpl":    {Data: []byte("package main\n")},
+		"bin/run":         {Data: []byte("#!/bin/sh\n"), Mode: 0755},
+		"docs/index.md":   {Data: []byte("docs\n")},
+		"empty/.gitkeep":  {Data: nil},
+		"nested/a/b/c.md": {Data: []byte("c\n")},
+	}
+
+	for _, format := range []scaffold.ArchiveFormat{scaffold.ArchiveTar, scaffold.ArchiveTgz, scaffold.ArchiveZip} {
+		t.Run(string(format), func(t *testing.T) {
+			// WriteArchive roots entries under one directory, which is stripped.
+			var buf bytes.Buffer
+			if err := scaffold.WriteArchive(&buf, project, format, "svc-template"); err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(t.TempDir(), "svc"+format.Ext())
+			if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			fsys, err := openArchive(t, &Archive{Path: path})
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			for name, file := range project {
+				data, err := fs.ReadFile(fsys, name)
+				if err != nil {
+					t.Errorf("ReadFile(%s) error = %v", name, err)
+					continue
+				}
+				if !bytes.Equal(data, file.Data) {
+					t.Errorf("%s = %q, want %q", name, data, file.Data)
+				}
+			}
+			info, err := fs.Stat(fsys, "bin/run")
+			if err != nil || info.Mode().Perm() != 0755 {
+				t.Errorf("bin/run should keep its executable mode, info = %v, err = %v", info, err)
+			}
+		})
+	}
+
+	t.Run("no top-level directory", func(t *testing.T) {
+		path := writeZip(t, "flat.zip", map[string]string{"main.go": "package main\n", "README.md": "hi\n"}, nil)
+		fsys, err := openArchive(t, &Archive{Path: path})
+		if err != nil {
+			t.Fatalf("Open() error = %v", err)
+		}
+		if _, err := fs.Stat(fsys, "main.go"); err != nil {
+			t.Errorf("main.go should be at the root: %v", err)
+		}
+	})
+}
+
+func TestArchive_Rejects(t *testing.T) {
+	reg := func(name, body string) tarEntry {
+		return tarEntry{tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644}, body}
+	}
+	tests := []struct {
+		name    string
+		entries []tarEntry
+		want    string
+	}{
+		{"parent traversal", []tarEntry{reg("t/../../evil", "x")}, "not a safe path"},
+		{"absolute path", []tarEntry{reg("/etc/evil", "x")}, "not a safe path"},
+		{"backslash", []tarEntry{reg(`t\..\evil`, "x")}, "not a safe path"},
+		{"symlink", []tarEntry{{tar.Header{Name: "t/passwd", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"}, ""}}, "is a link"},
+		{"hard link", []tarEntry{reg("t/a", "x"), {tar.Header{Name: "t/b", Typeflag: tar.TypeLink, Linkname: "t/a"}, ""}}, "is a link"},
+		{"device", []tarEntry{{tar.Header{Name: "t/null", Typeflag: tar.TypeChar}, ""}}, "not a regular file"},
+		{"empty", nil, "archive is empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTgz(t, "t.tgz", tt.entries)
+			_, err := openArchive(t, &Archive{Path: path})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Open() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	t.Run("zip traversal", func(t *testing.T) {
+		path := writeZip(t, "t.zip", map[string]string{"../evil": "x"}, nil)
+		if _, err := openArchive(t, &Archive{Path: path}); err == nil || !strings.Contains(err.Error(), "not a safe path") {
+			t.Errorf("Open() error = %v, want traversal rejected", err)
+		}
+	})
+
+	t.Run("zip symlink", func(t *testing.T) {
+		path := writeZip(t, "t.zip", map[string]string{"t/passwd": "/etc/passwd"}, map[string]fs.FileMode{"t/passwd": fs.ModeSymlink | 0777})
+		if _, err := openArchive(t, &Archive{Path: path}); err == nil || !strings.Contains(err.Error(), "is a link") {
+			t.Errorf("Open() error = %v, want symlink rejected", err)
+		}
+	})
+}
+
+func TestArchive_Limits(t *testing.T) {
+	// Highly compressible content, as in a decompression bomb.
+	big := strings.Repeat("0", 4096)
+	entries := []tarEntry{
+		{tar.Header{Name: "t/a", Typeflag: tar.TypeReg, Mode: 0644}, big},
+		{tar.Header{Name: "t/b", Typeflag: tar.TypeReg, Mode: 0644}, big},
+	}
+	path := writeTgz(t, "t.tgz", entries)
+
+	if _, err := openArchive(t, &Archive{Path: path}); err != nil {
+		t.Fatalf("Open() within default limits error = %v", err)
+	}
+	if _, err := openArchive(t, &Archive{Path: path, MaxSize: 6000}); err == nil || !strings.Contains(err.Error(), "more than 6000 bytes") {
+		t.Errorf("Open() error = %v, want size limit", err)
+	}
+	if _, err := openArchive(t, &Archive{Path: path, MaxFiles: 1}); err == nil || !strings.Contains(err.Error(), "more than 1 entries") {
+		t.Errorf("Open() error = %v, want entry limit", err)
+	}
+
+	zipPath := writeZip(t, "t.zip", map[string]string{"a": big, "b": big}, nil)
+	if _, err := openArchive(t, &Archive{Path: zipPath, MaxSize: 6000}); err == nil || !strings.Contains(err.Error(), "more than 6000 bytes") {
+		t.Errorf("zip Open() error = %v, want size limit", err)
+	}
+}
@@ -0,0 +1,231 @@
+package source
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/JackDrogon/project/pkg/git"
+)
+
+// gitPrefix marks a template source as a git repository.
+const gitPrefix = "git+"
+
+// Git is a template stored in a git repository, written as
+//
+//	git+<url>[#<ref>][:<subdir>]
+//
+// e.g. git+https://example.com/templates.git#v1.2:go or
+// git+file:///srv/templates.git#main. Ref is a branch, tag, or commit and
+// defaults to the repository's HEAD; Subdir is the template's directory
+// within the repository and defaults to its root.
+type Git struct {
+	URL    string
+	Ref    string
+	Subdir string
+}
+
+// ParseGit parses a git+ template source.
+func ParseGit(s string) (*Git, error) {
+	rest, ok := strings.CutPrefix(s, gitPrefix)
+	if !ok {
+		return nil, fmt.Errorf("unsupported template source %q: must start with %s, e.g. git+https://host/repo.git", s, gitPrefix)
+	}
+
+	url, fragment, _ := strings.Cut(rest, "#")
+	scheme, _, _ := strings.Cut(url, "://")
+	switch scheme {
+	case "https", "http", "ssh", "file":
+	default:
+		return nil, fmt.Errorf("unsupported template source %q: scheme must be https, http, ssh, or file", s)
+	}
+
+	// Ref names cannot contain ':', so the first one starts the subdir.
+	ref, subdir, _ := strings.Cut(fragment, ":")
+	if subdir != "" {
+		subdir = path.Clean(subdir)
+		if !filepath.IsLocal(filepath.FromSlash(subdir)) {
+			return nil, fmt.Errorf("template source %q: subdirectory %q must be relative and inside the repository", s, subdir)
+		}
+		if subdir == "." {
+			subdir = ""
+		}
+	}
+	return &Git{URL: url, Ref: ref, Subdir: subdir}, nil
+}
+
+// String returns the source in the form accepted by ParseGit.
+func (g *Git) String() string {
+	s := gitPrefix + g.URL
+	if g.Ref != "" || g.Subdir != "" {
+		s += "#" + g.Ref
+	}
+	if g.Subdir != "" {
+		s += ":" + g.Subdir
+	}
+	return s
+}
+
+// Name returns a template name for the source: the base name of the
+// subdirectory, or of the repository without its .git suffix.
+func (g *Git) Name() string {
+	if g.Subdir != "" {
+		return path.Base(g.Subdir)
+	}
+	return strings.TrimSuffix(path.Base(strings.TrimRight(g.URL, "/")), ".git")
+}
+
+// Open implements Source by fetching the template into opts.CacheDir.
+func (g *Git) Open(ctx context.Context, opts Options) (fs.FS, error) {
+	dir, err := g.Fetch(ctx, opts.CacheDir, opts.Refresh, opts.progress())
+	if err != nil {
+		return nil, err
+	}
+	return os.DirFS(dir), nil
+}
+
+// Fetch makes the template available under cacheDir and returns its
+// directory. The repository is mirrored once and reused; refresh fetches
+// it again to pick up new commits on branches and tags. Each commit is
+// checked out into its own directory, which is never modified afterwards.
+// Progress messages are written to w.
+func (g *Git) Fetch(ctx context.Context, cacheDir string, refresh bool, w io.Writer) (string, error) {
+	sum := sha256.Sum256([]byte(g.URL))
+	repo := filepath.Join(cacheDir, "repos", hex.EncodeToString(sum[:8])+".git")
+
+	fetched := false
+	if _, err := os.Stat(repo); errors.Is(err, os.ErrNotExist) {
+		_, _ = fmt.Fprintf(w, "Cloning template %s\n", g.URL)
+		if err := clone(ctx, g.URL, repo); err != nil {
+			return "", err
+		}
+		fetched = true
+	} else if err != nil {
+		return "", fmt.Errorf("failed to inspect template cache: %w", err)
+	} else if refresh {
+		if err := update(ctx, w, g.URL, repo); err != nil {
+			return "", err
+		}
+		fetched = true
+	}
+
+	commit, err := g.resolve(ctx, repo)
+	if err != nil && !fetched {
+		// The ref may be newer than the cached mirror.
+		if err := update(ctx, w, g.URL, repo); err != nil {
+			return "", err
+		}
+		commit, err = g.resolve(ctx, repo)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	tree, err := checkout(ctx, repo, commit, filepath.Join(cacheDir, "trees", commit))
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(tree, filepath.FromSlash(g.Subdir))
+	info, err := os.Lstat(dir)
+	if err != nil || !info.IsDir() {
+		return "", fmt.Errorf("template source %s: %q is not a directory at %s", g, g.Subdir, commit[:12])
+	}
+	if err := rejectSymlinks(dir); err != nil {
+		return "", fmt.Errorf("template source %s: %w", g, err)
+	}
+	return dir, nil
+}
+
+// rejectSymlinks fails if the template at dir contains a symbolic link,
+// which could make the template read files outside the repository.
+func rejectSymlinks(dir string) error {
+	return filepath.WalkDir(dir, func(name string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type()&os.ModeSymlink != 0 {
+			rel, _ := filepath.Rel(dir, name)
+			return fmt.Errorf("%s is a symbolic link; templates cannot contain symlinks", filepath.ToSlash(rel))
+		}
+		return nil
+	})
+}
+
+// resolve returns the commit id the source's ref points to in repo.
+func (g *Git) resolve(ctx context.Context, repo string) (string, error) {
+	ref := g.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	commit, err := git.Output(ctx, "", "--git-dir", repo, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("template source %s: unknown ref %q", g, ref)
+	}
+	return commit, nil
+}
+
+// clone mirrors url into repo. It clones into a temporary directory first so
+// an interrupted clone never leaves a broken cache entry.
+func clone(ctx context.Context, url, repo string) error {
+	if err := os.MkdirAll(filepath.Dir(repo), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.MkdirTemp(filepath.Dir(repo), ".clone-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	if err := git.Run(ctx, "", "clone", "--mirror", "--quiet", url, tmp); err != nil {
+		return fmt.Errorf("failed to clone template %s: %w", url, err)
+	}
+	return os.Rename(tmp, repo)
+}
+
+func update(ctx context.Context, w io.Writer, url, repo string) error {
+	_, _ = fmt.Fprintf(w, "Updating template %s\n", url)
+	if err := git.Run(ctx, "", "--git-dir", repo, "fetch", "--prune", "--quiet", "origin"); err != nil {
+		return fmt.Errorf("failed to update template %s: %w", url, err)
+	}
+	return nil
+}
+
+// checkout writes the tree of commit to dir unless it is already there.
+func checkout(ctx context.Context, repo, commit, dir string) (string, error) {
+	if _, err := os.Stat(dir); err == nil {
+		return dir, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return "", err
+	}
+	tmp, err := os.MkdirTemp(filepath.Dir(dir), ".checkout-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+
+	// A throwaway index keeps the checkout from touching the mirror's state.
+	index := tmp + ".index"
+	defer os.Remove(index)
+	env := []string{"GIT_INDEX_FILE=" + index}
+	args := []string{"--git-dir", repo, "--work-tree", tmp, "-c", "core.autocrlf=false", "checkout", "--force", commit, "--", "."}
+	if err := git.RunEnv(ctx, "", env, args...); err != nil {
+		return "", fmt.Errorf("failed to check out template at %s: %w", commit[:12], err)
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
@@ -0,0 +1,238 @@
+package source
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func requireGit(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Setenv("GIT_AUTHOR_NAME", "Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+}
+
+func run(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// commitFiles writes files into the work tree and commits them.
+func commitFiles(t *testing.T, work string, files map[string]string, message string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(work, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	run(t, work, "add", ".")
+	run(t, work, "commit", "-q", "-m", message)
+}
+
+// templateRepo creates a work tree with one commit tagged v1 and pushes it
+// to a bare repository, returning both and the bare repository's URL.
+func templateRepo(t *testing.T) (work, bare, url string) {
+	t.Helper()
+	tmp := t.TempDir()
+	work = filepath.Join(tmp, "work")
+	bare = filepath.Join(tmp, "templates.git")
+
+	run(t, tmp, "init", "-q", "-b", "main", work)
+	commitFiles(t, work, map[string]string{
+		"README.md":           "templates\n",
+		"go/main.go.tmpl":     "package main // v1\n",
+		"go/template.yaml":    "description: v1\n",
+		"cpp/src/main.cc":     "int main() {}\n",
+		"cpp/CMakeLists.txt":  "project(x)\n",
+		"rust/src/main.rs":    "fn main() {}\n",
+		"rust/template.yaml":  "description: rust\n",
+		"docs/guide/index.md": "guide\n",
+	}, "v1")
+	run(t, work, "tag", "v1")
+	run(t, tmp, "clone", "-q", "--bare", work, bare)
+	run(t, work, "remote", "add", "origin", bare)
+	return work, bare, "file://" + filepath.ToSlash(bare)
+}
+
+func TestParseGit(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Git
+		name string
+	}{
+		{"git+https://example.com/org/templates.git", Git{URL: "https://example.com/org/templates.git"}, "templates"},
+		{"git+https://example.com/org/templates#v1.2", Git{URL: "https://example.com/org/templates", Ref: "v1.2"}, "templates"},
+		{"git+file:///srv/templates.git#main:go", Git{URL: "file:///srv/templates.git", Ref: "main", Subdir: "go"}, "go"},
+		{"git+ssh://git@example.com/t.git#:lang/cpp/", Git{URL: "ssh://git@example.com/t.git", Subdir: "lang/cpp"}, "cpp"},
+		{"git+file:///srv/t.git#abc123:.", Git{URL: "file:///srv/t.git", Ref: "abc123"}, "t"},
+	}
+	for _, tt := range tests {
+		got, err := ParseGit(tt.in)
+		if err != nil {
+			t.Errorf("ParseGit(%q) error = %v", tt.in, err)
+			continue
+		}
+		if *got != tt.want {
+			t.Errorf("ParseGit(%q) = %+v, want %+v", tt.in, *got, tt.want)
+		}
+		if got.Name() != tt.name {
+			t.Errorf("ParseGit(%q).Name() = %q, want %q", tt.in, got.Name(), tt.name)
+		}
+	}
+
+	for _, in := range []string{
+		"https://example.com/t.git",
+		"git+ftp://example.com/t.git",
+		"git+/srv/t.git",
+		"git+file:///srv/t.git#main:../escape",
+		"git+file:///srv/t.git#main:/abs",
+	} {
+		if _, err := ParseGit(in); err == nil {
+			t.Errorf("ParseGit(%q) expected error, got nil", in)
+		}
+	}
+}
+
+func TestGitFetch(t *testing.T) {
+	requireGit(t)
+	work, _, url := templateRepo(t)
+	cache := t.TempDir()
+	ctx := context.Background()
+
+	fetch := func(t *testing.T, spec string, refresh bool) (string, string) {
+		t.Helper()
+		src, err := ParseGit(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out bytes.Buffer
+		dir, err := src.Fetch(ctx, cache, refresh, &out)
+		if err != nil {
+			t.Fatalf("Fetch(%s) error = %v", spec, err)
+		}
+		return dir, out.String()
+	}
+	read := func(t *testing.T, name string) string {
+		t.Helper()
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	dir, out := fetch(t, "git+"+url+"#main:go", false)
+	if got := read(t, filepath.Join(dir, "main.go.tmpl")); got != "package main // v1\n" {
+		t.Errorf("main.go.tmpl = %q", got)
+	}
+	if !strings.Contains(out, "Cloning template") {
+		t.Errorf("first fetch output = %q, want a clone message", out)
+	}
+
+	// A new commit upstream is not seen until --refresh.
+	commitFiles(t, work, map[string]string{"go/main.go.tmpl": "package main // v2\n"}, "v2")
+	run(t, work, "push", "-q", "origin", "main")
+
+	dir, out = fetch(t, "git+"+url+"#main:go", false)
+	if got := read(t, filepath.Join(dir, "main.go.tmpl")); got != "package main // v1\n" {
+		t.Errorf("cached main.go.tmpl = %q, want v1", got)
+	}
+	if out != "" {
+		t.Errorf("cached fetch output = %q, want none", out)
+	}
+
+	dir, _ = fetch(t, "git+"+url+"#main:go", true)
+	if got := read(t, filepath.Join(dir, "main.go.tmpl")); got != "package main // v2\n" {
+		t.Errorf("refreshed main.go.tmpl = %q, want v2", got)
+	}
+
+	t.Run("tag", func(t *testing.T) {
+		dir, _ := fetch(t, "git+"+url+"#v1:go", false)
+		if got := read(t, filepath.Join(dir, "main.go.tmpl")); got != "package main // v1\n" {
+			t.Errorf("main.go.tmpl at v1 = %q", got)
+		}
+	})
+
+	t.Run("commit", func(t *testing.T) {
+		commit := run(t, work, "rev-parse", "v1")
+		dir, _ := fetch(t, "git+"+url+"#"+commit[:10]+":go", false)
+		if got := read(t, filepath.Join(dir, "main.go.tmpl")); got != "package main // v1\n" {
+			t.Errorf("main.go.tmpl at %s = %q", commit[:10], got)
+		}
+	})
+
+	t.Run("new ref fetched automatically", func(t *testing.T) {
+		run(t, work, "tag", "v2")
+		run(t, work, "push", "-q", "origin", "v2")
+		dir, _ := fetch(t, "git+"+url+"#v2:go", false)
+		if got := read(t, filepath.Join(dir, "main.go.tmpl")); got != "package main // v2\n" {
+			t.Errorf("main.go.tmpl at v2 = %q", got)
+		}
+	})
+
+	t.Run("whole repository", func(t *testing.T) {
+		dir, _ := fetch(t, "git+"+url, false)
+		if got := read(t, filepath.Join(dir, "README.md")); got != "templates\n" {
+			t.Errorf("README.md = %q", got)
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			t.Error("checkout should not contain .git")
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		for _, spec := range []string{
+			"git+" + url + "#nope:go",
+			"git+" + url + "#main:missing",
+			"git+" + url + "#main:README.md",
+			"git+file://" + filepath.ToSlash(filepath.Join(t.TempDir(), "absent.git")),
+		} {
+			src, err := ParseGit(spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := src.Fetch(ctx, cache, false, &bytes.Buffer{}); err == nil {
+				t.Errorf("Fetch(%s) expected error, got nil", spec)
+			}
+		}
+	})
+}
+
+func TestGitFetch_RejectsSymlinks(t *testing.T) {
+	requireGit(t)
+	work, _, url := templateRepo(t)
+	if err := os.Symlink("/etc/passwd", filepath.Join(work, "go", "passwd")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	run(t, work, "add", ".")
+	run(t, work, "commit", "-q", "-m", "symlink")
+	run(t, work, "push", "-q", "origin", "main")
+
+	src, err := ParseGit("git+" + url + "#main:go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = src.Fetch(context.Background(), t.TempDir(), false, &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "symbolic link") {
+		t.Errorf("Fetch() error = %v, want symlink rejection", err)
+	}
+}
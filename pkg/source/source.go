@@ -0,0 +1,64 @@
+// Package source fetches templates that live outside the binary, such as in
+// git repositories or archive files.
+package source
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Source is a single template stored outside the binary.
+type Source interface {
+	// Name returns the name to use for the template when none is given.
+	Name() string
+	// String returns the source as it was written.
+	String() string
+	// Open makes the template available and returns its files, rooted at
+	// the template directory.
+	Open(ctx context.Context, opts Options) (fs.FS, error)
+}
+
+// Options controls how a Source is opened.
+type Options struct {
+	CacheDir string    // where fetched templates are cached
+	Refresh  bool      // update cached copies before using them
+	Progress io.Writer // receives progress messages; nil discards them
+}
+
+func (o Options) progress() io.Writer {
+	if o.Progress == nil {
+		return io.Discard
+	}
+	return o.Progress
+}
+
+// Parse parses a --template source: a git repository written as
+// git+<url>[#<ref>][:<subdir>], or the path of a local .tar, .tar.gz, .tgz,
+// or .zip archive.
+func Parse(s string) (Source, error) {
+	if strings.HasPrefix(s, gitPrefix) {
+		return ParseGit(s)
+	}
+	if strings.Contains(s, "://") {
+		return nil, fmt.Errorf("unsupported template source %q: use git+<url> for repositories; archives must be local files", s)
+	}
+	if _, ok := archiveFormat(s); ok {
+		return &Archive{Path: s}, nil
+	}
+	return nil, fmt.Errorf("unsupported template source %q: must be git+<url> or a .tar, .tar.gz, .tgz, or .zip file", s)
+}
+
+// DefaultCacheDir returns the directory fetched templates are cached in,
+// under the user cache directory (e.g. ~/.cache/project/templates).
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find cache directory: %w", err)
+	}
+	return filepath.Join(dir, "project", "templates"), nil
+}
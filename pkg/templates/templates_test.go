@@ -0,0 +1,54 @@
+package templates_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/JackDrogon/project/pkg/scaffold"
+	"github.com/JackDrogon/project/pkg/scaffold/scaffoldtest"
+	"github.com/JackDrogon/project/pkg/templates"
+)
+
+// TestGolden renders the built-in templates and compares them with the
+// projects under testdata. Run with -update after changing a template.
+func TestGolden(t *testing.T) {
+	creator := scaffold.NewCreator(templates.FS, io.Discard)
+	for _, lang := range []string{"go", "cpp"} {
+		t.Run(lang, func(t *testing.T) {
+			scaffoldtest.Run(t, creator, lang, filepath.Join("testdata", lang))
+		})
+	}
+}
+
+// TestVerify generates the built-in templates and runs the checks they
+// declare, such as go build for the go template. Checks whose toolchain is
+// not installed are skipped.
+func TestVerify(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds generated projects")
+	}
+	for _, lang := range []string{"go", "cpp"} {
+		t.Run(lang, func(t *testing.T) {
+			var out bytes.Buffer
+			creator := scaffold.NewCreator(templates.FS, &out)
+			results, err := creator.Verify(context.Background(), scaffold.Options{Lang: lang, ProjectName: "example"})
+			if err != nil {
+				t.Fatalf("Verify() error = %v\n%s", err, out.String())
+			}
+			for _, r := range results {
+				switch r.Status {
+				case scaffold.CheckFailed:
+					t.Errorf("check %s failed: %v", r.Name, r.Err)
+				case scaffold.CheckSkipped:
+					t.Logf("check %s skipped: %v not found", r.Name, r.Missing)
+				}
+			}
+			if t.Failed() {
+				t.Log(out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,6 @@
+#include <iostream>
+
+int main() {
+    std::cout << "Hello, my-lib!" << std::endl;
+    return 0;
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"example/pkg/version"
+)
+
+func main() {
+	showVersion := flag.Bool("version", false, "print the version and exit")
+	flag.Parse()
+
+	if *showVersion {
+		fmt.Println(version.GitTagSha)
+		return
+	}
+	fmt.Println("Hello, example!")
+}
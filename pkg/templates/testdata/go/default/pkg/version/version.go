@@ -0,0 +1,6 @@
+// Package version reports the version example was built from.
+package version
+
+// GitTagSha is the git tag or commit the binary was built from. `just build`
+// sets it with -ldflags; plain `go build` leaves it unset.
+var GitTagSha = "unknown"
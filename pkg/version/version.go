@@ -2,6 +2,7 @@ package version
 
 import (
 	"fmt"
+	"runtime"
 	"runtime/debug"
 	"strings"
 )
@@ -13,6 +14,7 @@ var Tag = "dev"
 // with VCS revision and dirty state (from runtime/debug.BuildInfo).
 func Info() string {
 	revision, modified := vcsInfo()
+	revision = shortRevision(revision)
 
 	var b strings.Builder
 	b.WriteString(Tag)
@@ -36,9 +38,6 @@ func vcsInfo() (revision string, modified bool) {
 		switch s.Key {
 		case "vcs.revision":
 			revision = s.Value
-			if len(revision) > 7 {
-				revision = revision[:7]
-			}
 		case "vcs.modified":
 			modified = s.Value == "true"
 		}
@@ -46,9 +45,18 @@ func vcsInfo() (revision string, modified bool) {
 	return
 }
 
+// shortRevision abbreviates a commit hash for display.
+func shortRevision(revision string) string {
+	if len(revision) > 7 {
+		return revision[:7]
+	}
+	return revision
+}
+
 // Verbose returns multi-line version details.
 func Verbose() string {
 	revision, modified := vcsInfo()
+	revision = shortRevision(revision)
 	lines := []string{fmt.Sprintf("Tag:      %s", Tag)}
 	if revision != "" {
 		lines = append(lines, fmt.Sprintf("Revision: %s", revision))
@@ -56,3 +64,17 @@ func Verbose() string {
 	lines = append(lines, fmt.Sprintf("Dirty:    %t", modified))
 	return strings.Join(lines, "\n")
 }
+
+// Details holds the build version as structured data.
+type Details struct {
+	Tag       string
+	Revision  string // full VCS commit hash, empty when unknown
+	Dirty     bool
+	GoVersion string
+}
+
+// Get returns the build's version details.
+func Get() Details {
+	revision, modified := vcsInfo()
+	return Details{Tag: Tag, Revision: revision, Dirty: modified, GoVersion: runtime.Version()}
+}